@@ -1,21 +1,31 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"regexp"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/integr8ly/heimdall/pkg/clair"
 	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/controller/cronjob"
+	"github.com/integr8ly/heimdall/pkg/controller/daemonset"
 	"github.com/integr8ly/heimdall/pkg/controller/deploymentconfigs"
 	"github.com/integr8ly/heimdall/pkg/controller/deployments"
+	"github.com/integr8ly/heimdall/pkg/controller/job"
+	"github.com/integr8ly/heimdall/pkg/controller/operators"
+	"github.com/integr8ly/heimdall/pkg/controller/orphans"
 	"github.com/integr8ly/heimdall/pkg/controller/statefulset"
 	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/osv"
 	"github.com/integr8ly/heimdall/pkg/registry"
 	"github.com/integr8ly/heimdall/pkg/rhcc"
-	"github.com/jedib0t/go-pretty/table"
+	"github.com/integr8ly/heimdall/pkg/transport"
 	v1 "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1"
 	imagesv1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -25,11 +35,48 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "export-bundle" {
+		exportBundle(os.Args[2:])
+		return
+	}
+	httpSettings, err := transport.SettingsFromEnv()
+	if err != nil {
+		log.Fatalf("error reading the http settings: %v", err)
+	}
 	namespacePtr := flag.String("namespaces", "", "the namespaces to check")
 	namespacePatternPtr := flag.String("namespace-pattern", "", "a go compilant regular expression to include only matching namespaces")
 	componentPtr := flag.String("component", "*", "the dc or deployment name to check in the namespace")
 	labelPodsPtr := flag.String("label-pods", "false", "add labels to the pods with the info discovered")
+	osvFeedPtr := flag.String("osv-feed", "", "url of an OSV format JSON feed to read CVEs from for images in registries matching -osv-hosts")
+	osvHostsPtr := flag.String("osv-hosts", "", "a go compliant regular expression matching the registry hosts to use the OSV feed for")
+	clairPtr := flag.String("clair", "", "url of a Clair v4 instance to read CVEs from for images in registries matching -clair-hosts")
+	clairHostsPtr := flag.String("clair-hosts", "", "a go compliant regular expression matching the registry hosts to use Clair for")
+	includeRegistriesPtr := flag.String("include-registries", "", "comma separated go compliant regular expressions matching the registry hosts to check images from (defaults to the redhat registries)")
+	excludeRegistriesPtr := flag.String("exclude-registries", "", "comma separated go compliant regular expressions matching the registry hosts to skip")
+	rhccURLPtr := flag.String("rhcc-url", "", "the url of the rhcc api, defaults to the public api")
+	rhccBundlePtr := flag.String("rhcc-bundle", "", "a bundle created with export-bundle to read rhcc data from instead of the rhcc api")
+	cacheDirPtr := flag.String("cache-dir", "", "a directory to persist registry and rhcc lookups in so they are reused by later runs")
+	cacheTTLPtr := flag.Duration("cache-ttl", registry.DefaultCacheTTL, "how long registry and rhcc lookups are cached for")
+	workersPtr := flag.Int("workers", registry.DefaultCheckWorkers, "how many images to check at once")
+	httpRateLimitPtr := flag.Float64("http-rate-limit", httpSettings.RateLimit, "the maximum requests per second sent to each registry or api host, 0 for no limit")
+	httpRetriesPtr := flag.Int("http-max-retries", httpSettings.MaxRetries, "how many times a request failing with a transient error is retried")
+	timeoutPtr := flag.Duration("timeout", 0, "how long the whole run may take before the checks still in progress are abandoned, 0 for no limit")
+	checkTimeoutPtr := flag.Duration("check-timeout", registry.DefaultCheckTimeout, "how long the check of a single image may take before it is abandoned")
+	mirrorsPtr := flag.String("mirrors", "", "comma separated mirror=source repository pairs used to resolve mirrored images back to their source e.g. mirror.local:5000/rh=registry.redhat.io")
+	excludeContainersPtr := flag.String("exclude-containers", "", "comma separated go compliant regular expressions matching the names of containers to skip e.g. oauth-proxy")
+	excludeImagesPtr := flag.String("exclude-images", "", "comma separated go compliant regular expressions matching the full path of images to skip")
+	orphansPtr := flag.Bool("orphans", false, "also check the images of running pods not controlled by a deployment, deploymentconfig, stateful set, daemon set, cron job or job")
+	operatorsPtr := flag.Bool("operators", false, "also check the images of the operators installed by OLM and list the operators with newer patch builds fixing CVEs")
+	outputPtr := flag.String("output", outputTable, "the format to write the reports in, one of "+strings.Join(outputFormats, ", "))
 	flag.Parse()
+	if !isOutputFormat(*outputPtr) {
+		log.Fatalf("unknown output format %s, expected one of %s", *outputPtr, strings.Join(outputFormats, ", "))
+	}
+	httpSettings.RateLimit = *httpRateLimitPtr
+	httpSettings.MaxRetries = *httpRetriesPtr
+	transport.SetShared(transport.New(nil, httpSettings))
+	ctx, cancel := runContext(*timeoutPtr)
+	defer cancel()
 
 	conf := config.GetConfigOrDie()
 	client, err := kubernetes.NewForConfig(conf)
@@ -47,51 +94,77 @@ func main() {
 		log.Fatal("failed to create image stream client")
 	}
 	clusterIS := cluster.NewImageService(client, isClient)
-	registryIS := registry.NewImagesService(&registry.Client{}, &rhcc.Client{}, &rhcc.Client{})
-	dcReport := deploymentconfigs.NewReport(clusterIS, registryIS, dcClient)
-	deploymentReport := deployments.NewReport(clusterIS, registryIS, client.AppsV1())
-	statefulSetReport := statefulset.NewReport(clusterIS, registryIS, client.AppsV1())
-	var reports []domain.ReportResult
+	sources := registry.NewSources()
+	if *osvFeedPtr != "" {
+		if err := sources.Register(*osvHostsPtr, registry.Source{CVEs: osv.NewClient(*osvFeedPtr)}); err != nil {
+			log.Fatalf("failed to add the osv feed: %v", err)
+		}
+	}
+	if *clairPtr != "" {
+		if err := sources.Register(*clairHostsPtr, registry.Source{CVEs: clair.NewClient(*clairPtr, &registry.Client{})}); err != nil {
+			log.Fatalf("failed to add clair: %v", err)
+		}
+	}
+	rhccClient := rhcc.NewClient(*rhccURLPtr)
+	if *rhccBundlePtr != "" {
+		bundle, err := rhcc.LoadBundle(*rhccBundlePtr)
+		if err != nil {
+			log.Fatalf("failed to load the rhcc bundle: %v", err)
+		}
+		rhccClient = rhcc.NewBundleClient(bundle)
+	}
+	registryIS := registry.NewImagesService(&registry.Client{}, rhccClient, rhccClient).
+		WithSources(sources).
+		WithCache(*cacheTTLPtr, registry.DefaultCacheSize, *cacheDirPtr).
+		WithWorkers(*workersPtr).
+		WithCheckTimeout(*checkTimeoutPtr)
+	policy, err := getPolicy(*includeRegistriesPtr, *excludeRegistriesPtr, *mirrorsPtr)
+	if err != nil {
+		log.Fatalf("error creating registry policy: %v", err)
+	}
+	policy, err = policy.WithExclusions(splitList(*excludeContainersPtr), splitList(*excludeImagesPtr))
+	if err != nil {
+		log.Fatalf("error creating registry policy: %v", err)
+	}
+	dcReport := deploymentconfigs.NewReport(clusterIS, registryIS, policy, dcClient)
+	deploymentReport := deployments.NewReport(clusterIS, registryIS, policy, client.AppsV1())
+	statefulSetReport := statefulset.NewReport(clusterIS, registryIS, policy, client.AppsV1())
+	daemonSetReport := daemonset.NewReport(clusterIS, registryIS, policy, client.AppsV1())
+	cronJobReport := cronjob.NewReport(clusterIS, registryIS, policy, client.BatchV1beta1())
+	jobReport := job.NewReport(clusterIS, registryIS, policy, client.BatchV1())
+	generateFns := []func(context.Context, string, string) ([]domain.ReportResult, error){
+		dcReport.Generate,
+		deploymentReport.Generate,
+		statefulSetReport.Generate,
+		daemonSetReport.Generate,
+		cronJobReport.Generate,
+		jobReport.Generate,
+	}
+	if *orphansPtr {
+		generateFns = append(generateFns, orphans.NewReport(clusterIS, registryIS, policy).Generate)
+	}
+	if *operatorsPtr {
+		c, err := client2.New(conf, client2.Options{})
+		if err != nil {
+			log.Fatal("failed to get a client for reading cluster service versions ", err)
+		}
+		generateFns = append(generateFns, operators.NewReport(cluster.NewOperators(c), registryIS, policy).Generate)
+	}
+	var results []namespaceReports
 	namespaces, err := getNamespaces(client, namespacePtr)
 	if err != nil {
 		log.Fatalf("error getting namespaces: %v", err)
 	}
-	namespaces, err = filterNamespaces(namespaces, namespacePatternPtr)
+	namespaces, err = cluster.FilterNamespaces(namespaces, *namespacePatternPtr)
 	if err != nil {
 		log.Fatalf("error filtering namespaces with pattern %s: %v", *namespacePatternPtr, err)
 	}
 	for _, n := range namespaces {
-		nsReports, err := accumulateReports(n, *componentPtr,
-			dcReport.Generate,
-			deploymentReport.Generate,
-			statefulSetReport.Generate,
-		)
+		nsReports, err := accumulateReports(ctx, n, *componentPtr, generateFns...)
 		if err != nil {
 			log.Println("failed to generate image report " + err.Error())
 		}
-		reports = append(reports, nsReports...)
-		t := table.NewWriter()
-		t.SetOutputMirror(os.Stdout)
-
-		t.AppendHeader(table.Row{"component", "Image", "Image Hash", "Image Stream", "Tag", "UpTo Date With Tag", "Persistent Image Tag", "Latest Patch Tag", "Floating Tag", "Using Floating Tag", "Upto Date with Floating Tag", "Critical CVEs", "Important CVEs", "Moderate CVEs"})
-		for i := range reports {
-			t.AppendRows([]table.Row{
-				{reports[i].Component,
-					reports[i].ClusterImage.OrgImagePath,
-					reports[i].ClusterImage.GetSHAFromPath(),
-					reports[i].ClusterImage.FromImageStream,
-					reports[i].ClusterImage.Tag,
-					reports[i].UpToDateWithOwnTag,
-					reports[i].CurrentVersion,
-					reports[i].LatestAvailablePatchVersion,
-					reports[i].FloatingTag,
-					reports[i].UsingFloatingTag,
-					reports[i].UpToDateWithFloatingTag,
-					len(reports[i].GetResolvableCriticalCVEs()),
-					len(reports[i].GetResolvableImportantCVEs()),
-					len(reports[i].GetResolvableModerateCVEs())},
-			})
-		}
+		results = append(results, namespaceReports{namespace: n, reports: nsReports})
 
 		if *labelPodsPtr == "true" {
 			c, err := client2.New(conf, client2.Options{})
@@ -100,17 +173,48 @@ func main() {
 				return
 			}
 			podService := cluster.NewPods(c)
-			for _, r := range reports {
-				if err := podService.LabelPods(&r); err != nil {
+			for _, r := range nsReports {
+				if err := podService.LabelPods(ctx, &r); err != nil {
 					log.Println("failed to label pods ", err)
 				}
 			}
 		}
-		t.Render()
-		//time.Sleep(time.Minute * 3)
+	}
+	if err := writeReports(os.Stdout, *outputPtr, results); err != nil {
+		log.Fatalf("failed to write the reports: %v", err)
+	}
+	if *operatorsPtr && *outputPtr == outputTable {
+		var reports []domain.ReportResult
+		for _, nr := range results {
+			reports = append(reports, nr.reports...)
+		}
+		renderOperatorUpdates(os.Stdout, operators.Updates(reports))
 	}
 }
 
+// exportBundle snapshots the rhcc data for the repositories passed as command
+// arguments into a bundle that can be used with -rhcc-bundle when offline
+func exportBundle(args []string) {
+	flags := flag.NewFlagSet("export-bundle", flag.ExitOnError)
+	repositoriesPtr := flags.String("repositories", "", "comma separated repositories to export e.g. amq7/amq-online-1-api-server")
+	outputPtr := flags.String("output", "rhcc-bundle.json", "the file to write the bundle to")
+	rhccURLPtr := flags.String("rhcc-url", "", "the url of the rhcc api, defaults to the public api")
+	flags.Parse(args)
+
+	repositories := splitList(*repositoriesPtr)
+	if len(repositories) == 0 {
+		log.Fatal("at least one repository is required")
+	}
+	bundle, err := rhcc.NewClient(*rhccURLPtr).ExportBundle(context.Background(), repositories)
+	if err != nil {
+		log.Fatalf("failed to export the rhcc bundle: %v", err)
+	}
+	if err := bundle.Save(*outputPtr); err != nil {
+		log.Fatalf("failed to save the rhcc bundle: %v", err)
+	}
+	fmt.Printf("exported %d repositories and %d images to %s\n", len(bundle.Repositories), len(bundle.Images), *outputPtr)
+}
+
 // getNamespaces obtains a slice of namespaces to inspect based on the presence
 // and contents of the `namespaceFlag` passed as a command argument
 func getNamespaces(client *kubernetes.Clientset, namespaceFlag *string) ([]string, error) {
@@ -131,50 +235,70 @@ func getNamespaces(client *kubernetes.Clientset, namespaceFlag *string) ([]strin
 	return result, nil
 }
 
-// filterNamespaces returns a slice of strings from the `namespaces` parameter
-// that match the `namespacePatternFlag` regex. If `namespacePatternFlag` has
-// no value, all namespaces are included.
-func filterNamespaces(namespaces []string, namespacePatternFlag *string) ([]string, error) {
-	if namespacePatternFlag == nil || *namespacePatternFlag == "" {
-		return namespaces, nil
-	}
-
-	if len(namespaces) == 0 {
-		return namespaces, nil
+// getPolicy creates the registry policy from the comma separated include and
+// exclude patterns and mirror=source pairs passed as command arguments
+func getPolicy(include, exclude, mirrors string) (*registry.Policy, error) {
+	var ms []registry.Mirror
+	for _, m := range splitList(mirrors) {
+		parts := strings.Split(m, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expected a mirror in the format mirror=source but got %s", m)
+		}
+		ms = append(ms, registry.Mirror{Source: parts[1], Mirrors: []string{parts[0]}})
 	}
+	return registry.NewPolicy(splitList(include), splitList(exclude), ms)
+}
 
-	pattern := strings.Trim(*namespacePatternFlag, "\"")
-
-	result := make([]string, 0, len(namespaces))
-	re, err := regexp.Compile(pattern)
-	if err != nil {
-		return nil, err
+func splitList(list string) []string {
+	if list == "" {
+		return nil
 	}
+	return strings.Split(list, ",")
+}
 
-	for _, namespace := range namespaces {
-		if re.MatchString(namespace) {
-			result = append(result, namespace)
+// runContext returns the context the checks run with. It is cancelled on an
+// interrupt or once timeout has passed if it is greater than zero.
+func runContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		cancelRun := cancel
+		cancel = func() {
+			cancelTimeout()
+			cancelRun()
 		}
 	}
-
-	return result, nil
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-interrupt:
+			log.Println("interrupted, abandoning the checks in progress")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
 }
 
 // accumulateReports takes a variadic list of functions that generate reports
-// and invokes them passing the same given ns and name, and accumulates all
+// and invokes them passing the same given ctx, ns and name, and accumulates all
 // the results in a single slice. If any of the generate function fails, the
 // function returns the error.
-func accumulateReports(ns, name string, generateFns ...func(string, string) ([]domain.ReportResult, error)) ([]domain.ReportResult, error) {
+func accumulateReports(ctx context.Context, ns, name string, generateFns ...func(context.Context, string, string) ([]domain.ReportResult, error)) ([]domain.ReportResult, error) {
 	result := []domain.ReportResult{}
+	var errs registry.CheckErrors
 
 	for _, generateFn := range generateFns {
-		reports, err := generateFn(ns, name)
+		// the reports of the images that were checked are kept when others failed
+		reports, err := generateFn(ctx, ns, name)
 		if err != nil {
-			return result, err
+			errs = append(errs, err)
 		}
 
 		result = append(result, reports...)
 	}
 
-	return result, nil
+	return result, errs.Err()
 }
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/integr8ly/heimdall/pkg/controller/operators"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/export"
+	"github.com/jedib0t/go-pretty/table"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8sjson "k8s.io/apimachinery/pkg/runtime/serializer/json"
+)
+
+// The formats -output supports
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputCSV   = "csv"
+	outputSARIF = "sarif"
+	outputCDX   = "cyclonedx"
+	outputHTML  = "html"
+	outputMD    = "markdown"
+)
+
+var outputFormats = []string{outputTable, outputJSON, outputYAML, outputCSV, outputSARIF, outputCDX, outputHTML, outputMD}
+
+func isOutputFormat(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceReports are the reports for the workloads in a namespace
+type namespaceReports struct {
+	namespace string
+	reports   []domain.ReportResult
+}
+
+// reportDocument is the document written by the json and yaml formats. It implements runtime.Object so it can be
+// written as yaml by the apimachinery serializer.
+type reportDocument struct {
+	Reports []report `json:"reports"`
+}
+
+func (d *reportDocument) GetObjectKind() schema.ObjectKind {
+	return schema.EmptyObjectKind
+}
+
+func (d *reportDocument) DeepCopyObject() runtime.Object {
+	reports := make([]report, len(d.Reports))
+	copy(reports, d.Reports)
+	return &reportDocument{Reports: reports}
+}
+
+// report is a domain.ReportResult as written by the json and yaml formats
+type report struct {
+	Namespace               string   `json:"namespace"`
+	Component               string   `json:"component"`
+	Image                   string   `json:"image"`
+	ImageRef                string   `json:"imageRef"`
+	ActualImageRef          string   `json:"actualImageRef,omitempty"`
+	MirrorPath              string   `json:"mirrorPath,omitempty"`
+	ImageStreamTag          string   `json:"imageStreamTag,omitempty"`
+	Pods                    []string `json:"pods,omitempty"`
+	Containers              []string `json:"containers,omitempty"`
+	InitContainers          []string `json:"initContainers,omitempty"`
+	Tag                     string   `json:"tag"`
+	CurrentVersion          string   `json:"currentVersion"`
+	LatestPatchVersion      string   `json:"latestPatchVersion"`
+	FloatingTag             string   `json:"floatingTag"`
+	UsingFloatingTag        bool     `json:"usingFloatingTag"`
+	UpToDateWithTag         bool     `json:"upToDateWithTag"`
+	UpToDateWithFloatingTag bool     `json:"upToDateWithFloatingTag"`
+	CurrentGrade            string   `json:"currentGrade,omitempty"`
+	LatestGrade             string   `json:"latestGrade,omitempty"`
+	ResolvableCVEs          []cve    `json:"resolvableCVEs"`
+}
+
+type cve struct {
+	ID         string `json:"id"`
+	Severity   string `json:"severity"`
+	AdvisoryID string `json:"advisoryID,omitempty"`
+}
+
+func newReport(ns string, r domain.ReportResult) report {
+	image := r.ClusterImage
+	out := report{
+		Namespace:               ns,
+		Component:               r.Component,
+		Image:                   image.FullPath,
+		ImageRef:                image.SHA256Path,
+		ActualImageRef:          r.ActualImageRef,
+		MirrorPath:              image.MirrorPath,
+		Containers:              image.ContainerNames(domain.ContainerTypeContainer),
+		InitContainers:          image.ContainerNames(domain.ContainerTypeInit),
+		Tag:                     image.Tag,
+		CurrentVersion:          r.CurrentVersion,
+		LatestPatchVersion:      r.LatestAvailablePatchVersion,
+		FloatingTag:             r.FloatingTag,
+		UsingFloatingTag:        r.UsingFloatingTag,
+		UpToDateWithTag:         r.UpToDateWithOwnTag,
+		UpToDateWithFloatingTag: r.UpToDateWithFloatingTag,
+		CurrentGrade:            r.CurrentGrade,
+		LatestGrade:             r.LatestGrade,
+		ResolvableCVEs:          []cve{},
+	}
+	if image.ImageStreamTag != nil {
+		out.ImageStreamTag = image.ImageStreamTag.Namespace + "/" + image.ImageStreamTag.Name
+	}
+	seen := map[string]bool{}
+	for _, p := range image.Pods {
+		if p.Name != "" && !seen[p.Name] {
+			seen[p.Name] = true
+			out.Pods = append(out.Pods, p.Name)
+		}
+	}
+	sort.Strings(out.Pods)
+	for _, c := range r.ResolvableCVEs {
+		out.ResolvableCVEs = append(out.ResolvableCVEs, cve{ID: c.ID, Severity: c.Severity, AdvisoryID: c.AdvisoryID})
+	}
+	return out
+}
+
+// writeReports writes the reports to w in format, which is one of outputFormats
+func writeReports(w io.Writer, format string, results []namespaceReports) error {
+	reports := []report{}
+	for _, nr := range results {
+		for _, r := range nr.reports {
+			reports = append(reports, newReport(nr.namespace, r))
+		}
+	}
+	doc := &reportDocument{Reports: reports}
+	switch format {
+	case outputTable:
+		renderTable(w, results)
+		return nil
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case outputYAML:
+		return k8sjson.NewYAMLSerializer(k8sjson.DefaultMetaFactory, nil, nil).Encode(doc, w)
+	case outputCSV:
+		return writeCSV(w, reports)
+	case outputSARIF:
+		return export.WriteSARIF(w, exportReports(results))
+	case outputCDX:
+		return export.WriteCycloneDX(w, exportReports(results))
+	case outputHTML:
+		return export.WriteHTML(w, exportReports(results))
+	case outputMD:
+		return export.WriteMarkdown(w, exportReports(results))
+	}
+	return fmt.Errorf("unknown output format %s, expected one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// exportReports flattens the results into the reports the export formats are written from
+func exportReports(results []namespaceReports) []export.Report {
+	reports := []export.Report{}
+	for _, nr := range results {
+		for _, r := range nr.reports {
+			reports = append(reports, export.Report{Namespace: nr.namespace, ReportResult: r})
+		}
+	}
+	return reports
+}
+
+// writeCSV writes a row for each resolvable CVE of each report, or a single row without a CVE for a report without
+// any, so the CVEs can be filtered and counted with spreadsheet tools
+func writeCSV(w io.Writer, reports []report) error {
+	cw := csv.NewWriter(w)
+	header := []string{"namespace", "component", "image", "image_ref", "containers", "init_containers", "tag",
+		"current_version", "latest_patch_version", "floating_tag", "using_floating_tag", "up_to_date_with_tag",
+		"up_to_date_with_floating_tag", "cve_id", "cve_severity", "cve_advisory_id"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, r := range reports {
+		row := []string{r.Namespace, r.Component, r.Image, r.ImageRef, strings.Join(r.Containers, ";"),
+			strings.Join(r.InitContainers, ";"), r.Tag, r.CurrentVersion, r.LatestPatchVersion, r.FloatingTag,
+			strconv.FormatBool(r.UsingFloatingTag), strconv.FormatBool(r.UpToDateWithTag),
+			strconv.FormatBool(r.UpToDateWithFloatingTag)}
+		cves := r.ResolvableCVEs
+		if len(cves) == 0 {
+			cves = []cve{{}}
+		}
+		for _, c := range cves {
+			if err := cw.Write(append(row, c.ID, c.Severity, c.AdvisoryID)); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// renderTable renders the reports as a table with the number of CVEs of each severity
+func renderTable(w io.Writer, results []namespaceReports) {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+
+	t.AppendHeader(table.Row{"component", "Image", "Containers", "Image Hash", "Image Stream", "Tag", "UpTo Date With Tag", "Persistent Image Tag", "Latest Patch Tag", "Floating Tag", "Using Floating Tag", "Upto Date with Floating Tag", "Critical CVEs", "Important CVEs", "Moderate CVEs"})
+	for _, nr := range results {
+		reports := nr.reports
+		for i := range reports {
+			t.AppendRows([]table.Row{
+				{reports[i].Component,
+					reports[i].ClusterImage.OrgImagePath,
+					containers(reports[i].ClusterImage),
+					reports[i].ClusterImage.GetSHAFromPath(),
+					reports[i].ClusterImage.FromImageStream,
+					reports[i].ClusterImage.Tag,
+					reports[i].UpToDateWithOwnTag,
+					reports[i].CurrentVersion,
+					reports[i].LatestAvailablePatchVersion,
+					reports[i].FloatingTag,
+					reports[i].UsingFloatingTag,
+					reports[i].UpToDateWithFloatingTag,
+					len(reports[i].GetResolvableCriticalCVEs()),
+					len(reports[i].GetResolvableImportantCVEs()),
+					len(reports[i].GetResolvableModerateCVEs())},
+			})
+		}
+	}
+	t.Render()
+}
+
+// renderOperatorUpdates lists the images of the installed operators that have a newer patch build fixing CVEs
+func renderOperatorUpdates(w io.Writer, updates []operators.Update) {
+	if len(updates) == 0 {
+		return
+	}
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{"Operator", "Image", "Tag", "Latest Patch Tag", "Critical CVEs", "Important CVEs", "Moderate CVEs"})
+	for _, u := range updates {
+		for _, r := range u.Images {
+			t.AppendRow(table.Row{
+				u.Operator,
+				r.ClusterImage.OrgImagePath,
+				r.CurrentVersion,
+				r.LatestAvailablePatchVersion,
+				len(r.GetResolvableCriticalCVEs()),
+				len(r.GetResolvableImportantCVEs()),
+				len(r.GetResolvableModerateCVEs()),
+			})
+		}
+	}
+	t.Render()
+}
+
+// containers lists the containers using the image with init containers marked as such
+func containers(image *domain.ClusterImage) string {
+	names := image.ContainerNames(domain.ContainerTypeContainer)
+	for _, c := range image.ContainerNames(domain.ContainerTypeInit) {
+		names = append(names, c+" (init)")
+	}
+	return strings.Join(names, ",")
+}
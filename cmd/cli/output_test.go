@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// cveRow is a resolvable CVE of a component as written by a format, components without any have a row without a CVE
+type cveRow struct {
+	Component, ID, Severity, AdvisoryID string
+}
+
+func documentRows(doc reportDocument) []cveRow {
+	var rows []cveRow
+	for _, r := range doc.Reports {
+		if len(r.ResolvableCVEs) == 0 {
+			rows = append(rows, cveRow{Component: r.Component})
+		}
+		for _, c := range r.ResolvableCVEs {
+			rows = append(rows, cveRow{Component: r.Component, ID: c.ID, Severity: c.Severity, AdvisoryID: c.AdvisoryID})
+		}
+	}
+	return rows
+}
+
+func TestWriteReports(t *testing.T) {
+	results := []namespaceReports{{
+		namespace: "fuse",
+		reports: []domain.ReportResult{
+			{
+				Component:      "broker",
+				CurrentVersion: "7.5-1",
+				ResolvableCVEs: []domain.CVE{
+					{ID: "CVE-2020-1", Severity: "Critical", AdvisoryID: "RHSA-2020:1"},
+					{ID: "CVE-2020-2", Severity: "Moderate"},
+				},
+				ClusterImage: &domain.ClusterImage{FullPath: "registry.redhat.io/amq7/amq-broker:7.5", SHA256Path: "registry.redhat.io/amq7/amq-broker@sha256:1"},
+			},
+			{
+				Component:    `console, "v2"`,
+				ClusterImage: &domain.ClusterImage{FullPath: "registry.redhat.io/ubi8/ubi:8.1", SHA256Path: "registry.redhat.io/ubi8/ubi@sha256:2"},
+			},
+		},
+	}}
+	expected := []cveRow{
+		{Component: "broker", ID: "CVE-2020-1", Severity: "Critical", AdvisoryID: "RHSA-2020:1"},
+		{Component: "broker", ID: "CVE-2020-2", Severity: "Moderate"},
+		{Component: `console, "v2"`},
+	}
+
+	cases := []struct {
+		Name     string
+		Format   string
+		Contains []string
+		Parse    func(t *testing.T, out []byte) []cveRow
+	}{
+		{
+			Name:     "test json has a report per image with its cves",
+			Format:   outputJSON,
+			Contains: []string{`"advisoryID": "RHSA-2020:1"`, `"resolvableCVEs": []`},
+			Parse: func(t *testing.T, out []byte) []cveRow {
+				var doc reportDocument
+				if err := json.Unmarshal(out, &doc); err != nil {
+					t.Fatal("expected valid json but got ", err)
+				}
+				return documentRows(doc)
+			},
+		},
+		{
+			Name:     "test yaml has a report per image with its cves",
+			Format:   outputYAML,
+			Contains: []string{"advisoryID: RHSA-2020:1", "resolvableCVEs: []"},
+			Parse: func(t *testing.T, out []byte) []cveRow {
+				var doc reportDocument
+				if err := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(out), len(out)).Decode(&doc); err != nil {
+					t.Fatal("expected valid yaml but got ", err)
+				}
+				return documentRows(doc)
+			},
+		},
+		{
+			Name:     "test csv has a row per cve with fields holding commas and quotes escaped",
+			Format:   outputCSV,
+			Contains: []string{`"console, ""v2"""`},
+			Parse: func(t *testing.T, out []byte) []cveRow {
+				records, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+				if err != nil {
+					t.Fatal("expected valid csv but got ", err)
+				}
+				header := strings.Join(records[0], ",")
+				if !strings.HasPrefix(header, "namespace,component,image,image_ref,") || !strings.HasSuffix(header, ",cve_id,cve_severity,cve_advisory_id") {
+					t.Fatal("unexpected csv header ", header)
+				}
+				var rows []cveRow
+				for _, r := range records[1:] {
+					if len(r) != len(records[0]) {
+						t.Fatal("expected every row to have a field per column but got ", r)
+					}
+					rows = append(rows, cveRow{Component: r[1], ID: r[len(r)-3], Severity: r[len(r)-2], AdvisoryID: r[len(r)-1]})
+				}
+				return rows
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			if err := writeReports(out, tc.Format, results); err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			for _, c := range tc.Contains {
+				if !strings.Contains(out.String(), c) {
+					t.Fatal("expected the output to contain ", c, " but got ", out.String())
+				}
+			}
+			rows := tc.Parse(t, out.Bytes())
+			if len(rows) != len(expected) {
+				t.Fatal("expected ", len(expected), " rows but got ", rows)
+			}
+			for i := range expected {
+				if rows[i] != expected[i] {
+					t.Fatal("expected ", expected[i], " but got ", rows[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteReports_UnknownFormat(t *testing.T) {
+	if err := writeReports(&bytes.Buffer{}, "xml", nil); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
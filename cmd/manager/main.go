@@ -15,6 +15,7 @@ import (
 	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/integr8ly/heimdall/pkg/apis"
 	"github.com/integr8ly/heimdall/pkg/controller"
+	"github.com/integr8ly/heimdall/pkg/export"
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	"github.com/operator-framework/operator-sdk/pkg/leader"
 	"github.com/operator-framework/operator-sdk/pkg/ready"
@@ -105,6 +106,11 @@ func main() {
 		log.Error(err, "")
 		os.Exit(1)
 	}
+	// ImageMonitors with alerts own a PrometheusRule
+	if err := monitoringv1.AddToScheme(mgr.GetScheme()); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
 
 	// Setup all Controllers
 	if err := controller.AddToManager(mgr); err != nil {
@@ -112,6 +118,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Serve the latest reports as SARIF and CycloneDX when an address is set, they are not authenticated
+	if addr := os.Getenv(export.EnvReportsAddress); addr != "" {
+		if err := export.AddToManager(mgr, addr); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
 	if err = serveCRMetrics(cfg); err != nil {
 		log.Info("Could not generate and serve custom resource metrics", "error", err.Error())
 	}
@@ -128,7 +142,7 @@ func main() {
 	// CreateServiceMonitors will automatically create the prometheus-operator ServiceMonitor resources
 	// necessary to configure Prometheus to scrape metrics from this operator.
 	services := []*v1.Service{service}
-	_, err = metrics.CreateServiceMonitors(cfg, operatorNamespace, services, addMonitoringKeyLabelToOperatorServiceMonitor)
+	_, err = metrics.CreateServiceMonitors(cfg, operatorNamespace, services, addMonitoringKeyLabelToOperatorServiceMonitor, honorScanLabels)
 	if err != nil {
 		log.Info("Could not create ServiceMonitor object", "error", err.Error())
 		// If this operator is deployed to a cluster without the prometheus-operator running, it will return
@@ -157,6 +171,15 @@ func addMonitoringKeyLabelToOperatorServiceMonitor(serviceMonitor *monitoringv1.
 	return nil
 }
 
+// honorScanLabels keeps the namespace label of the scan result metrics, which is the namespace of the scanned
+// workload, rather than replacing it with the namespace of the operator
+func honorScanLabels(serviceMonitor *monitoringv1.ServiceMonitor) error {
+	for i := range serviceMonitor.Spec.Endpoints {
+		serviceMonitor.Spec.Endpoints[i].HonorLabels = true
+	}
+	return nil
+}
+
 // serveCRMetrics gets the Operator/CustomResource GVKs and generates metrics based on those types.
 // It serves those metrics on "http://metricsHost:operatorMetricsPort".
 func serveCRMetrics(cfg *rest.Config) error {
@@ -0,0 +1,82 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterImageMonitorSpec defines the desired state of ClusterImageMonitor
+type ClusterImageMonitorSpec struct {
+	// NamespaceSelector selects the namespaces to monitor by their labels. All namespaces are selected when unset
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// NamespacePattern is a regular expression the name of a selected namespace must match to be monitored
+	NamespacePattern string `json:"namespacePattern,omitempty"`
+	ExcludePattern   string `json:"excludePattern,omitempty"`
+	// Registries decides which images are checked in namespaces without an ImageMonitor of their own
+	Registries     *RegistryPolicy `json:"registries,omitempty"`
+	WorkloadFilter `json:",inline"`
+	CheckSchedule  `json:",inline"`
+}
+
+// ClusterImageMonitorStatus defines the observed state of ClusterImageMonitor
+type ClusterImageMonitorStatus struct {
+	// Summary totals the results of the monitored workloads across all namespaces
+	Summary ScanSummary `json:"summary"`
+	// Namespaces holds the totals for each monitored namespace
+	Namespaces []NamespaceSummary `json:"namespaces,omitempty"`
+	Conditions []Condition        `json:"conditions,omitempty"`
+}
+
+// ScanSummary totals the results of the last check of a set of workloads
+type ScanSummary struct {
+	Workloads int `json:"workloads"`
+	// Checked is the number of workloads that have been checked at least once
+	Checked int `json:"checked"`
+	// Failed is the number of workloads whose last check failed
+	Failed int `json:"failed"`
+	// OutdatedImages is the number of images that have a newer patch tag available
+	OutdatedImages int       `json:"outdatedImages"`
+	ResolvableCVEs CVECounts `json:"resolvableCVEs"`
+}
+
+// NamespaceSummary totals the results of the monitored workloads in a namespace
+type NamespaceSummary struct {
+	Namespace   string `json:"namespace"`
+	ScanSummary `json:",inline"`
+}
+
+// SetCondition adds or updates the condition of the same type. The transition time is only changed when the status
+// of the condition changes.
+func (s *ClusterImageMonitorStatus) SetCondition(c Condition) {
+	s.Conditions = setCondition(s.Conditions, c)
+}
+
+// GetCondition returns the condition of type t or nil if it has not been set
+func (s *ClusterImageMonitorStatus) GetCondition(t ConditionType) *Condition {
+	return getCondition(s.Conditions, t)
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterImageMonitor is the Schema for the clusterimagemonitors API. It monitors the images of every namespace it
+// selects in the same way as an ImageMonitor in each of them.
+// +k8s:openapi-gen=true
+type ClusterImageMonitor struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterImageMonitorSpec   `json:"spec,omitempty"`
+	Status ClusterImageMonitorStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterImageMonitorList contains a list of ClusterImageMonitor
+type ClusterImageMonitorList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterImageMonitor `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterImageMonitor{}, &ClusterImageMonitorList{})
+}
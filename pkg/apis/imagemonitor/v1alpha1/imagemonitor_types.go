@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -10,11 +11,184 @@ import (
 // ImageMonitorSpec defines the desired state of ImageMonitor
 type ImageMonitorSpec struct {
 	ExcludePattern string `json:"excludePattern"`
+	// Registries decides which images are checked based on the registry they are pulled from
+	Registries     *RegistryPolicy `json:"registries,omitempty"`
+	WorkloadFilter `json:",inline"`
+	CheckSchedule  `json:",inline"`
+	// Alerts creates a PrometheusRule in the namespace alerting on the scan results of the monitored workloads. No
+	// rule is created when unset
+	Alerts *AlertRules `json:"alerts,omitempty"`
+}
+
+// AlertRules configures the alerts of the PrometheusRule created for an ImageMonitor
+type AlertRules struct {
+	// CriticalCVEsThreshold is the number of resolvable critical CVEs an image may have without alerting. Defaults to 0
+	CriticalCVEsThreshold int `json:"criticalCVEsThreshold,omitempty"`
+	// CriticalCVEsFor is how long an image must have more resolvable critical CVEs than the threshold before alerting.
+	// Defaults to 24h
+	CriticalCVEsFor *metav1.Duration `json:"criticalCVEsFor,omitempty"`
+	// OutdatedFor is how long an image may be behind the latest patch build of its tag before alerting. Defaults to 168h
+	OutdatedFor *metav1.Duration `json:"outdatedFor,omitempty"`
+	// Severity is the severity label of the alerts. Defaults to warning
+	Severity string `json:"severity,omitempty"`
+	// Labels are added to the PrometheusRule so the ruleSelector of a Prometheus selects it. Defaults to
+	// monitoring-key: middleware, the label of the operator's ServiceMonitor
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// CheckSchedule decides when the monitored workloads are rechecked. Only one of RecheckInterval and Schedule may be
+// set, when neither is the interval in HEIMDALL_RECHECK_MINS is used.
+type CheckSchedule struct {
+	// RecheckInterval is how long after a check a workload is checked again e.g. 6h
+	RecheckInterval *metav1.Duration `json:"recheckInterval,omitempty"`
+	// Schedule is a cron expression, evaluated in UTC, for when workloads are checked again e.g. "0 2 * * *" for daily
+	// at 02:00
+	Schedule string `json:"schedule,omitempty"`
+	// MaintenanceWindows are periods during which no checks are run, checks that become due run once the window ends
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+}
+
+// MaintenanceWindow is a period starting each time its cron expression matches and lasting for its duration
+type MaintenanceWindow struct {
+	// Start is a cron expression, evaluated in UTC, for when the window opens e.g. "0 22 * * sat"
+	Start    string          `json:"start"`
+	Duration metav1.Duration `json:"duration"`
+}
+
+// WorkloadFilter narrows down the workloads and containers that are monitored
+type WorkloadFilter struct {
+	// WorkloadSelector selects the workloads to monitor by their labels. All workloads are selected when unset
+	WorkloadSelector *metav1.LabelSelector `json:"workloadSelector,omitempty"`
+	// IncludePattern is a regular expression the name of a workload must match to be monitored
+	IncludePattern string `json:"includePattern,omitempty"`
+	// Kinds turns monitoring of each kind of workload on or off. All kinds are monitored by default
+	Kinds *WorkloadKinds `json:"kinds,omitempty"`
+	// ExcludeContainers is a list of regular expressions matched against container names to skip e.g. sidecars
+	ExcludeContainers []string `json:"excludeContainers,omitempty"`
+	// ExcludeImages is a list of regular expressions matched against the full path of images to skip
+	ExcludeImages []string `json:"excludeImages,omitempty"`
+}
+
+// WorkloadKinds turns monitoring of each kind of workload on or off. Unset kinds are monitored
+type WorkloadKinds struct {
+	DeploymentConfigs *bool `json:"deploymentConfigs,omitempty"`
+	Deployments       *bool `json:"deployments,omitempty"`
+	StatefulSets      *bool `json:"statefulSets,omitempty"`
+	DaemonSets        *bool `json:"daemonSets,omitempty"`
+	CronJobs          *bool `json:"cronJobs,omitempty"`
+	Jobs              *bool `json:"jobs,omitempty"`
+}
+
+// RegistryPolicy decides which images are checked based on the registry host they are pulled from
+type RegistryPolicy struct {
+	// Include is a list of regular expressions matched against the registry host. Defaults to the Red Hat registries
+	Include []string `json:"include,omitempty"`
+	// Exclude is a list of regular expressions matched against the registry host that take precedence over Include
+	Exclude []string `json:"exclude,omitempty"`
+	// Mirrors maps mirrored repositories back to their source in the same way as an ImageContentSourcePolicy
+	Mirrors []RepositoryMirror `json:"mirrors,omitempty"`
+}
+
+// RepositoryMirror lists the repositories that mirror a source repository
+type RepositoryMirror struct {
+	Source  string   `json:"source"`
+	Mirrors []string `json:"mirrors"`
 }
 
 // ImageMonitorStatus defines the observed state of ImageMonitor
 type ImageMonitorStatus struct {
-	Reports map[string]map[string]string `json:"reports"`
+	// Workloads holds the result of the last check of each monitored workload in the namespace
+	Workloads  []WorkloadStatus `json:"workloads,omitempty"`
+	Conditions []Condition      `json:"conditions,omitempty"`
+}
+
+// WorkloadStatus is the result of the last check of the images used by a workload
+type WorkloadStatus struct {
+	Kind string `json:"kind,omitempty"`
+	Name string `json:"name,omitempty"`
+	// LastChecked is unset until the workload has been checked
+	LastChecked *metav1.Time `json:"lastChecked,omitempty"`
+	// Error is set when the last check failed to check some or all of the images. Images and LastChecked are those of
+	// the images that were checked.
+	Error  string        `json:"error,omitempty"`
+	Images []ImageStatus `json:"images,omitempty"`
+}
+
+// ImageStatus is the result of checking an image used by containers of a workload
+type ImageStatus struct {
+	Containers              []string  `json:"containers,omitempty"`
+	InitContainers          []string  `json:"initContainers,omitempty"`
+	Image                   string    `json:"image"`
+	CurrentTag              string    `json:"currentTag,omitempty"`
+	LatestPatchTag          string    `json:"latestPatchTag,omitempty"`
+	FloatingTag             string    `json:"floatingTag,omitempty"`
+	UsingFloatingTag        bool      `json:"usingFloatingTag"`
+	UpToDateWithFloatingTag bool      `json:"upToDateWithFloatingTag"`
+	ResolvableCVEs          CVECounts `json:"resolvableCVEs"`
+}
+
+// CVECounts is the number of CVEs by severity that would be resolved by updating to the latest patch tag
+type CVECounts struct {
+	Critical  int `json:"critical"`
+	Important int `json:"important"`
+	Moderate  int `json:"moderate"`
+}
+
+// ConditionType is the type of an ImageMonitor condition
+type ConditionType string
+
+const (
+	// ConditionScanning is true while there are monitored workloads that have not been checked yet
+	ConditionScanning ConditionType = "Scanning"
+	// ConditionUpToDate is true when every checked image is on its latest patch tag
+	ConditionUpToDate ConditionType = "UpToDate"
+	// ConditionVulnerabilitiesFound is true when updating an image would resolve at least one CVE
+	ConditionVulnerabilitiesFound ConditionType = "VulnerabilitiesFound"
+	// ConditionDegraded is true when the last check of at least one workload failed
+	ConditionDegraded ConditionType = "Degraded"
+)
+
+// Condition describes an aspect of the state of the images in the namespace
+type Condition struct {
+	Type               ConditionType          `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+}
+
+// SetCondition adds or updates the condition of the same type. The transition time is only changed when the status
+// of the condition changes.
+func (s *ImageMonitorStatus) SetCondition(c Condition) {
+	s.Conditions = setCondition(s.Conditions, c)
+}
+
+// GetCondition returns the condition of type t or nil if it has not been set
+func (s *ImageMonitorStatus) GetCondition(t ConditionType) *Condition {
+	return getCondition(s.Conditions, t)
+}
+
+func setCondition(conditions []Condition, c Condition) []Condition {
+	for i := range conditions {
+		if conditions[i].Type != c.Type {
+			continue
+		}
+		if conditions[i].Status == c.Status {
+			c.LastTransitionTime = conditions[i].LastTransitionTime
+		}
+		conditions[i] = c
+		return conditions
+	}
+	return append(conditions, c)
+}
+
+func getCondition(conditions []Condition, t ConditionType) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == t {
+			return &conditions[i]
+		}
+	}
+	return nil
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -0,0 +1,88 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImageNotifierSpec defines what the monitored workloads in the namespace are notified about and where to
+type ImageNotifierSpec struct {
+	// MinSeverity is the lowest severity of a new resolvable CVE that is notified, one of critical, important, moderate
+	// or low. Defaults to critical
+	MinSeverity string `json:"minSeverity,omitempty"`
+	// NotifyOutdated also notifies when an image falls behind the latest patch build of its tag
+	NotifyOutdated bool `json:"notifyOutdated,omitempty"`
+	// Webhook posts each notification as JSON to a URL
+	Webhook *WebhookNotifier `json:"webhook,omitempty"`
+	// Slack posts each notification to a Slack compatible incoming webhook
+	Slack *SlackNotifier `json:"slack,omitempty"`
+	// Email sends each notification over SMTP
+	Email *EmailNotifier `json:"email,omitempty"`
+}
+
+// WebhookNotifier posts notifications to a URL
+type WebhookNotifier struct {
+	// URL to post to. Ignored when URLSecret is set
+	URL string `json:"url,omitempty"`
+	// URLSecret is the key of a Secret in the namespace holding the URL to post to
+	URLSecret *corev1.SecretKeySelector `json:"urlSecret,omitempty"`
+	// Headers are added to each request e.g. an Authorization header
+	Headers map[string]string `json:"headers,omitempty"`
+	// BodyTemplate is a Go template rendering the JSON body of each request from the notification. The notification
+	// is posted as JSON when unset
+	BodyTemplate string `json:"bodyTemplate,omitempty"`
+}
+
+// SlackNotifier posts notifications to a Slack compatible incoming webhook
+type SlackNotifier struct {
+	// URL of the incoming webhook. Ignored when URLSecret is set
+	URL string `json:"url,omitempty"`
+	// URLSecret is the key of a Secret in the namespace holding the URL of the incoming webhook
+	URLSecret *corev1.SecretKeySelector `json:"urlSecret,omitempty"`
+	// Channel overrides the channel of the incoming webhook
+	Channel string `json:"channel,omitempty"`
+}
+
+// EmailNotifier sends notifications over SMTP
+type EmailNotifier struct {
+	// SMTPServer is the host:port of the SMTP server
+	SMTPServer string   `json:"smtpServer"`
+	From       string   `json:"from"`
+	To         []string `json:"to"`
+	// CredentialsSecret is the name of a Secret in the namespace with username and password keys to authenticate with
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+}
+
+// ImageNotifierStatus defines the observed state of ImageNotifier
+type ImageNotifierStatus struct {
+	// LastSent is when a notification was last sent
+	LastSent *metav1.Time `json:"lastSent,omitempty"`
+	// LastError is the error of the last notification that failed to send
+	LastError string `json:"lastError,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageNotifier is the Schema for the imagenotifiers API. It notifies about new findings in the monitored workloads
+// of its namespace.
+// +k8s:openapi-gen=true
+type ImageNotifier struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageNotifierSpec   `json:"spec,omitempty"`
+	Status ImageNotifierStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageNotifierList contains a list of ImageNotifier
+type ImageNotifierList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImageNotifier `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImageNotifier{}, &ImageNotifierList{})
+}
@@ -0,0 +1,77 @@
+package clair
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/integr8ly/heimdall/pkg/customMetrics"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/transport"
+	"github.com/pkg/errors"
+)
+
+const vulnerabilityReport = "%s/matcher/api/v1/vulnerability_report/%s:%s"
+
+type digestGetter interface {
+	Get(ctx context.Context, ref string) (*domain.RemoteImageDigest, error)
+}
+
+// Client reads CVEs from the vulnerability report a Clair v4 instance holds for an image manifest. Clair reports are
+// keyed on the manifest digest so the tag is resolved to a digest using the registry first. The manifest must already
+// have been indexed by Clair (e.g. by the Quay security scanner).
+type Client struct {
+	Host    string
+	digests digestGetter
+}
+
+func NewClient(host string, digests digestGetter) *Client {
+	return &Client{Host: strings.TrimSuffix(host, "/"), digests: digests}
+}
+
+func (c *Client) CVES(ctx context.Context, repo, tag string) ([]domain.CVE, error) {
+	if repo == "" || tag == "" {
+		return nil, errors.New("expected a repo and a tag but got repo " + repo + " tag " + tag)
+	}
+	digest, err := c.digests.Get(ctx, repo+":"+tag)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the digest of "+repo+":"+tag)
+	}
+	resp, err := transport.Get(ctx, fmt.Sprintf(vulnerabilityReport, c.Host, digest.Algorithm, digest.Hash))
+	customMetrics.RegistryCallsTotal.Inc()
+	if err != nil {
+		customMetrics.RegistryCallsFailure.Inc()
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		customMetrics.RegistryCallsFailure.Inc()
+		return nil, errors.New("the manifest for " + repo + ":" + tag + " has not been indexed by clair")
+	}
+	if resp.StatusCode != http.StatusOK {
+		customMetrics.RegistryCallsFailure.Inc()
+		return nil, errors.New("unexpected response from clair " + resp.Status)
+	}
+	report := &VulnerabilityReport{}
+	if err := json.NewDecoder(resp.Body).Decode(report); err != nil {
+		customMetrics.RegistryCallsFailure.Inc()
+		return nil, err
+	}
+	var cves []domain.CVE
+	for _, v := range report.Vulnerabilities {
+		cve := domain.CVE{ID: v.Name, Severity: domain.NormaliseSeverity(v.NormalizedSeverity)}
+		// links are space separated, the first is the advisory for the vulnerability
+		if links := strings.Fields(v.Links); len(links) > 0 {
+			cve.AdvisoryID = links[0]
+		}
+		cves = append(cves, cve)
+	}
+	sort.Slice(cves, func(i, j int) bool {
+		return cves[i].ID < cves[j].ID
+	})
+	customMetrics.RegistryCallsSuccess.Inc()
+	return cves, nil
+}
@@ -0,0 +1,94 @@
+package clair_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/clair"
+	"github.com/integr8ly/heimdall/pkg/domain"
+)
+
+type digestGetter func(context.Context, string) (*domain.RemoteImageDigest, error)
+
+func (dg digestGetter) Get(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+	return dg(ctx, ref)
+}
+
+const report = `{
+  "manifest_hash": "sha256:abc",
+  "vulnerabilities": {
+    "2": {"id": "2", "name": "CVE-2020-2", "links": "https://access.redhat.com/errata/RHSA-2020:2 https://cve", "normalized_severity": "Medium"},
+    "1": {"id": "1", "name": "CVE-2020-1", "links": "", "normalized_severity": "Critical"}
+  }
+}`
+
+func TestClient_CVES(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Digests     digestGetter
+		ExpectError bool
+		Validate    func(t *testing.T, cves []domain.CVE)
+	}{
+		{
+			Name: "test cves are read from the vulnerability report for the tag digest",
+			Digests: func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+				if ref != "quay.io/org/image:1.0" {
+					return nil, errors.New("unexpected ref " + ref)
+				}
+				return domain.NewRemoteImageDigest("abc", "sha256"), nil
+			},
+			Validate: func(t *testing.T, cves []domain.CVE) {
+				if len(cves) != 2 {
+					t.Fatal("expected 2 cves but got ", len(cves))
+				}
+				if cves[0].ID != "CVE-2020-1" || cves[0].Severity != "critical" {
+					t.Fatal("unexpected cve ", cves[0])
+				}
+				if cves[1].Severity != "moderate" || cves[1].AdvisoryID != "https://access.redhat.com/errata/RHSA-2020:2" {
+					t.Fatal("unexpected cve ", cves[1])
+				}
+			},
+		},
+		{
+			Name: "test error when the manifest has not been indexed",
+			Digests: func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+				return domain.NewRemoteImageDigest("unknown", "sha256"), nil
+			},
+			ExpectError: true,
+		},
+		{
+			Name: "test error when the digest cannot be resolved",
+			Digests: func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+				return nil, errors.New("not found")
+			},
+			ExpectError: true,
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/matcher/api/v1/vulnerability_report/sha256:abc" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(report))
+	}))
+	defer server.Close()
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cves, err := clair.NewClient(server.URL, tc.Digests).CVES(context.TODO(), "quay.io/org/image", "1.0")
+			if tc.ExpectError && err == nil {
+				t.Fatal("expected an error but did not get one")
+			}
+			if !tc.ExpectError && err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if tc.Validate != nil {
+				tc.Validate(t, cves)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package clair
+
+// VulnerabilityReport is the response from the Clair v4 matcher vulnerability_report endpoint
+type VulnerabilityReport struct {
+	ManifestHash           string                    `json:"manifest_hash"`
+	Vulnerabilities        map[string]*Vulnerability `json:"vulnerabilities"`
+	PackageVulnerabilities map[string][]string       `json:"package_vulnerabilities"`
+}
+
+type Vulnerability struct {
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Description        string `json:"description"`
+	Links              string `json:"links"`
+	Severity           string `json:"severity"`
+	NormalizedSeverity string `json:"normalized_severity"`
+	FixedInVersion     string `json:"fixed_in_version"`
+}
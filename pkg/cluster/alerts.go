@@ -0,0 +1,176 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultCriticalCVEsFor = 24 * time.Hour
+	defaultOutdatedFor     = 7 * 24 * time.Hour
+	defaultAlertSeverity   = "warning"
+)
+
+// defaultRuleLabels match the labels of the operator's ServiceMonitor so the Prometheus scraping heimdall also loads
+// its rules
+var defaultRuleLabels = map[string]string{"monitoring-key": "middleware"}
+
+// AlertRulesName is the name of the PrometheusRule created for an ImageMonitor
+func AlertRulesName(monitor *v1alpha1.ImageMonitor) string {
+	return "heimdall-" + monitor.Name
+}
+
+// NewAlertRules returns the PrometheusRule alerting on the scan results of the workloads in the namespace of monitor.
+// It is owned by the ImageMonitor so it is removed along with it.
+func NewAlertRules(monitor *v1alpha1.ImageMonitor) *monitoringv1.PrometheusRule {
+	alerts := monitor.Spec.Alerts
+	if alerts == nil {
+		alerts = &v1alpha1.AlertRules{}
+	}
+	criticalFor := defaultCriticalCVEsFor
+	if alerts.CriticalCVEsFor != nil {
+		criticalFor = alerts.CriticalCVEsFor.Duration
+	}
+	outdatedFor := defaultOutdatedFor
+	if alerts.OutdatedFor != nil {
+		outdatedFor = alerts.OutdatedFor.Duration
+	}
+	severity := alerts.Severity
+	if severity == "" {
+		severity = defaultAlertSeverity
+	}
+	labels := alerts.Labels
+	if len(labels) == 0 {
+		labels = defaultRuleLabels
+	}
+	ruleLabels := map[string]string{}
+	for k, v := range labels {
+		ruleLabels[k] = v
+	}
+
+	ns := monitor.Namespace
+	return &monitoringv1.PrometheusRule{
+		TypeMeta: metav1.TypeMeta{APIVersion: monitoringv1.SchemeGroupVersion.String(), Kind: monitoringv1.PrometheusRuleKind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      AlertRulesName(monitor),
+			Namespace: ns,
+			Labels:    ruleLabels,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(monitor, v1alpha1.SchemeGroupVersion.WithKind("ImageMonitor")),
+			},
+		},
+		Spec: monitoringv1.PrometheusRuleSpec{
+			Groups: []monitoringv1.RuleGroup{{
+				Name: "heimdall.rules",
+				Rules: []monitoringv1.Rule{
+					{
+						Alert: "HeimdallImageCriticalCVEs",
+						Expr: intstr.FromString(fmt.Sprintf(
+							`sum by (namespace, kind, component, container, image) (heimdall_image_resolvable_cves{namespace=%q,severity="critical"}) > %d`,
+							ns, alerts.CriticalCVEsThreshold)),
+						For:    promDuration(criticalFor),
+						Labels: map[string]string{"severity": severity},
+						Annotations: map[string]string{
+							"summary":     "Image {{ $labels.image }} has resolvable critical CVEs",
+							"description": "{{ $value }} critical CVEs in the image of container {{ $labels.container }} of {{ $labels.kind }} {{ $labels.namespace }}/{{ $labels.component }} are resolved by updating to the latest patch build of its tag.",
+						},
+					},
+					{
+						Alert:  "HeimdallImageOutdated",
+						Expr:   intstr.FromString(fmt.Sprintf(`heimdall_image_up_to_date{namespace=%q,tag="latest_patch"} == 0`, ns)),
+						For:    promDuration(outdatedFor),
+						Labels: map[string]string{"severity": severity},
+						Annotations: map[string]string{
+							"summary":     "Image {{ $labels.image }} is behind the latest patch build of its tag",
+							"description": "The image of container {{ $labels.container }} of {{ $labels.kind }} {{ $labels.namespace }}/{{ $labels.component }} has not been updated to the latest patch build of its tag for " + promDuration(outdatedFor) + ".",
+						},
+					},
+				},
+			}},
+		},
+	}
+}
+
+// promDuration formats d in the largest unit Prometheus accepts that represents it exactly
+func promDuration(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "0s"
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return fmt.Sprintf("%ds", d/time.Second)
+}
+
+// Alerts creates, updates and removes the PrometheusRules of ImageMonitors. Rules are read from the api server rather
+// than a cache so the operator does not depend on the PrometheusRule CRD being installed.
+type Alerts struct {
+	client client.Client
+	reader client.Reader
+}
+
+func NewAlerts(c client.Client, reader client.Reader) *Alerts {
+	return &Alerts{client: c, reader: reader}
+}
+
+// Ensure creates or updates the PrometheusRule of monitor, or removes it when monitor has no alerts. Clusters without
+// the prometheus operator are skipped.
+func (a *Alerts) Ensure(ctx context.Context, monitor *v1alpha1.ImageMonitor) error {
+	err := a.ensure(ctx, monitor)
+	if meta.IsNoMatchError(errors.Cause(err)) {
+		return nil
+	}
+	return err
+}
+
+func (a *Alerts) ensure(ctx context.Context, monitor *v1alpha1.ImageMonitor) error {
+	existing := &monitoringv1.PrometheusRule{}
+	key := client.ObjectKey{Namespace: monitor.Namespace, Name: AlertRulesName(monitor)}
+	err := a.reader.Get(ctx, key, existing)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to get prometheus rule "+key.Name)
+	}
+	found := err == nil
+
+	if monitor.Spec.Alerts == nil {
+		if !found || !metav1.IsControlledBy(existing, monitor) {
+			return nil
+		}
+		if err := a.client.Delete(ctx, existing); err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrap(err, "failed to delete prometheus rule "+key.Name)
+		}
+		return nil
+	}
+
+	desired := NewAlertRules(monitor)
+	if !found {
+		if err := a.client.Create(ctx, desired); err != nil {
+			return errors.Wrap(err, "failed to create prometheus rule "+key.Name)
+		}
+		return nil
+	}
+	if reflect.DeepEqual(existing.Spec, desired.Spec) && reflect.DeepEqual(existing.Labels, desired.Labels) &&
+		metav1.IsControlledBy(existing, monitor) {
+		return nil
+	}
+	existing.Spec = desired.Spec
+	existing.Labels = desired.Labels
+	existing.OwnerReferences = desired.OwnerReferences
+	if err := a.client.Update(ctx, existing); err != nil {
+		return errors.Wrap(err, "failed to update prometheus rule "+key.Name)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package cluster_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/integr8ly/heimdall/pkg/apis"
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/customMetrics"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+func imageMonitor(alerts *v1alpha1.AlertRules) *v1alpha1.ImageMonitor {
+	return &v1alpha1.ImageMonitor{
+		ObjectMeta: metav1.ObjectMeta{Name: "monitor", Namespace: "fuse", UID: "monitor-uid"},
+		Spec:       v1alpha1.ImageMonitorSpec{Alerts: alerts},
+	}
+}
+
+func TestNewAlertRules(t *testing.T) {
+	cases := []struct {
+		Name              string
+		Alerts            *v1alpha1.AlertRules
+		ExpectCriticalFor string
+		ExpectOutdatedFor string
+		ExpectThreshold   string
+		ExpectSeverity    string
+		ExpectLabels      map[string]string
+	}{
+		{
+			Name:              "test defaults are used for an empty alerts section",
+			Alerts:            &v1alpha1.AlertRules{},
+			ExpectCriticalFor: "24h",
+			ExpectOutdatedFor: "168h",
+			ExpectThreshold:   "> 0",
+			ExpectSeverity:    "warning",
+			ExpectLabels:      map[string]string{"monitoring-key": "middleware"},
+		},
+		{
+			Name: "test configured thresholds are used",
+			Alerts: &v1alpha1.AlertRules{
+				CriticalCVEsThreshold: 2,
+				CriticalCVEsFor:       &metav1.Duration{Duration: 90 * time.Minute},
+				OutdatedFor:           &metav1.Duration{Duration: 72 * time.Hour},
+				Severity:              "critical",
+				Labels:                map[string]string{"prometheus": "k8s"},
+			},
+			ExpectCriticalFor: "90m",
+			ExpectOutdatedFor: "72h",
+			ExpectThreshold:   "> 2",
+			ExpectSeverity:    "critical",
+			ExpectLabels:      map[string]string{"prometheus": "k8s"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			monitor := imageMonitor(tc.Alerts)
+			rule := cluster.NewAlertRules(monitor)
+			if rule.Name != "heimdall-monitor" || rule.Namespace != "fuse" {
+				t.Fatal("expected the rule to be named after the monitor in its namespace but got ", rule.Namespace, rule.Name)
+			}
+			if !metav1.IsControlledBy(rule, monitor) {
+				t.Fatal("expected the rule to be owned by the monitor")
+			}
+			if len(rule.Labels) != len(tc.ExpectLabels) || rule.Labels[firstKey(tc.ExpectLabels)] != tc.ExpectLabels[firstKey(tc.ExpectLabels)] {
+				t.Fatal("expected labels ", tc.ExpectLabels, " but got ", rule.Labels)
+			}
+			rules := rule.Spec.Groups[0].Rules
+			if len(rules) != 2 {
+				t.Fatal("expected a critical CVE and an outdated image alert but got ", len(rules))
+			}
+			critical, outdated := rules[0], rules[1]
+			if critical.For != tc.ExpectCriticalFor || outdated.For != tc.ExpectOutdatedFor {
+				t.Fatal("expected the alerts to be pending for ", tc.ExpectCriticalFor, " and ", tc.ExpectOutdatedFor, " but got ", critical.For, outdated.For)
+			}
+			if !strings.HasSuffix(critical.Expr.String(), tc.ExpectThreshold) || !strings.Contains(critical.Expr.String(), `namespace="fuse"`) {
+				t.Fatal("expected the critical CVEs in fuse above the threshold but got ", critical.Expr.String())
+			}
+			if !strings.Contains(outdated.Expr.String(), `namespace="fuse",tag="latest_patch"`) {
+				t.Fatal("expected the outdated images in fuse but got ", outdated.Expr.String())
+			}
+			if critical.Labels["severity"] != tc.ExpectSeverity || outdated.Labels["severity"] != tc.ExpectSeverity {
+				t.Fatal("expected severity ", tc.ExpectSeverity, " but got ", critical.Labels, outdated.Labels)
+			}
+		})
+	}
+}
+
+func TestNewAlertRules_OutdatedPinnedTag(t *testing.T) {
+	// an image pinned to a patch tag that has not been rebuilt is up to date with its own tag but not the latest patch
+	customMetrics.RecordScan("deployment", "fuse", "api", []domain.ReportResult{{
+		Component:                   "api",
+		CurrentVersion:              "1.0.0",
+		LatestAvailablePatchVersion: "1.0.3",
+		UpToDateWithOwnTag:          true,
+		ClusterImage: &domain.ClusterImage{
+			FullPath: "registry.redhat.io/org/image:1.0.0",
+			Pods:     []domain.PodAndContainerRef{{Name: "api-1", Namespace: "fuse", Containers: []string{"api"}}},
+		},
+	}}, time.Now(), true)
+	defer customMetrics.DeleteScan("deployment", "fuse", "api")
+
+	expr := cluster.NewAlertRules(imageMonitor(&v1alpha1.AlertRules{})).Spec.Groups[0].Rules[1].Expr.String()
+	if !strings.HasSuffix(expr, "} == 0") {
+		t.Fatal("expected the outdated alert to select the series that are 0 but got ", expr)
+	}
+	selector := map[string]string{}
+	for _, m := range strings.Split(expr[strings.Index(expr, "{")+1:strings.Index(expr, "}")], ",") {
+		kv := strings.SplitN(m, "=", 2)
+		selector[kv[0]] = strings.Trim(kv[1], `"`)
+	}
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatal("did not expect an error gathering the metrics ", err)
+	}
+	fires := false
+	for _, f := range families {
+		if f.GetName() != "heimdall_image_up_to_date" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			matched := 0
+			for _, l := range m.GetLabel() {
+				if v, ok := selector[l.GetName()]; ok && v == l.GetValue() {
+					matched++
+				}
+			}
+			if matched == len(selector) && m.GetGauge().GetValue() == 0 {
+				fires = true
+			}
+		}
+	}
+	if !fires {
+		t.Fatal("expected the outdated alert to fire for an image pinned to an outdated patch tag with ", expr)
+	}
+}
+
+func firstKey(m map[string]string) string {
+	for k := range m {
+		return k
+	}
+	return ""
+}
+
+func TestAlerts_Ensure(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal("did not expect an error building the scheme ", err)
+	}
+	if err := monitoringv1.AddToScheme(s); err != nil {
+		t.Fatal("did not expect an error building the scheme ", err)
+	}
+	c := fakeclient.NewFakeClientWithScheme(s)
+	alerts := cluster.NewAlerts(c, c)
+	ctx := context.TODO()
+	key := client.ObjectKey{Namespace: "fuse", Name: "heimdall-monitor"}
+
+	if err := alerts.Ensure(ctx, imageMonitor(nil)); err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	if err := c.Get(ctx, key, &monitoringv1.PrometheusRule{}); !k8serrors.IsNotFound(err) {
+		t.Fatal("expected no rule for a monitor without alerts but got ", err)
+	}
+
+	if err := alerts.Ensure(ctx, imageMonitor(&v1alpha1.AlertRules{})); err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	rule := &monitoringv1.PrometheusRule{}
+	if err := c.Get(ctx, key, rule); err != nil {
+		t.Fatal("expected the rule to be created but got ", err)
+	}
+
+	if err := alerts.Ensure(ctx, imageMonitor(&v1alpha1.AlertRules{Severity: "critical"})); err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	if err := c.Get(ctx, key, rule); err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	if rule.Spec.Groups[0].Rules[0].Labels["severity"] != "critical" {
+		t.Fatal("expected the rule to be updated but got ", rule.Spec.Groups[0].Rules[0].Labels)
+	}
+
+	if err := alerts.Ensure(ctx, imageMonitor(nil)); err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	if err := c.Get(ctx, key, &monitoringv1.PrometheusRule{}); !k8serrors.IsNotFound(err) {
+		t.Fatal("expected the rule to be removed once alerts are unset but got ", err)
+	}
+}
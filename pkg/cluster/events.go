@@ -0,0 +1,132 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// The reasons of the events recorded on monitored workloads and ImageMonitors
+const (
+	EventScanSucceeded       = "ScanSucceeded"
+	EventScanFailed          = "ScanFailed"
+	EventImageOutdated       = "ImageOutdated"
+	EventResolvableCVEsFound = "ResolvableCVEsFound"
+)
+
+// EventRecorderName is the component the events of heimdall are recorded as
+const EventRecorderName = "heimdall"
+
+// RecordScanEvents records the outcome of checking the images of a workload as events on it so they are shown by
+// oc describe: an event for each image that is behind the latest patch build of its tag or has resolvable CVEs, and
+// one for the check. The check is recorded as failed when checkErr is set as some images could not be checked.
+func RecordScanEvents(recorder record.EventRecorder, obj runtime.Object, reports []domain.ReportResult, checkErr error) {
+	outdated, vulnerable := 0, 0
+	for _, r := range reports {
+		image := r.ClusterImage.FullPath
+		if r.LatestAvailablePatchVersion != "" && r.CurrentVersion != r.LatestAvailablePatchVersion {
+			outdated++
+			recorder.Eventf(obj, corev1.EventTypeWarning, EventImageOutdated, "Image %s is on tag %s, patch build %s is available",
+				image, r.CurrentVersion, r.LatestAvailablePatchVersion)
+		}
+		if len(r.ResolvableCVEs) > 0 {
+			vulnerable++
+			recorder.Eventf(obj, corev1.EventTypeWarning, EventResolvableCVEsFound, "Updating image %s to %s resolves %s: %s",
+				image, fixedTag(r), cveCounts(r), cveIDs(r.ResolvableCVEs))
+		}
+	}
+	if checkErr != nil {
+		recorder.Eventf(obj, corev1.EventTypeWarning, EventScanFailed, "Checked %d images, %d are outdated and %d have resolvable CVEs, failed to check the others: %v",
+			len(reports), outdated, vulnerable, checkErr)
+		return
+	}
+	recorder.Eventf(obj, corev1.EventTypeNormal, EventScanSucceeded, "Checked %d images, %d are outdated and %d have resolvable CVEs",
+		len(reports), outdated, vulnerable)
+}
+
+// RecordScanFailure records a failed check of the images of a workload as an event on it
+func RecordScanFailure(recorder record.EventRecorder, obj runtime.Object, checkErr error) {
+	recorder.Eventf(obj, corev1.EventTypeWarning, EventScanFailed, "Failed to check the images: %v", checkErr)
+}
+
+// RecordConditionEvents records an event on an ImageMonitor for each of its conditions that changed to report a
+// problem, or to report the monitored workloads have all been checked
+func RecordConditionEvents(recorder record.EventRecorder, obj runtime.Object, previous, current []v1alpha1.Condition) {
+	changed := func(t v1alpha1.ConditionType, status corev1.ConditionStatus) *v1alpha1.Condition {
+		c := (&v1alpha1.ImageMonitorStatus{Conditions: current}).GetCondition(t)
+		if c == nil || c.Status != status {
+			return nil
+		}
+		if p := (&v1alpha1.ImageMonitorStatus{Conditions: previous}).GetCondition(t); p != nil && p.Status == status {
+			return nil
+		}
+		return c
+	}
+	if c := changed(v1alpha1.ConditionScanning, corev1.ConditionFalse); c != nil {
+		recorder.Event(obj, corev1.EventTypeNormal, EventScanSucceeded, "All monitored workloads have been checked")
+	}
+	if c := changed(v1alpha1.ConditionUpToDate, corev1.ConditionFalse); c != nil {
+		recorder.Event(obj, corev1.EventTypeWarning, EventImageOutdated, upperFirst(c.Message))
+	}
+	if c := changed(v1alpha1.ConditionVulnerabilitiesFound, corev1.ConditionTrue); c != nil {
+		recorder.Event(obj, corev1.EventTypeWarning, EventResolvableCVEsFound, upperFirst(c.Message))
+	}
+	if c := changed(v1alpha1.ConditionDegraded, corev1.ConditionTrue); c != nil {
+		recorder.Event(obj, corev1.EventTypeWarning, EventScanFailed, upperFirst(c.Message))
+	}
+}
+
+func fixedTag(r domain.ReportResult) string {
+	if r.LatestAvailablePatchVersion == "" {
+		return "its latest patch build"
+	}
+	return r.LatestAvailablePatchVersion
+}
+
+func cveCounts(r domain.ReportResult) string {
+	var counts []string
+	if n := len(r.GetResolvableCriticalCVEs()); n > 0 {
+		counts = append(counts, fmt.Sprintf("%d critical", n))
+	}
+	if n := len(r.GetResolvableImportantCVEs()); n > 0 {
+		counts = append(counts, fmt.Sprintf("%d important", n))
+	}
+	if n := len(r.GetResolvableModerateCVEs()); n > 0 {
+		counts = append(counts, fmt.Sprintf("%d moderate", n))
+	}
+	other := len(r.ResolvableCVEs) - len(r.GetResolvableCriticalCVEs()) - len(r.GetResolvableImportantCVEs()) - len(r.GetResolvableModerateCVEs())
+	if other > 0 {
+		counts = append(counts, fmt.Sprintf("%d other", other))
+	}
+	return strings.Join(counts, ", ") + " CVEs"
+}
+
+// maxEventCVEs limits the CVE IDs listed in an event, the full list is in the reports exported by the operator
+const maxEventCVEs = 5
+
+func cveIDs(cves []domain.CVE) string {
+	var ids []string
+	for _, c := range cves {
+		id := c.ID
+		if id == "" {
+			id = c.AdvisoryID
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > maxEventCVEs {
+		return strings.Join(ids[:maxEventCVEs], ", ") + fmt.Sprintf(" and %d more", len(ids)-maxEventCVEs)
+	}
+	return strings.Join(ids, ", ")
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
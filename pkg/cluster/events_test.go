@@ -0,0 +1,143 @@
+package cluster_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	v12 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// recorded drains the events of the fake recorder
+func recorded(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case e := <-recorder.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestRecordScanEvents(t *testing.T) {
+	cves := []domain.CVE{{ID: "CVE-1", Severity: "Critical"}, {ID: "CVE-2", Severity: "Important"}, {ID: "CVE-3", Severity: "Important"}}
+	for i := 4; i <= 8; i++ {
+		cves = append(cves, domain.CVE{AdvisoryID: "RHSA-2020:" + strconv.Itoa(i), Severity: "Low"})
+	}
+	cases := []struct {
+		Name         string
+		Reports      []domain.ReportResult
+		CheckErr     error
+		ExpectEvents []string
+	}{
+		{
+			Name: "test up to date image only records the check",
+			Reports: []domain.ReportResult{
+				{CurrentVersion: "1.0-2", LatestAvailablePatchVersion: "1.0-2", ClusterImage: &domain.ClusterImage{FullPath: "registry.redhat.io/org/image:1.0"}},
+			},
+			ExpectEvents: []string{"Normal ScanSucceeded Checked 1 images, 0 are outdated and 0 have resolvable CVEs"},
+		},
+		{
+			Name: "test outdated image with CVEs records an event for each",
+			Reports: []domain.ReportResult{
+				{CurrentVersion: "1.0-1", LatestAvailablePatchVersion: "1.0-2", ResolvableCVEs: cves, ClusterImage: &domain.ClusterImage{FullPath: "registry.redhat.io/org/image:1.0"}},
+			},
+			ExpectEvents: []string{
+				"Warning ImageOutdated Image registry.redhat.io/org/image:1.0 is on tag 1.0-1, patch build 1.0-2 is available",
+				"Warning ResolvableCVEsFound Updating image registry.redhat.io/org/image:1.0 to 1.0-2 resolves 1 critical, 2 important, 5 other CVEs: CVE-1, CVE-2, CVE-3, RHSA-2020:4, RHSA-2020:5 and 3 more",
+				"Normal ScanSucceeded Checked 1 images, 1 are outdated and 1 have resolvable CVEs",
+			},
+		},
+		{
+			Name: "test check with failed images is recorded as failed",
+			Reports: []domain.ReportResult{
+				{CurrentVersion: "1.0-2", LatestAvailablePatchVersion: "1.0-2", ClusterImage: &domain.ClusterImage{FullPath: "registry.redhat.io/org/image:1.0"}},
+			},
+			CheckErr:     errors.New("failed to check image registry.redhat.io/org/other:1.0: registry unavailable"),
+			ExpectEvents: []string{"Warning ScanFailed Checked 1 images, 0 are outdated and 0 have resolvable CVEs, failed to check the others: failed to check image registry.redhat.io/org/other:1.0: registry unavailable"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			cluster.RecordScanEvents(recorder, &v12.Deployment{}, tc.Reports, tc.CheckErr)
+			events := recorded(recorder)
+			if strings.Join(events, "\n") != strings.Join(tc.ExpectEvents, "\n") {
+				t.Fatal("expected events\n", strings.Join(tc.ExpectEvents, "\n"), "\nbut got\n", strings.Join(events, "\n"))
+			}
+		})
+	}
+}
+
+func TestRecordScanFailure(t *testing.T) {
+	recorder := record.NewFakeRecorder(1)
+	cluster.RecordScanFailure(recorder, &v12.Deployment{}, errors.New("registry unavailable"))
+	if events := recorded(recorder); len(events) != 1 || events[0] != "Warning ScanFailed Failed to check the images: registry unavailable" {
+		t.Fatal("expected a scan failed event but got ", events)
+	}
+}
+
+func TestRecordConditionEvents(t *testing.T) {
+	condition := func(t v1alpha1.ConditionType, status corev1.ConditionStatus, message string) v1alpha1.Condition {
+		return v1alpha1.Condition{Type: t, Status: status, Message: message}
+	}
+	problems := []v1alpha1.Condition{
+		condition(v1alpha1.ConditionScanning, corev1.ConditionFalse, ""),
+		condition(v1alpha1.ConditionUpToDate, corev1.ConditionFalse, "2 images have a newer patch tag available"),
+		condition(v1alpha1.ConditionVulnerabilitiesFound, corev1.ConditionTrue, "updating would resolve 1 critical CVE"),
+		condition(v1alpha1.ConditionDegraded, corev1.ConditionTrue, "the last check of 1 workloads failed: Deployment/api"),
+	}
+	cases := []struct {
+		Name         string
+		Previous     []v1alpha1.Condition
+		Current      []v1alpha1.Condition
+		ExpectEvents []string
+	}{
+		{
+			Name:    "test problems found on the first status are recorded",
+			Current: problems,
+			ExpectEvents: []string{
+				"Normal ScanSucceeded All monitored workloads have been checked",
+				"Warning ImageOutdated 2 images have a newer patch tag available",
+				"Warning ResolvableCVEsFound Updating would resolve 1 critical CVE",
+				"Warning ScanFailed The last check of 1 workloads failed: Deployment/api",
+			},
+		},
+		{
+			Name:     "test unchanged conditions are not recorded again",
+			Previous: problems,
+			Current:  problems,
+		},
+		{
+			Name: "test resolved problems are not recorded",
+			Previous: []v1alpha1.Condition{
+				condition(v1alpha1.ConditionScanning, corev1.ConditionTrue, ""),
+				condition(v1alpha1.ConditionUpToDate, corev1.ConditionFalse, ""),
+			},
+			Current: []v1alpha1.Condition{
+				condition(v1alpha1.ConditionScanning, corev1.ConditionTrue, ""),
+				condition(v1alpha1.ConditionUpToDate, corev1.ConditionTrue, ""),
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			monitor := &v1alpha1.ImageMonitor{ObjectMeta: metav1.ObjectMeta{Name: "monitor", Namespace: "fuse"}}
+			cluster.RecordConditionEvents(recorder, monitor, tc.Previous, tc.Current)
+			events := recorded(recorder)
+			if strings.Join(events, "\n") != strings.Join(tc.ExpectEvents, "\n") {
+				t.Fatal("expected events\n", strings.Join(tc.ExpectEvents, "\n"), "\nbut got\n", strings.Join(events, "\n"))
+			}
+		})
+	}
+}
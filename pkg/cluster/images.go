@@ -1,6 +1,7 @@
 package cluster
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	v13 "github.com/openshift/api/image/v1"
 	v14 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
@@ -31,7 +33,9 @@ func NewImageService(k8s kubernetes.Interface, ic v14.ImageV1Interface) *ImageSe
 }
 
 // if a deploymentconfig has triggers that use image change params this method will use those to find the underlying docker image no need to check all containers etc in this case
-func (is *ImageService) FindImagesFromImageChangeParams(defaultNS string, params []*v12.DeploymentTriggerImageChangeParams, dcLabels map[string]string) ([]*domain.ClusterImage, error) {
+// only the pods controlled by dc are referenced
+func (is *ImageService) FindImagesFromImageChangeParams(ctx context.Context, dc v1.Object, params []*v12.DeploymentTriggerImageChangeParams, dcLabels map[string]string) ([]*domain.ClusterImage, error) {
+	defaultNS := dc.GetNamespace()
 	var images []*domain.ClusterImage
 	var selectors []string
 	// build a label selector based on the deploymentconfig labels
@@ -39,12 +43,19 @@ func (is *ImageService) FindImagesFromImageChangeParams(defaultNS string, params
 		selectors = append(selectors, fmt.Sprintf("%s=%s", k, v))
 	}
 	log.V(10).Info("selector ", "s ", strings.Join(selectors, ","))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	// find all the pods that match the dc labels
 	pods, err := is.client.CoreV1().Pods(defaultNS).List(v1.ListOptions{LabelSelector: strings.Join(selectors, ",")})
 	if err != nil {
 		log.Error(err, "failed to list pods with labels "+strings.Join(selectors, ","))
 		return nil, errors.Wrap(err, "failed to list pods with labels "+strings.Join(selectors, ","))
 	}
+	owned, err := is.ownedPods(ctx, dc, pods.Items)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, p := range params {
 		var ns = p.From.Namespace
@@ -55,6 +66,9 @@ func (is *ImageService) FindImagesFromImageChangeParams(defaultNS string, params
 		if p.From.Kind != "ImageStreamTag" {
 			continue
 		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		// if the last triggered image is from the built in registry try find it on the ist
 		ist, err := is.imageClient.ImageStreamTags(ns).Get(p.From.Name, v1.GetOptions{})
 		if err != nil {
@@ -64,7 +78,10 @@ func (is *ImageService) FindImagesFromImageChangeParams(defaultNS string, params
 		if err != nil {
 			return nil, err
 		}
-		parsedImage := ParseImage(actualImage)
+		parsedImage, err := ParseImage(actualImage)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse the image of imagestreamtag "+ns+" "+p.From.Name)
+		}
 		parsedImage.FromImageStream = true
 		parsedImage.ImageStreamTag = ist
 		// if this is a local image ref we need to use the registry so as to avoid hitting the local registry
@@ -78,17 +95,23 @@ func (is *ImageService) FindImagesFromImageChangeParams(defaultNS string, params
 		}
 		parsedImage.SHA256Path = imageSHA
 		parsedImage.Pods = []domain.PodAndContainerRef{}
-		for _, p := range pods.Items {
+		for _, p := range owned {
 			podContainerRef := domain.PodAndContainerRef{}
 			podContainerRef.Namespace = p.Namespace
 			podContainerRef.Name = p.Name
 			podContainerRef.Containers = []string{}
+			podContainerRef.ContainerType = domain.ContainerTypeContainer
 			for _, c := range p.Spec.Containers {
 				if c.Image == imageSHA {
 					podContainerRef.Containers = append(podContainerRef.Containers, c.Name)
 				}
 			}
 			parsedImage.Pods = append(parsedImage.Pods, podContainerRef)
+			for _, c := range p.Spec.InitContainers {
+				if c.Image == imageSHA {
+					parsedImage.AddContainer(p.Name, p.Namespace, domain.ContainerTypeInit, c.Name)
+				}
+			}
 		}
 		images = append(images, parsedImage)
 		continue
@@ -113,87 +136,260 @@ func (is *ImageService) findImageAndSHABehindImageTag(tag *v13.ImageStreamTag) (
 }
 
 // Finds images in pods with the specified labels (doesn't return images if they are in image streams)
-func (is *ImageService) FindImagesFromLabels(ns string, deploymentLabels map[string]string) ([]*domain.ClusterImage, error) {
+func (is *ImageService) FindImagesFromLabels(ctx context.Context, ns string, deploymentLabels map[string]string) ([]*domain.ClusterImage, error) {
 	var selectors []string
-	var images []*domain.ClusterImage
 	for k, v := range deploymentLabels {
 		selectors = append(selectors, fmt.Sprintf("%s=%s", k, v))
 	}
 	log.V(10).Info("selector ", "s ", strings.Join(selectors, ","))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	pods, err := is.client.CoreV1().Pods(ns).List(v1.ListOptions{LabelSelector: strings.Join(selectors, ",")})
 	if err != nil {
 		log.Error(err, "failed to list pods with labels "+strings.Join(selectors, ","))
 		return nil, errors.Wrap(err, "failed to list pods with labels "+strings.Join(selectors, ","))
 	}
-	// create a unique set of images
-	imageIDS := map[string]*domain.ClusterImage{}
-	// get all images from pods
-	for _, p := range pods.Items {
-		pcr := domain.PodAndContainerRef{
-			Name:       p.Name,
-			Namespace:  p.Namespace,
-			Containers: []string{},
-		}
-		for _, cs := range p.Status.ContainerStatuses {
-			//check if this image is coming from the the internal registry if so skip
-			if strings.Contains(cs.ImageID, "docker-registry") {
-				log.Info("skipping image ", cs.ImageID, " as it is internal. It will be picked up as part of image stream checking ")
-				continue
-			}
-			imageID := strings.Replace(cs.ImageID, "docker-pullable://", "", 1)
+	return imagesFromPods(pods.Items), nil
+}
 
-			// check have we looked at this image already. Can happen when multiple pods or multiple containers with same image
-			var image *domain.ClusterImage
-			if i, ok := imageIDS[imageID]; ok {
-				image = i
-			} else {
-				image = ParseImage(cs.Image)
-				image.SHA256Path = imageID
+// DigestGetter looks up the digest of an image reference in its registry
+type DigestGetter interface {
+	Digest(ctx context.Context, ref string) (*domain.RemoteImageDigest, error)
+}
 
-			}
+// ImageFilter decides which of the images found in a pod template are checked so only those are resolved to digests
+type ImageFilter interface {
+	Checked(image *domain.ClusterImage) bool
+}
 
-			for _, c := range p.Spec.Containers {
-				pcr.Containers = append(pcr.Containers, c.Name)
+// FindImagesFromPodTemplate finds the images of the containers in the pod template of owner. The images of the running
+// pods with the template labels that owner controls are used when there are any, otherwise the image references in the template are resolved to
+// digests with the registry so workloads scaled to zero, failing to start or whose pods come and go, such as jobs, are
+// still checked. Only the template images accepted by filter are resolved, images that can not be parsed or resolved
+// are logged and skipped.
+func (is *ImageService) FindImagesFromPodTemplate(ctx context.Context, owner v1.Object, template corev1.PodTemplateSpec, filter ImageFilter, digests DigestGetter) ([]*domain.ClusterImage, error) {
+	ns := owner.GetNamespace()
+	// without labels every pod in the namespace would match
+	if len(template.Labels) > 0 {
+		var selectors []string
+		for k, v := range template.Labels {
+			selectors = append(selectors, fmt.Sprintf("%s=%s", k, v))
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pods, err := is.client.CoreV1().Pods(ns).List(v1.ListOptions{LabelSelector: strings.Join(selectors, ",")})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list pods with labels "+strings.Join(selectors, ","))
+		}
+		// completed pods can not be labelled
+		var running []corev1.Pod
+		for _, p := range pods.Items {
+			if p.Status.Phase == corev1.PodRunning {
+				running = append(running, p)
 			}
+		}
+		running, err = is.ownedPods(ctx, owner, running)
+		if err != nil {
+			return nil, err
+		}
+		if images := imagesFromPods(running); len(images) > 0 {
+			return images, nil
+		}
+	}
 
-			foundPod := false
-			for _, sp := range image.Pods {
-				if sp.Name == p.Name {
-					foundPod = true
-					break
-				}
+	var found []*domain.ClusterImage
+	imageRefs := map[string]*domain.ClusterImage{}
+	containers := []struct {
+		containerType domain.ContainerType
+		containers    []corev1.Container
+	}{
+		{domain.ContainerTypeInit, template.Spec.InitContainers},
+		{domain.ContainerTypeContainer, template.Spec.Containers},
+	}
+	for _, typed := range containers {
+		for _, c := range typed.containers {
+			// images from the internal registry are picked up as part of image stream checking
+			if strings.Contains(c.Image, "docker-registry") {
+				continue
 			}
-			if !foundPod {
-				image.Pods = append(image.Pods, pcr)
+			image, ok := imageRefs[c.Image]
+			if !ok {
+				var err error
+				if image, err = ParseImage(c.Image); err != nil {
+					log.Error(err, "skipping image of container "+c.Name+" in "+ns+"/"+owner.GetName())
+				} else {
+					found = append(found, image)
+				}
+				imageRefs[c.Image] = image
 			}
-			if _, ok := imageIDS[imageID]; !ok {
-				imageIDS[imageID] = image
-				images = append(images, image)
+			if image == nil {
+				continue
 			}
+			// there is no pod to label but the containers are kept so they can be excluded
+			image.AddContainer("", ns, typed.containerType, c.Name)
+		}
+	}
+
+	// the containers of an image are known now so the filter can skip images only used by excluded containers
+	var images []*domain.ClusterImage
+	for _, image := range found {
+		if filter != nil && !filter.Checked(image) {
+			continue
+		}
+		if err := resolveDigest(ctx, image, digests); err != nil {
+			log.Error(err, "skipping image in "+ns+"/"+owner.GetName())
+			continue
 		}
+		images = append(images, image)
 	}
 	return images, nil
 }
 
-// parse image it will look like
-func ParseImage(i string) *domain.ClusterImage {
-	reg := regexp.MustCompile("@?sha256")
-	si := reg.ReplaceAllString(i, "")
-
-	registryURLParts := strings.Split(si, "/")
-	imageParts := strings.Split(registryURLParts[len(registryURLParts)-1], ":")
-	registryURL := strings.Join(registryURLParts[:len(registryURLParts)-1], "/") + "/" + imageParts[0]
-	imagePath := strings.Split(si, "/")
-	imagePath[2] = strings.Split(imagePath[2], ":")[0] // remove the tag from the last part
-	if len(imageParts) == 1 {
-		imageParts = append(imageParts, "latest")
-	}
-	return &domain.ClusterImage{
-		FullPath:     i,
-		OrgImagePath: imagePath[1] + "/" + imagePath[2],
-		ImageName:    imageParts[0],
-		Tag:          imageParts[1],
-		RegistryPath: registryURL,
-		Org:          registryURLParts[1],
+// resolveImage parses an image reference, resolving a tag to the digest it currently points to in the registry
+func resolveImage(ctx context.Context, ref string, digests DigestGetter) (*domain.ClusterImage, error) {
+	image, err := ParseImage(ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveDigest(ctx, image, digests); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+// resolveDigest sets the SHA256Path of an image, looking up the digest its tag currently points to in the registry
+// unless it is referenced by digest
+func resolveDigest(ctx context.Context, image *domain.ClusterImage, digests DigestGetter) error {
+	if image.IsSHATag() {
+		image.SHA256Path = image.FullPath
+		return nil
+	}
+	digest, err := digests.Digest(ctx, image.FullPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to resolve the digest of image "+image.FullPath)
+	}
+	image.SHA256Path = image.RegistryPath + "@" + digest.Algorithm + ":" + digest.Hash
+	return nil
+}
+
+// imagesFromPods creates a unique set of the images used by the containers and init containers of the pods
+func imagesFromPods(pods []corev1.Pod) []*domain.ClusterImage {
+	var images []*domain.ClusterImage
+	imageIDS := map[string]*domain.ClusterImage{}
+	// get all images from pods
+	for _, p := range pods {
+		statuses := []struct {
+			containerType domain.ContainerType
+			statuses      []corev1.ContainerStatus
+		}{
+			{domain.ContainerTypeInit, p.Status.InitContainerStatuses},
+			{domain.ContainerTypeContainer, p.Status.ContainerStatuses},
+		}
+		for _, typed := range statuses {
+			for _, cs := range typed.statuses {
+				// the image has not been pulled yet
+				if cs.ImageID == "" {
+					continue
+				}
+				//check if this image is coming from the the internal registry if so skip
+				if strings.Contains(cs.ImageID, "docker-registry") {
+					log.Info("skipping image ", cs.ImageID, " as it is internal. It will be picked up as part of image stream checking ")
+					continue
+				}
+				imageID := strings.Replace(cs.ImageID, "docker-pullable://", "", 1)
+
+				// check have we looked at this image already. Can happen when multiple pods or multiple containers with same image
+				image, ok := imageIDS[imageID]
+				if !ok {
+					var err error
+					if image, err = ParseImage(cs.Image); err != nil {
+						log.Error(err, "skipping image of container "+cs.Name+" in pod "+p.Name)
+						continue
+					}
+					image.SHA256Path = imageID
+					imageIDS[imageID] = image
+					images = append(images, image)
+				}
+				image.AddContainer(p.Name, p.Namespace, typed.containerType, cs.Name)
+			}
+		}
+	}
+	return images
+}
+
+// The registry and organisation of image references without them, as the container runtimes default them e.g. nginx:1.19
+// is pulled from docker.io/library/nginx:1.19
+const (
+	defaultRegistry = "docker.io"
+	defaultOrg      = "library"
+)
+
+// ParseImage parses an image reference such as registry.redhat.io/3scale-amp26/system:1.10 or a reference by digest.
+// The registry and organisation are defaulted for short references like nginx:1.19 and the tag is the digest for a
+// reference by digest alone. An error is returned for references that can not be parsed.
+func ParseImage(i string) (*domain.ClusterImage, error) {
+	invalid := errors.New("invalid image reference " + i)
+	ref, tag := i, ""
+	if at := strings.Index(ref, "@"); at >= 0 {
+		digest := strings.SplitN(ref[at+1:], ":", 2)
+		if len(digest) != 2 || digest[0] == "" || digest[1] == "" {
+			return nil, invalid
+		}
+		ref, tag = ref[:at], digest[1]
+	}
+	segments := strings.Split(ref, "/")
+	host := defaultRegistry
+	if len(segments) > 1 && (strings.ContainsAny(segments[0], ".:") || segments[0] == "localhost") {
+		host, segments = segments[0], segments[1:]
+	}
+	name := segments[len(segments)-1]
+	repository := ref
+	if colon := strings.LastIndex(name, ":"); colon >= 0 {
+		// the tag takes precedence over the digest of a reference with both
+		tag, name = name[colon+1:], name[:colon]
+		if tag == "" {
+			return nil, invalid
+		}
+		segments[len(segments)-1] = name
+		repository = ref[:len(ref)-len(tag)-1]
+	}
+	if tag == "" {
+		tag = "latest"
+	}
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, invalid
+		}
+	}
+	fullPath := i
+	if host == defaultRegistry && len(segments) == 1 {
+		segments = []string{defaultOrg, name}
+	}
+	path := strings.Join(segments, "/")
+	if repository != host+"/"+path {
+		fullPath = host + "/" + path + strings.TrimPrefix(i, repository)
+	}
+	image := &domain.ClusterImage{
+		FullPath:     fullPath,
+		OrgImagePath: path,
+		ImageName:    name,
+		Tag:          tag,
+		RegistryPath: host + "/" + path,
+	}
+	if len(segments) > 1 {
+		image.Org = segments[0]
+	}
+	return image, nil
+}
+
+// MustParseImage is like ParseImage but panics when the reference can not be parsed. It is for references known to be
+// valid.
+func MustParseImage(i string) *domain.ClusterImage {
+	image, err := ParseImage(i)
+	if err != nil {
+		panic(err)
 	}
+	return image
 }
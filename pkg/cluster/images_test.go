@@ -1,6 +1,7 @@
 package cluster_test
 
 import (
+	"context"
 	"fmt"
 	"github.com/integr8ly/heimdall/pkg/cluster"
 	"github.com/integr8ly/heimdall/pkg/domain"
@@ -9,9 +10,12 @@ import (
 	v12 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
 	v12fake "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1/fake"
 	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	v13 "k8s.io/api/core/v1"
 	v14 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	testing2 "k8s.io/client-go/testing"
@@ -21,9 +25,10 @@ import (
 
 func TestParseImage(t *testing.T) {
 	cases := []struct {
-		Name   string
-		Image  string
-		Expect *domain.ClusterImage
+		Name        string
+		Image       string
+		Expect      *domain.ClusterImage
+		ExpectError bool
 	}{
 		{
 			Name:  "test parsing image with sha",
@@ -51,11 +56,123 @@ func TestParseImage(t *testing.T) {
 				SHA256Path:   "",
 			},
 		},
+		{
+			Name:  "test parsing a short image defaults the registry and organisation",
+			Image: "nginx:1.19",
+			Expect: &domain.ClusterImage{
+				FullPath:     "docker.io/library/nginx:1.19",
+				OrgImagePath: "library/nginx",
+				Tag:          "1.19",
+				ImageName:    "nginx",
+				RegistryPath: "docker.io/library/nginx",
+				Org:          "library",
+			},
+		},
+		{
+			Name:  "test parsing an image with an organisation defaults the registry and tag",
+			Image: "bitnami/redis",
+			Expect: &domain.ClusterImage{
+				FullPath:     "docker.io/bitnami/redis",
+				OrgImagePath: "bitnami/redis",
+				Tag:          "latest",
+				ImageName:    "redis",
+				RegistryPath: "docker.io/bitnami/redis",
+				Org:          "bitnami",
+			},
+		},
+		{
+			Name:  "test parsing an image directly under the registry host",
+			Image: "registry.access.redhat.com/ubi8:8.1",
+			Expect: &domain.ClusterImage{
+				FullPath:     "registry.access.redhat.com/ubi8:8.1",
+				OrgImagePath: "ubi8",
+				Tag:          "8.1",
+				ImageName:    "ubi8",
+				RegistryPath: "registry.access.redhat.com/ubi8",
+			},
+		},
+		{
+			Name:  "test parsing an image from a registry with a port",
+			Image: "localhost:5000/team/app:2",
+			Expect: &domain.ClusterImage{
+				FullPath:     "localhost:5000/team/app:2",
+				OrgImagePath: "team/app",
+				Tag:          "2",
+				ImageName:    "app",
+				RegistryPath: "localhost:5000/team/app",
+				Org:          "team",
+			},
+		},
+		{
+			Name:  "test parsing an image by digest",
+			Image: "registry.redhat.io/amq7/amq-broker@sha256:eb98e41a",
+			Expect: &domain.ClusterImage{
+				FullPath:     "registry.redhat.io/amq7/amq-broker@sha256:eb98e41a",
+				OrgImagePath: "amq7/amq-broker",
+				Tag:          "eb98e41a",
+				ImageName:    "amq-broker",
+				RegistryPath: "registry.redhat.io/amq7/amq-broker",
+				Org:          "amq7",
+			},
+		},
+		{
+			Name:  "test parsing a short image by digest",
+			Image: "nginx@sha256:eb98e41a",
+			Expect: &domain.ClusterImage{
+				FullPath:     "docker.io/library/nginx@sha256:eb98e41a",
+				OrgImagePath: "library/nginx",
+				Tag:          "eb98e41a",
+				ImageName:    "nginx",
+				RegistryPath: "docker.io/library/nginx",
+				Org:          "library",
+			},
+		},
+		{
+			Name:  "test parsing an image by tag and digest uses the tag",
+			Image: "quay.io/org/image:1.0@sha256:eb98e41a",
+			Expect: &domain.ClusterImage{
+				FullPath:     "quay.io/org/image:1.0@sha256:eb98e41a",
+				OrgImagePath: "org/image",
+				Tag:          "1.0",
+				ImageName:    "image",
+				RegistryPath: "quay.io/org/image",
+				Org:          "org",
+			},
+		},
+		{
+			Name:        "test an empty image is an error",
+			Image:       "",
+			ExpectError: true,
+		},
+		{
+			Name:        "test an image with an empty path segment is an error",
+			Image:       "quay.io//image:1.0",
+			ExpectError: true,
+		},
+		{
+			Name:        "test an image with an empty tag is an error",
+			Image:       "quay.io/org/image:",
+			ExpectError: true,
+		},
+		{
+			Name:        "test an image with an invalid digest is an error",
+			Image:       "quay.io/org/image@sha256",
+			ExpectError: true,
+		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
-			ci := cluster.ParseImage(tc.Image)
+			ci, err := cluster.ParseImage(tc.Image)
+			if tc.ExpectError {
+				if err == nil {
+					t.Fatal("expected an error but got ", ci)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
 			if !reflect.DeepEqual(*ci, *tc.Expect) {
 				t.Fatal("expected ", tc.Expect, " but got ", ci)
 			}
@@ -68,6 +185,30 @@ type podArgs struct {
 	NS      string
 	Image   string
 	ImageID string
+	Owner   *v14.OwnerReference
+}
+
+func controlledBy(kind, name string) *v14.OwnerReference {
+	controller := true
+	return &v14.OwnerReference{Kind: kind, Name: name, UID: types.UID(name + "-uid"), Controller: &controller}
+}
+
+// addGetReactor answers gets of resource with an object built by newObj for the requested name and a uid derived from it
+func addGetReactor(c *fake.Clientset, resource string, newObj func(meta v14.ObjectMeta) runtime.Object) {
+	c.AddReactor("get", resource, func(action testing2.Action) (handled bool, ret runtime.Object, err error) {
+		name := action.(testing2.GetAction).GetName()
+		return true, newObj(v14.ObjectMeta{Name: name, Namespace: action.GetNamespace(), UID: types.UID(name + "-uid")}), nil
+	})
+}
+
+// addReplicationControllers adds the replication controller dc-1 of deployment config dc and other-1 without a controller
+func addReplicationControllers(c *fake.Clientset) {
+	addGetReactor(c, "replicationcontrollers", func(meta v14.ObjectMeta) runtime.Object {
+		if meta.Name == "dc-1" {
+			meta.OwnerReferences = []v14.OwnerReference{*controlledBy("DeploymentConfig", "dc")}
+		}
+		return &v13.ReplicationController{ObjectMeta: meta}
+	})
 }
 
 func buildPodList(podArgs []podArgs) *v13.PodList {
@@ -76,11 +217,16 @@ func buildPodList(podArgs []podArgs) *v13.PodList {
 	}
 
 	for _, pa := range podArgs {
+		var owners []v14.OwnerReference
+		if pa.Owner != nil {
+			owners = []v14.OwnerReference{*pa.Owner}
+		}
 		pl.Items = append(pl.Items, v13.Pod{
 			TypeMeta: v14.TypeMeta{},
 			ObjectMeta: v14.ObjectMeta{
-				Namespace: pa.NS,
-				Name:      pa.Name,
+				Namespace:       pa.NS,
+				Name:            pa.Name,
+				OwnerReferences: owners,
 			},
 			Spec: v13.PodSpec{
 				Containers: []v13.Container{
@@ -136,8 +282,10 @@ func TestImageService_FindImagesFromImageChangeParams(t *testing.T) {
 						Name:  "test-pod",
 						Image: testImageRef,
 						NS:    "test",
+						Owner: controlledBy("ReplicationController", "dc-1"),
 					}}), nil
 				})
+				addReplicationControllers(c)
 				return c
 			},
 			ImageClient: func() v12.ImageV1Interface {
@@ -214,14 +362,22 @@ func TestImageService_FindImagesFromImageChangeParams(t *testing.T) {
 						NS:    "test",
 						Name:  "test-pod",
 						Image: testImageRef,
+						Owner: controlledBy("ReplicationController", "dc-1"),
 					}, {
 						NS:    "test",
 						Name:  "test-pod2",
 						Image: testImageRef,
+						Owner: controlledBy("ReplicationController", "dc-1"),
+					}, {
+						NS:    "test",
+						Name:  "other-pod",
+						Image: testImageRef,
+						Owner: controlledBy("ReplicationController", "other-1"),
 					}})
 					pl.Items[0].Spec.Containers = append(pl.Items[0].Spec.Containers, v13.Container{})
 					return true, pl, nil
 				})
+				addReplicationControllers(c)
 				return c
 			},
 			ImageClient: func() v12.ImageV1Interface {
@@ -280,6 +436,7 @@ func TestImageService_FindImagesFromImageChangeParams(t *testing.T) {
 					if image.Tag != "2.0" {
 						t.Fatal("expected the image tag to be 2.0 but got ", image.Tag)
 					}
+					// the pod of the other replication controller shares the labels but is not controlled by the dc
 					if len(image.Pods) != 2 {
 						t.Fatalf("expected a two pod references but got %v ", len(image.Pods))
 					}
@@ -297,7 +454,8 @@ func TestImageService_FindImagesFromImageChangeParams(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
 			is := cluster.NewImageService(tc.K8sClient(), tc.ImageClient())
-			images, err := is.FindImagesFromImageChangeParams(tc.Namespace, tc.ChangeParams, tc.DeploymentLabels)
+			dc := &v1.DeploymentConfig{ObjectMeta: v14.ObjectMeta{Name: "dc", Namespace: tc.Namespace, UID: "dc-uid"}}
+			images, err := is.FindImagesFromImageChangeParams(context.TODO(), dc, tc.ChangeParams, tc.DeploymentLabels)
 			if tc.ExpectErr && err == nil {
 				t.Fatal("expected an error but got none")
 			}
@@ -410,6 +568,56 @@ func TestImageService_FindImagesFromLabels(t *testing.T) {
 				}
 			},
 		},
+		{
+			Name:      "Test init containers are found and typed",
+			Namespace: "test",
+			Labels:    map[string]string{},
+			K8sClient: func() kubernetes.Interface {
+				c := &fake.Clientset{}
+				c.AddReactor("list", "pods", func(action testing2.Action) (handled bool, ret runtime.Object, err error) {
+					pl := buildPodList([]podArgs{{
+						NS:      "test",
+						Name:    "test-pod",
+						Image:   fmt.Sprintf(testImage, "0"),
+						ImageID: fmt.Sprintf(testImageID, "test0sha"),
+					}})
+					pl.Items[0].Status.ContainerStatuses[0].Name = "web"
+					pl.Items[0].Status.InitContainerStatuses = []v13.ContainerStatus{{
+						Name:    "migrate",
+						Image:   fmt.Sprintf(testImage, "1"),
+						ImageID: fmt.Sprintf(testImageID, "test1sha"),
+					}, {
+						Name:    "wait",
+						Image:   fmt.Sprintf(testImage, "0"),
+						ImageID: fmt.Sprintf(testImageID, "test0sha"),
+					}}
+					return true, pl, nil
+				})
+				return c
+			},
+			ImageClient: func() v12.ImageV1Interface {
+				return nil
+			},
+			Validate: func(t *testing.T, images []*domain.ClusterImage) {
+				if len(images) != 2 {
+					t.Fatalf("expected 2 cluster images but got %v", len(images))
+				}
+				for _, image := range images {
+					if image.SHA256Path == fmt.Sprintf(testImageSha, "test1sha") {
+						if len(image.Pods) != 1 || image.Pods[0].Type() != domain.ContainerTypeInit || image.Pods[0].Containers[0] != "migrate" {
+							t.Fatal("expected the init container image to be referenced by the init container but got ", image.Pods)
+						}
+						continue
+					}
+					if names := image.ContainerNames(domain.ContainerTypeContainer); len(names) != 1 || names[0] != "web" {
+						t.Fatal("expected the shared image to be used by container web but got ", names)
+					}
+					if names := image.ContainerNames(domain.ContainerTypeInit); len(names) != 1 || names[0] != "wait" {
+						t.Fatal("expected the shared image to be used by init container wait but got ", names)
+					}
+				}
+			},
+		},
 		{
 			Name:      "Expect error when fail to get pods",
 			Namespace: "test",
@@ -430,7 +638,7 @@ func TestImageService_FindImagesFromLabels(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
 			is := cluster.NewImageService(tc.K8sClient(), tc.ImageClient())
-			clusterImages, err := is.FindImagesFromLabels(tc.Namespace, tc.Labels)
+			clusterImages, err := is.FindImagesFromLabels(context.TODO(), tc.Namespace, tc.Labels)
 			if tc.ExpectErr && err == nil {
 				t.Fatal("expected an error but got none")
 			}
@@ -444,3 +652,222 @@ func TestImageService_FindImagesFromLabels(t *testing.T) {
 
 	}
 }
+
+type digestGetterFunc func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error)
+
+func (f digestGetterFunc) Digest(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+	return f(ctx, ref)
+}
+
+type imageFilterFunc func(image *domain.ClusterImage) bool
+
+func (f imageFilterFunc) Checked(image *domain.ClusterImage) bool {
+	return f(image)
+}
+
+func TestImageService_FindImagesFromPodTemplate(t *testing.T) {
+	var testImage = "registry.redhat.io/amq7/amq-online-1-address-space-controller:1.3"
+	var testImageSha = "registry.redhat.io/amq7/amq-online-1-address-space-controller@sha256:%v"
+	template := v13.PodTemplateSpec{
+		ObjectMeta: v14.ObjectMeta{Labels: map[string]string{"job-name": "nightly"}},
+		Spec: v13.PodSpec{Containers: []v13.Container{
+			{Name: "main", Image: testImage},
+			{Name: "sidecar", Image: testImage},
+			{Name: "pinned", Image: fmt.Sprintf(testImageSha, "pinned")},
+		}},
+	}
+	owner := &batchv1.Job{ObjectMeta: v14.ObjectMeta{Name: "nightly", Namespace: "test", UID: "nightly-uid"}}
+	podsOf := func(controller string, phase v13.PodPhase) func() kubernetes.Interface {
+		return func() kubernetes.Interface {
+			c := &fake.Clientset{}
+			c.AddReactor("list", "pods", func(action testing2.Action) (handled bool, ret runtime.Object, err error) {
+				pl := buildPodList([]podArgs{{
+					NS:      "test",
+					Name:    controller + "-abcde",
+					Image:   testImage,
+					ImageID: "docker-pullable://" + fmt.Sprintf(testImageSha, "running"),
+					Owner:   controlledBy("Job", controller),
+				}})
+				pl.Items[0].Labels = template.Labels
+				pl.Items[0].Status.Phase = phase
+				return true, pl, nil
+			})
+			addGetReactor(c, "jobs", func(meta v14.ObjectMeta) runtime.Object {
+				return &batchv1.Job{ObjectMeta: meta}
+			})
+			return c
+		}
+	}
+	podsWithPhase := func(phase v13.PodPhase) func() kubernetes.Interface {
+		return podsOf("nightly", phase)
+	}
+	resolved := digestGetterFunc(func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+		if ref != testImage {
+			t.Fatal("did not expect the digest of ", ref, " to be looked up")
+		}
+		return domain.NewRemoteImageDigest("resolved", "sha256"), nil
+	})
+	cases := []struct {
+		Name      string
+		K8sClient func() kubernetes.Interface
+		Digests   cluster.DigestGetter
+		Filter    cluster.ImageFilter
+		ExpectErr bool
+		Validate  func(t *testing.T, images []*domain.ClusterImage)
+	}{
+		{
+			Name:      "test images of running pods are used",
+			K8sClient: podsWithPhase(v13.PodRunning),
+			Digests:   resolved,
+			Validate: func(t *testing.T, images []*domain.ClusterImage) {
+				if len(images) != 1 || images[0].SHA256Path != fmt.Sprintf(testImageSha, "running") {
+					t.Fatal("expected the image of the running pod but got ", images)
+				}
+				if images[0].Pods[0].Name != "nightly-abcde" {
+					t.Fatal("expected the running pod to be referenced but got ", images[0].Pods)
+				}
+			},
+		},
+		{
+			Name:      "test template images are resolved when pods have completed",
+			K8sClient: podsWithPhase(v13.PodSucceeded),
+			Digests:   resolved,
+			Validate: func(t *testing.T, images []*domain.ClusterImage) {
+				if len(images) != 2 {
+					t.Fatal("expected the two images in the template but got ", len(images))
+				}
+				if images[0].SHA256Path != fmt.Sprintf(testImageSha, "resolved") || images[0].Tag != "1.3" {
+					t.Fatal("expected the tag to be resolved to its digest but got ", images[0].SHA256Path)
+				}
+				if pods := images[0].Pods; len(pods) != 1 || pods[0].Name != "" || !reflect.DeepEqual(pods[0].Containers, []string{"main", "sidecar"}) {
+					t.Fatal("expected the containers using the image without a pod but got ", pods)
+				}
+				if images[1].SHA256Path != fmt.Sprintf(testImageSha, "pinned") {
+					t.Fatal("expected an image referenced by digest to be used as is but got ", images[1].SHA256Path)
+				}
+			},
+		},
+		{
+			Name:      "test images of pods controlled by another workload with the same labels are not used",
+			K8sClient: podsOf("weekly", v13.PodRunning),
+			Digests:   resolved,
+			Validate: func(t *testing.T, images []*domain.ClusterImage) {
+				if len(images) != 2 || images[0].SHA256Path != fmt.Sprintf(testImageSha, "resolved") {
+					t.Fatal("expected the images in the template but got ", images)
+				}
+			},
+		},
+		{
+			Name: "test template images are resolved when scaled to zero",
+			K8sClient: func() kubernetes.Interface {
+				c := &fake.Clientset{}
+				c.AddReactor("list", "pods", func(action testing2.Action) (handled bool, ret runtime.Object, err error) {
+					return true, &v13.PodList{}, nil
+				})
+				return c
+			},
+			Digests: resolved,
+			Validate: func(t *testing.T, images []*domain.ClusterImage) {
+				if len(images) != 2 || images[0].SHA256Path != fmt.Sprintf(testImageSha, "resolved") {
+					t.Fatal("expected the images in the template but got ", images)
+				}
+			},
+		},
+		{
+			Name: "test template images are resolved when the running pods have not pulled their images",
+			K8sClient: func() kubernetes.Interface {
+				c := &fake.Clientset{}
+				c.AddReactor("list", "pods", func(action testing2.Action) (handled bool, ret runtime.Object, err error) {
+					pl := buildPodList([]podArgs{{NS: "test", Name: "nightly-abcde", Image: testImage, Owner: controlledBy("Job", "nightly")}})
+					pl.Items[0].Labels = template.Labels
+					pl.Items[0].Status.Phase = v13.PodRunning
+					return true, pl, nil
+				})
+				return c
+			},
+			Digests: resolved,
+			Validate: func(t *testing.T, images []*domain.ClusterImage) {
+				if len(images) != 2 || images[0].Pods[0].Name != "" {
+					t.Fatal("expected the images in the template but got ", images)
+				}
+			},
+		},
+		{
+			Name:      "test images whose digest can not be resolved are skipped",
+			K8sClient: podsWithPhase(v13.PodFailed),
+			Digests: digestGetterFunc(func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+				return nil, errors.New("registry unavailable")
+			}),
+			Validate: func(t *testing.T, images []*domain.ClusterImage) {
+				if len(images) != 1 || images[0].SHA256Path != fmt.Sprintf(testImageSha, "pinned") {
+					t.Fatal("expected only the image referenced by digest but got ", images)
+				}
+			},
+		},
+		{
+			Name:      "test images the filter does not check are not resolved",
+			K8sClient: podsWithPhase(v13.PodSucceeded),
+			Digests: digestGetterFunc(func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+				t.Fatal("did not expect the digest of ", ref, " to be looked up")
+				return nil, nil
+			}),
+			Filter: imageFilterFunc(func(image *domain.ClusterImage) bool {
+				return image.IsSHATag()
+			}),
+			Validate: func(t *testing.T, images []*domain.ClusterImage) {
+				if len(images) != 1 || images[0].SHA256Path != fmt.Sprintf(testImageSha, "pinned") {
+					t.Fatal("expected only the image accepted by the filter but got ", images)
+				}
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			is := cluster.NewImageService(tc.K8sClient(), nil)
+			images, err := is.FindImagesFromPodTemplate(context.TODO(), owner, template, tc.Filter, tc.Digests)
+			if tc.ExpectErr && err == nil {
+				t.Fatal("expected an error but got none")
+			}
+			if !tc.ExpectErr && err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if tc.Validate != nil {
+				tc.Validate(t, images)
+			}
+		})
+	}
+}
+
+func TestImageService_FindImagesFromPodTemplate_ShortImageNames(t *testing.T) {
+	owner := &appsv1.Deployment{ObjectMeta: v14.ObjectMeta{Name: "web", Namespace: "test", UID: "web-uid"}}
+	template := v13.PodTemplateSpec{
+		ObjectMeta: v14.ObjectMeta{Labels: map[string]string{"app": "web"}},
+		Spec: v13.PodSpec{Containers: []v13.Container{
+			{Name: "nginx", Image: "nginx:1.19"},
+			{Name: "ubi", Image: "registry.access.redhat.com/ubi8:8.1"},
+			{Name: "broken", Image: "quay.io//broken:1"},
+		}},
+	}
+	c := &fake.Clientset{}
+	c.AddReactor("list", "pods", func(action testing2.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &v13.PodList{}, nil
+	})
+	var looked []string
+	digests := digestGetterFunc(func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+		looked = append(looked, ref)
+		return domain.NewRemoteImageDigest("resolved", "sha256"), nil
+	})
+	images, err := cluster.NewImageService(c, nil).FindImagesFromPodTemplate(context.TODO(), owner, template, nil, digests)
+	if err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	if len(images) != 2 {
+		t.Fatal("expected the two images that can be parsed but got ", images)
+	}
+	if images[0].SHA256Path != "docker.io/library/nginx@sha256:resolved" || images[1].SHA256Path != "registry.access.redhat.com/ubi8@sha256:resolved" {
+		t.Fatal("expected the short images to be resolved but got ", images[0].SHA256Path, images[1].SHA256Path)
+	}
+	if !reflect.DeepEqual(looked, []string{"docker.io/library/nginx:1.19", "registry.access.redhat.com/ubi8:8.1"}) {
+		t.Fatal("expected the digests of the normalised references to be looked up but got ", looked)
+	}
+}
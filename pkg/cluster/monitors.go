@@ -0,0 +1,124 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/integr8ly/heimdall/pkg/registry"
+	"github.com/integr8ly/heimdall/pkg/schedule"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Monitors finds the ImageMonitor covering a namespace so its settings can be applied to the workloads it monitors
+type Monitors struct {
+	client client.Client
+}
+
+func NewMonitors(c client.Client) *Monitors {
+	return &Monitors{client: c}
+}
+
+// ForNamespace returns the ImageMonitor in ns or nil if there is none. If there is more than one the oldest is used.
+func (m *Monitors) ForNamespace(ctx context.Context, ns string) (*v1alpha1.ImageMonitor, error) {
+	list := &v1alpha1.ImageMonitorList{}
+	if err := m.client.List(ctx, list, &client.ListOptions{Namespace: ns}); err != nil {
+		return nil, errors.Wrap(err, "failed to list image monitors in namespace "+ns)
+	}
+	var monitor *v1alpha1.ImageMonitor
+	for i := range list.Items {
+		im := &list.Items[i]
+		if im.DeletionTimestamp != nil {
+			continue
+		}
+		if monitor == nil || im.CreationTimestamp.Before(&monitor.CreationTimestamp) {
+			monitor = im
+		}
+	}
+	return monitor, nil
+}
+
+// PolicyFor returns the registry policy of the ImageMonitor in namespace. Namespaces without an ImageMonitor use the
+// policy of the ClusterImageMonitor selecting them, otherwise the default policy is used.
+func (m *Monitors) PolicyFor(ctx context.Context, namespace string) (*registry.Policy, error) {
+	monitor, err := m.ForNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if monitor != nil {
+		return policyFor(monitor.Spec.Registries, monitor.Spec.WorkloadFilter)
+	}
+	clusterMonitor, err := m.ClusterMonitorFor(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if clusterMonitor == nil {
+		return registry.DefaultPolicy(), nil
+	}
+	return policyFor(clusterMonitor.Spec.Registries, clusterMonitor.Spec.WorkloadFilter)
+}
+
+func policyFor(registries *v1alpha1.RegistryPolicy, filter v1alpha1.WorkloadFilter) (*registry.Policy, error) {
+	policy := registry.DefaultPolicy()
+	if registries != nil {
+		var err error
+		if policy, err = PolicyFromSpec(registries); err != nil {
+			return nil, err
+		}
+	}
+	return policy.WithExclusions(filter.ExcludeContainers, filter.ExcludeImages)
+}
+
+// ScheduleFor returns the check schedule of the ImageMonitor in namespace. Namespaces without an ImageMonitor use the
+// schedule of the ClusterImageMonitor selecting them, otherwise workloads are rechecked after the default interval.
+func (m *Monitors) ScheduleFor(ctx context.Context, namespace string) (*schedule.Schedule, error) {
+	var spec v1alpha1.CheckSchedule
+	monitor, err := m.ForNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if monitor != nil {
+		spec = monitor.Spec.CheckSchedule
+	} else {
+		clusterMonitor, err := m.ClusterMonitorFor(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if clusterMonitor != nil {
+			spec = clusterMonitor.Spec.CheckSchedule
+		}
+	}
+	return ScheduleFromSpec(spec)
+}
+
+// ScheduleFromSpec converts the check schedule of an ImageMonitor to a schedule.Schedule
+func ScheduleFromSpec(spec v1alpha1.CheckSchedule) (*schedule.Schedule, error) {
+	if spec.RecheckInterval != nil && spec.Schedule != "" {
+		return nil, errors.New("only one of recheckInterval and schedule may be set")
+	}
+	interval, err := schedule.DefaultInterval()
+	if err != nil {
+		log.Error(err, "invalid default recheck interval")
+	}
+	if spec.RecheckInterval != nil {
+		interval = spec.RecheckInterval.Duration
+	}
+	var windows []schedule.Window
+	for _, w := range spec.MaintenanceWindows {
+		window, err := schedule.NewWindow(w.Start, w.Duration.Duration)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return schedule.New(interval, spec.Schedule, windows...)
+}
+
+// PolicyFromSpec converts the registry policy of an ImageMonitor to a registry.Policy
+func PolicyFromSpec(spec *v1alpha1.RegistryPolicy) (*registry.Policy, error) {
+	var mirrors []registry.Mirror
+	for _, m := range spec.Mirrors {
+		mirrors = append(mirrors, registry.Mirror{Source: m.Source, Mirrors: m.Mirrors})
+	}
+	return registry.NewPolicy(spec.Include, spec.Exclude, mirrors)
+}
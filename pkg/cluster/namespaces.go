@@ -0,0 +1,118 @@
+package cluster
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FilterNamespaces returns the namespaces that match the regular expression pattern. If pattern is empty all
+// namespaces are included.
+func FilterNamespaces(namespaces []string, pattern string) ([]string, error) {
+	if pattern == "" || len(namespaces) == 0 {
+		return namespaces, nil
+	}
+	re, err := regexp.Compile(strings.Trim(pattern, "\""))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		if re.MatchString(namespace) {
+			result = append(result, namespace)
+		}
+	}
+	return result, nil
+}
+
+// SelectsNamespace reports whether ns is selected by both the namespace selector and pattern of the ClusterImageMonitor
+func SelectsNamespace(monitor *v1alpha1.ClusterImageMonitor, ns *corev1.Namespace) (bool, error) {
+	selector, err := namespaceSelector(monitor)
+	if err != nil {
+		return false, err
+	}
+	if !selector.Matches(labels.Set(ns.Labels)) {
+		return false, nil
+	}
+	matched, err := FilterNamespaces([]string{ns.Name}, monitor.Spec.NamespacePattern)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to compile namespace pattern "+monitor.Spec.NamespacePattern)
+	}
+	return len(matched) == 1, nil
+}
+
+// SelectedNamespaces lists the names of the namespaces selected by the ClusterImageMonitor in order
+func (m *Monitors) SelectedNamespaces(ctx context.Context, monitor *v1alpha1.ClusterImageMonitor) ([]string, error) {
+	selector, err := namespaceSelector(monitor)
+	if err != nil {
+		return nil, err
+	}
+	list := &corev1.NamespaceList{}
+	if err := m.client.List(ctx, list, &client.ListOptions{LabelSelector: selector}); err != nil {
+		return nil, errors.Wrap(err, "failed to list namespaces")
+	}
+	var names []string
+	for _, ns := range list.Items {
+		if ns.Status.Phase == corev1.NamespaceTerminating {
+			continue
+		}
+		names = append(names, ns.Name)
+	}
+	names, err = FilterNamespaces(names, monitor.Spec.NamespacePattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compile namespace pattern "+monitor.Spec.NamespacePattern)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ClusterMonitorFor returns the ClusterImageMonitor selecting ns or nil if there is none. If there is more than one
+// the oldest is used.
+func (m *Monitors) ClusterMonitorFor(ctx context.Context, ns string) (*v1alpha1.ClusterImageMonitor, error) {
+	list := &v1alpha1.ClusterImageMonitorList{}
+	if err := m.client.List(ctx, list); err != nil {
+		return nil, errors.Wrap(err, "failed to list cluster image monitors")
+	}
+	if len(list.Items) == 0 {
+		return nil, nil
+	}
+	namespace := &corev1.Namespace{}
+	if err := m.client.Get(ctx, client.ObjectKey{Name: ns}, namespace); err != nil {
+		return nil, errors.Wrap(err, "failed to get namespace "+ns)
+	}
+	var monitor *v1alpha1.ClusterImageMonitor
+	for i := range list.Items {
+		cim := &list.Items[i]
+		if cim.DeletionTimestamp != nil {
+			continue
+		}
+		selected, err := SelectsNamespace(cim, namespace)
+		if err != nil {
+			log.Error(err, "ignoring invalid cluster image monitor", "name", cim.Name)
+			continue
+		}
+		if selected && (monitor == nil || cim.CreationTimestamp.Before(&monitor.CreationTimestamp)) {
+			monitor = cim
+		}
+	}
+	return monitor, nil
+}
+
+func namespaceSelector(monitor *v1alpha1.ClusterImageMonitor) (labels.Selector, error) {
+	if monitor.Spec.NamespaceSelector == nil {
+		return labels.Everything(), nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(monitor.Spec.NamespaceSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid namespace selector on cluster image monitor "+monitor.Name)
+	}
+	return selector, nil
+}
@@ -0,0 +1,184 @@
+package cluster_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/apis"
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func monitorsWith(t *testing.T, objects ...runtime.Object) *cluster.Monitors {
+	s := runtime.NewScheme()
+	if err := scheme.AddToScheme(s); err != nil {
+		t.Fatal("did not expect an error building the scheme ", err)
+	}
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal("did not expect an error building the scheme ", err)
+	}
+	return cluster.NewMonitors(fakeclient.NewFakeClientWithScheme(s, objects...))
+}
+
+func namespace(name string, labels map[string]string) *v1.Namespace {
+	return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestFilterNamespaces(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Pattern   string
+		Expect    []string
+		ExpectErr bool
+	}{
+		{
+			Name:   "test all namespaces are included without a pattern",
+			Expect: []string{"redhat-rhmi-fuse", "redhat-rhmi-amq", "default"},
+		},
+		{
+			Name:    "test only matching namespaces are included",
+			Pattern: "^redhat-rhmi-",
+			Expect:  []string{"redhat-rhmi-fuse", "redhat-rhmi-amq"},
+		},
+		{
+			Name:    "test quotes around the pattern are ignored",
+			Pattern: "\"fuse$\"",
+			Expect:  []string{"redhat-rhmi-fuse"},
+		},
+		{
+			Name:      "test error when the pattern is invalid",
+			Pattern:   "(",
+			ExpectErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			namespaces, err := cluster.FilterNamespaces([]string{"redhat-rhmi-fuse", "redhat-rhmi-amq", "default"}, tc.Pattern)
+			if tc.ExpectErr && err == nil {
+				t.Fatal("expected an error but got none")
+			}
+			if !tc.ExpectErr && err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if len(namespaces) != len(tc.Expect) {
+				t.Fatal("expected namespaces ", tc.Expect, " but got ", namespaces)
+			}
+			for i := range tc.Expect {
+				if namespaces[i] != tc.Expect[i] {
+					t.Fatal("expected namespaces ", tc.Expect, " but got ", namespaces)
+				}
+			}
+		})
+	}
+}
+
+func TestMonitors_SelectedNamespaces(t *testing.T) {
+	middleware := map[string]string{"monitoring-key": "middleware"}
+	monitors := monitorsWith(t,
+		namespace("redhat-rhmi-fuse", middleware),
+		namespace("redhat-rhmi-amq", nil),
+		namespace("middleware-other", middleware),
+	)
+	cases := []struct {
+		Name   string
+		Spec   v1alpha1.ClusterImageMonitorSpec
+		Expect []string
+	}{
+		{
+			Name:   "test all namespaces are selected by default",
+			Expect: []string{"middleware-other", "redhat-rhmi-amq", "redhat-rhmi-fuse"},
+		},
+		{
+			Name:   "test namespaces are selected by their labels",
+			Spec:   v1alpha1.ClusterImageMonitorSpec{NamespaceSelector: &metav1.LabelSelector{MatchLabels: middleware}},
+			Expect: []string{"middleware-other", "redhat-rhmi-fuse"},
+		},
+		{
+			Name: "test namespaces must match both the selector and the pattern",
+			Spec: v1alpha1.ClusterImageMonitorSpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: middleware},
+				NamespacePattern:  "^redhat-rhmi-",
+			},
+			Expect: []string{"redhat-rhmi-fuse"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			namespaces, err := monitors.SelectedNamespaces(context.TODO(), &v1alpha1.ClusterImageMonitor{Spec: tc.Spec})
+			if err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if len(namespaces) != len(tc.Expect) {
+				t.Fatal("expected namespaces ", tc.Expect, " but got ", namespaces)
+			}
+			for i := range tc.Expect {
+				if namespaces[i] != tc.Expect[i] {
+					t.Fatal("expected namespaces ", tc.Expect, " but got ", namespaces)
+				}
+			}
+		})
+	}
+}
+
+func TestMonitors_PolicyFor(t *testing.T) {
+	older := metav1.NewTime(time.Now().Add(-time.Hour))
+	monitors := monitorsWith(t,
+		namespace("cluster-only", map[string]string{"monitoring-key": "middleware"}),
+		namespace("own-monitor", map[string]string{"monitoring-key": "middleware"}),
+		namespace("unselected", nil),
+		&v1alpha1.ClusterImageMonitor{
+			ObjectMeta: metav1.ObjectMeta{Name: "newer", CreationTimestamp: metav1.Now()},
+			Spec:       v1alpha1.ClusterImageMonitorSpec{Registries: &v1alpha1.RegistryPolicy{Include: []string{"^newer.io$"}}},
+		},
+		&v1alpha1.ClusterImageMonitor{
+			ObjectMeta: metav1.ObjectMeta{Name: "older", CreationTimestamp: older},
+			Spec: v1alpha1.ClusterImageMonitorSpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring-key": "middleware"}},
+				Registries:        &v1alpha1.RegistryPolicy{Include: []string{"^quay.io$"}},
+			},
+		},
+		&v1alpha1.ImageMonitor{
+			ObjectMeta: metav1.ObjectMeta{Name: "monitor", Namespace: "own-monitor"},
+			Spec:       v1alpha1.ImageMonitorSpec{Registries: &v1alpha1.RegistryPolicy{Include: []string{"^registry.local$"}}},
+		},
+	)
+	cases := []struct {
+		Name      string
+		Namespace string
+		Allowed   string
+	}{
+		{
+			Name:      "test the image monitor in the namespace takes precedence",
+			Namespace: "own-monitor",
+			Allowed:   "registry.local",
+		},
+		{
+			Name:      "test the oldest cluster image monitor selecting the namespace is used",
+			Namespace: "cluster-only",
+			Allowed:   "quay.io",
+		},
+		{
+			Name:      "test cluster image monitors only apply to the namespaces they select",
+			Namespace: "unselected",
+			Allowed:   "newer.io",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			policy, err := monitors.PolicyFor(context.TODO(), tc.Namespace)
+			if err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if !policy.Allowed(&domain.ClusterImage{RegistryPath: tc.Allowed + "/org/image"}) {
+				t.Fatal("expected images from ", tc.Allowed, " to be allowed in namespace ", tc.Namespace)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// KindClusterServiceVersion is the kind of the OLM resource describing an installed operator version
+const KindClusterServiceVersion = "ClusterServiceVersion"
+
+// csvCopiedFrom is set by OLM on the copies of a ClusterServiceVersion it makes in the namespaces an operator watches
+const csvCopiedFrom = "olm.copiedFrom"
+
+var csvListKind = schema.GroupVersionKind{Group: "operators.coreos.com", Version: "v1alpha1", Kind: "ClusterServiceVersionList"}
+
+// InstalledOperator is an operator version installed by OLM and the images it uses
+type InstalledOperator struct {
+	// Name is the name of the ClusterServiceVersion, which includes the version of the operator
+	Name      string
+	Namespace string
+	Images    []*domain.ClusterImage
+}
+
+// Operators finds the operators installed by OLM from their ClusterServiceVersions. The OLM types are read as
+// unstructured objects so Heimdall does not depend on a particular version of OLM.
+type Operators struct {
+	client client.Client
+}
+
+func NewOperators(c client.Client) *Operators {
+	return &Operators{client: c}
+}
+
+// FindInstalled finds the operators installed in ns with the images of their deployments and the related images their
+// bundle declares, resolving the tags of the images accepted by filter to digests with digests. Images that can not be
+// parsed or resolved are logged and skipped. The copies OLM makes of a ClusterServiceVersion in the namespaces an
+// operator watches are skipped as they are reported in the namespace the operator is installed in.
+func (o *Operators) FindInstalled(ctx context.Context, ns string, filter ImageFilter, digests DigestGetter) ([]InstalledOperator, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(csvListKind)
+	if err := o.client.List(ctx, list, &client.ListOptions{Namespace: ns}); err != nil {
+		return nil, errors.Wrap(err, "failed to list cluster service versions in namespace "+ns)
+	}
+	var installed []InstalledOperator
+	for i := range list.Items {
+		csv := &list.Items[i]
+		if _, ok := csv.GetLabels()[csvCopiedFrom]; ok {
+			continue
+		}
+		op, err := operatorFromCSV(ctx, csv, filter, digests)
+		if err != nil {
+			return nil, err
+		}
+		installed = append(installed, op)
+	}
+	return installed, nil
+}
+
+// operatorFromCSV reads the images of the deployments and the related images of an operator's csv
+func operatorFromCSV(ctx context.Context, csv *unstructured.Unstructured, filter ImageFilter, digests DigestGetter) (InstalledOperator, error) {
+	op := InstalledOperator{Name: csv.GetName(), Namespace: csv.GetNamespace()}
+
+	var found []*domain.ClusterImage
+	imageRefs := map[string]*domain.ClusterImage{}
+	image := func(ref string) *domain.ClusterImage {
+		if i, ok := imageRefs[ref]; ok {
+			return i
+		}
+		i, err := ParseImage(ref)
+		if err != nil {
+			log.Error(err, "skipping image of cluster service version "+op.Namespace+"/"+op.Name)
+		} else {
+			found = append(found, i)
+		}
+		imageRefs[ref] = i
+		return i
+	}
+
+	deployments, _, err := unstructured.NestedSlice(csv.Object, "spec", "install", "spec", "deployments")
+	if err != nil {
+		return op, errors.Wrap(err, "failed to read the deployments of cluster service version "+op.Name)
+	}
+	for _, d := range deployments {
+		spec, ok, _ := unstructured.NestedMap(asMap(d), "spec")
+		if !ok {
+			continue
+		}
+		var deployment appsv1.DeploymentSpec
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &deployment); err != nil {
+			return op, errors.Wrap(err, "failed to read the deployments of cluster service version "+op.Name)
+		}
+		containers := []struct {
+			containerType domain.ContainerType
+			containers    []corev1.Container
+		}{
+			{domain.ContainerTypeInit, deployment.Template.Spec.InitContainers},
+			{domain.ContainerTypeContainer, deployment.Template.Spec.Containers},
+		}
+		for _, typed := range containers {
+			for _, c := range typed.containers {
+				i := image(c.Image)
+				if i == nil {
+					continue
+				}
+				// the pods are labelled through the deployment OLM creates
+				i.AddContainer("", op.Namespace, typed.containerType, c.Name)
+			}
+		}
+	}
+
+	related, _, err := unstructured.NestedSlice(csv.Object, "spec", "relatedImages")
+	if err != nil {
+		return op, errors.Wrap(err, "failed to read the related images of cluster service version "+op.Name)
+	}
+	for _, r := range related {
+		ref, _, _ := unstructured.NestedString(asMap(r), "image")
+		if ref == "" {
+			continue
+		}
+		image(ref)
+	}
+
+	// the containers of an image are known now so the filter can skip images only used by excluded containers
+	for _, i := range found {
+		if filter != nil && !filter.Checked(i) {
+			continue
+		}
+		if err := resolveDigest(ctx, i, digests); err != nil {
+			log.Error(err, "skipping image of cluster service version "+op.Namespace+"/"+op.Name)
+			continue
+		}
+		op.Images = append(op.Images, i)
+	}
+	return op, nil
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
@@ -0,0 +1,109 @@
+package cluster_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// csvLister lists cluster service versions, the fake client can not decode kinds without a registered type
+type csvLister struct {
+	client.Client
+	items []unstructured.Unstructured
+}
+
+func (l csvLister) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	list.(*unstructured.UnstructuredList).Items = l.items
+	return nil
+}
+
+func TestOperators_FindInstalled(t *testing.T) {
+	csv := func(name string, labels map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "operators.coreos.com/v1alpha1",
+			"kind":       "ClusterServiceVersion",
+			"metadata":   map[string]interface{}{"name": name, "namespace": "test", "labels": labels},
+			"spec": map[string]interface{}{
+				"version": "1.3.0",
+				"install": map[string]interface{}{
+					"strategy": "deployment",
+					"spec": map[string]interface{}{
+						"deployments": []interface{}{
+							map[string]interface{}{
+								"name": "amq-streams-cluster-operator",
+								"spec": map[string]interface{}{
+									"template": map[string]interface{}{
+										"spec": map[string]interface{}{
+											"containers": []interface{}{
+												map[string]interface{}{"name": "operator", "image": "registry.redhat.io/amq7/amq-streams-operator:1.3.0"},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				"relatedImages": []interface{}{
+					map[string]interface{}{"name": "operator", "image": "registry.redhat.io/amq7/amq-streams-operator:1.3.0"},
+					map[string]interface{}{"name": "kafka", "image": "registry.redhat.io/amq7/amq-streams-kafka-23@sha256:kafka"},
+				},
+			},
+		}}
+	}
+	c := csvLister{items: []unstructured.Unstructured{
+		*csv("amqstreams.v1.3.0", nil),
+		*csv("copied.v1.3.0", map[string]interface{}{"olm.copiedFrom": "openshift-operators"}),
+	}}
+	digests := digestGetterFunc(func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+		return domain.NewRemoteImageDigest("operator", "sha256"), nil
+	})
+	installed, err := cluster.NewOperators(c).FindInstalled(context.TODO(), "test", nil, digests)
+	if err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	if len(installed) != 1 || installed[0].Name != "amqstreams.v1.3.0" {
+		t.Fatal("expected only the csv that is not a copy but got ", installed)
+	}
+	images := installed[0].Images
+	if len(images) != 2 {
+		t.Fatal("expected the operator image once and the related kafka image but got ", images)
+	}
+	if images[0].SHA256Path != "registry.redhat.io/amq7/amq-streams-operator@sha256:operator" {
+		t.Fatal("expected the tag of the operator image to be resolved but got ", images[0].SHA256Path)
+	}
+	if names := images[0].ContainerNames(domain.ContainerTypeContainer); len(names) != 1 || names[0] != "operator" {
+		t.Fatal("expected the operator container to be recorded but got ", names)
+	}
+	if images[1].SHA256Path != "registry.redhat.io/amq7/amq-streams-kafka-23@sha256:kafka" || len(images[1].Pods) != 0 {
+		t.Fatal("expected the related image to be used as is but got ", images[1])
+	}
+
+	failing := digestGetterFunc(func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+		return nil, errors.New("registry unavailable")
+	})
+	installed, err = cluster.NewOperators(c).FindInstalled(context.TODO(), "test", nil, failing)
+	if err != nil {
+		t.Fatal("did not expect an image that fails to resolve to be an error but got ", err)
+	}
+	if images := installed[0].Images; len(images) != 1 || images[0].ImageName != "amq-streams-kafka-23" {
+		t.Fatal("expected the image that failed to resolve to be skipped but got ", images)
+	}
+
+	operatorOnly := imageFilterFunc(func(image *domain.ClusterImage) bool {
+		return image.ImageName == "amq-streams-operator"
+	})
+	installed, err = cluster.NewOperators(c).FindInstalled(context.TODO(), "test", operatorOnly, digests)
+	if err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	if images := installed[0].Images; len(images) != 1 || images[0].ImageName != "amq-streams-operator" {
+		t.Fatal("expected only the image accepted by the filter but got ", images)
+	}
+}
@@ -0,0 +1,174 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The kinds of object created by workloads that own their pods
+const (
+	KindReplicaSet            = "ReplicaSet"
+	KindReplicationController = "ReplicationController"
+	KindPod                   = "Pod"
+)
+
+// owners resolves the workload controlling a pod by following the controller references of the pod through the
+// replica sets, replication controllers and jobs in between. Lookups are cached so it should only be used for a
+// single check.
+type owners struct {
+	client kubernetes.Interface
+	ns     string
+	// controllers holds the controller of each object looked up, nil when the object has none or no longer exists
+	controllers map[types.UID]*metav1.OwnerReference
+}
+
+func newOwners(client kubernetes.Interface, ns string) *owners {
+	return &owners{
+		client:      client,
+		ns:          ns,
+		controllers: map[types.UID]*metav1.OwnerReference{},
+	}
+}
+
+// root returns the top most controller of obj or nil if obj has no controller
+func (o *owners) root(ctx context.Context, obj metav1.Object) (*metav1.OwnerReference, error) {
+	ref := metav1.GetControllerOf(obj)
+	for ref != nil {
+		parent, err := o.controllerOf(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			return ref, nil
+		}
+		ref = parent
+	}
+	return nil, nil
+}
+
+// controllerOf returns the controller of the object ref refers to. Only replica sets, replication controllers and jobs
+// are looked up, any other kind is the workload itself.
+func (o *owners) controllerOf(ctx context.Context, ref *metav1.OwnerReference) (*metav1.OwnerReference, error) {
+	if parent, ok := o.controllers[ref.UID]; ok {
+		return parent, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	obj, err := o.get(ref)
+	if err != nil && !k8serr.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to get the %s %s controlling pods in namespace %s", ref.Kind, ref.Name, o.ns)
+	}
+	var parent *metav1.OwnerReference
+	// an object recreated with the same name is not the one that was referenced
+	if obj != nil && obj.GetUID() == ref.UID {
+		parent = metav1.GetControllerOf(obj)
+	}
+	o.controllers[ref.UID] = parent
+	return parent, nil
+}
+
+func (o *owners) get(ref *metav1.OwnerReference) (metav1.Object, error) {
+	switch ref.Kind {
+	case KindReplicaSet:
+		rs, err := o.client.AppsV1().ReplicaSets(o.ns).Get(ref.Name, metav1.GetOptions{})
+		if err != nil || rs == nil {
+			return nil, err
+		}
+		return rs, nil
+	case KindReplicationController:
+		rc, err := o.client.CoreV1().ReplicationControllers(o.ns).Get(ref.Name, metav1.GetOptions{})
+		if err != nil || rc == nil {
+			return nil, err
+		}
+		return rc, nil
+	case KindJob:
+		job, err := o.client.BatchV1().Jobs(o.ns).Get(ref.Name, metav1.GetOptions{})
+		if err != nil || job == nil {
+			return nil, err
+		}
+		return job, nil
+	}
+	return nil, nil
+}
+
+// ownedPods returns the pods controlled by owner, directly or through the objects it creates. Pods matching the labels
+// of a workload are not necessarily its own when the label sets of workloads overlap.
+func (is *ImageService) ownedPods(ctx context.Context, owner metav1.Object, pods []corev1.Pod) ([]corev1.Pod, error) {
+	o := newOwners(is.client, owner.GetNamespace())
+	var owned []corev1.Pod
+	for _, p := range pods {
+		root, err := o.root(ctx, &p)
+		if err != nil {
+			return nil, err
+		}
+		if root != nil && root.UID == owner.GetUID() {
+			owned = append(owned, p)
+		}
+	}
+	return owned, nil
+}
+
+// OrphanImages are the images used by the pods of an object that is not a workload Heimdall monitors
+type OrphanImages struct {
+	// Kind is the kind of the top most controller of the pods or Pod for a pod without a controller
+	Kind   string
+	Name   string
+	Images []*domain.ClusterImage
+}
+
+// FindOrphanImages finds the images of the running pods in ns that are not controlled by a workload Heimdall monitors,
+// such as pods created on their own and the pods of replica sets created without a deployment. The images are grouped
+// by the top most controller of the pods.
+func (is *ImageService) FindOrphanImages(ctx context.Context, ns string) ([]OrphanImages, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	pods, err := is.client.CoreV1().Pods(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pods in namespace "+ns)
+	}
+	monitored := map[string]bool{}
+	for _, k := range workloadKinds {
+		monitored[k.kind] = true
+	}
+
+	o := newOwners(is.client, ns)
+	var orphans []OrphanImages
+	var orphanPods [][]corev1.Pod
+	index := map[types.UID]int{}
+	for _, p := range pods.Items {
+		if p.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		root, err := o.root(ctx, &p)
+		if err != nil {
+			return nil, err
+		}
+		if root == nil {
+			root = &metav1.OwnerReference{Kind: KindPod, Name: p.Name, UID: p.UID}
+		}
+		if monitored[root.Kind] {
+			continue
+		}
+		i, ok := index[root.UID]
+		if !ok {
+			i = len(orphans)
+			index[root.UID] = i
+			orphans = append(orphans, OrphanImages{Kind: root.Kind, Name: root.Name})
+			orphanPods = append(orphanPods, nil)
+		}
+		orphanPods[i] = append(orphanPods[i], p)
+	}
+	for i := range orphans {
+		orphans[i].Images = imagesFromPods(orphanPods[i])
+	}
+	return orphans, nil
+}
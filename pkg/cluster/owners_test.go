@@ -0,0 +1,60 @@
+package cluster_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	appsv1 "k8s.io/api/apps/v1"
+	v13 "k8s.io/api/core/v1"
+	v14 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestImageService_FindOrphanImages(t *testing.T) {
+	pod := func(name string, phase v13.PodPhase, owner *v14.OwnerReference) *v13.Pod {
+		p := &buildPodList([]podArgs{{
+			NS:      "test",
+			Name:    name,
+			Image:   "registry.redhat.io/test/" + name + ":1.0",
+			ImageID: "docker-pullable://registry.redhat.io/test/" + name + "@sha256:" + name,
+			Owner:   owner,
+		}}).Items[0]
+		p.Status.Phase = phase
+		return p
+	}
+	objects := []runtime.Object{
+		pod("debug", v13.PodRunning, nil),
+		pod("finished", v13.PodSucceeded, nil),
+		pod("web-abc-1", v13.PodRunning, controlledBy("ReplicaSet", "web-abc")),
+		pod("bare-rs-1", v13.PodRunning, controlledBy("ReplicaSet", "bare-rs")),
+		pod("bare-rs-2", v13.PodRunning, controlledBy("ReplicaSet", "bare-rs")),
+		pod("db-0", v13.PodRunning, controlledBy("StatefulSet", "db")),
+		&appsv1.ReplicaSet{ObjectMeta: v14.ObjectMeta{
+			Name:            "web-abc",
+			Namespace:       "test",
+			UID:             "web-abc-uid",
+			OwnerReferences: []v14.OwnerReference{*controlledBy("Deployment", "web")},
+		}},
+		&appsv1.ReplicaSet{ObjectMeta: v14.ObjectMeta{Name: "bare-rs", Namespace: "test", UID: "bare-rs-uid"}},
+	}
+	is := cluster.NewImageService(fake.NewSimpleClientset(objects...), nil)
+	orphans, err := is.FindOrphanImages(context.TODO(), "test")
+	if err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	found := map[string]cluster.OrphanImages{}
+	for _, o := range orphans {
+		found[o.Kind+"/"+o.Name] = o
+	}
+	if len(found) != 2 {
+		t.Fatal("expected the bare pod and replica set to be the only orphans but got ", orphans)
+	}
+	if o, ok := found["Pod/debug"]; !ok || len(o.Images) != 1 || o.Images[0].Pods[0].Name != "debug" {
+		t.Fatal("expected the running pod without a controller to be an orphan but got ", o)
+	}
+	if o, ok := found["ReplicaSet/bare-rs"]; !ok || len(o.Images) != 2 {
+		t.Fatal("expected the images of both pods of the replica set without a deployment but got ", o)
+	}
+}
@@ -25,13 +25,17 @@ func NewPods(c client.Client) *Pods {
 	return &Pods{client: c}
 }
 
-func (p *Pods) LabelPods(rep *domain.ReportResult) error {
+func (p *Pods) LabelPods(ctx context.Context, rep *domain.ReportResult) error {
 
 	var labelErrors = []error{}
 	for _, pd := range rep.ClusterImage.Pods {
+		if pd.Name == "" {
+			// found in a pod template, there is no pod to label
+			continue
+		}
 		log.Info("labeling pod with image info ", "pod ", pd.Name, "namespace", pd.Namespace)
 		pod := &v1.Pod{}
-		if err := p.client.Get(context.TODO(), client.ObjectKey{Name: pd.Name, Namespace: pd.Namespace}, pod); err != nil {
+		if err := p.client.Get(ctx, client.ObjectKey{Name: pd.Name, Namespace: pd.Namespace}, pod); err != nil {
 			log.Error(err, "failed to get the pod "+pd.Name+" in namespace "+pd.Namespace+" will try again later")
 			labelErrors = append(labelErrors, err)
 			continue
@@ -58,11 +62,12 @@ func (p *Pods) LabelPods(rep *domain.ReportResult) error {
 			pod.Labels[fmt.Sprintf(labelAggregateFormat, "updatedImageAvailable")] = fmt.Sprintf("%v", rep.UpToDateWithFloatingTag == false)
 			pod.Labels[fmt.Sprintf(LabelContainerFormat, c, "latestPatchImage")] = fmt.Sprintf("%v", rep.LatestAvailablePatchVersion)
 			pod.Labels[fmt.Sprintf(LabelContainerFormat, c, "currentImage")] = fmt.Sprintf("%v", rep.CurrentVersion)
+			pod.Labels[fmt.Sprintf(LabelContainerFormat, c, "containerType")] = string(pd.Type())
 			if rep.ClusterImage.FromImageStream {
 				pod.Annotations[fmt.Sprintf(LabelContainerFormat, c, "imagestreamTag")] = fmt.Sprintf("%v", rep.ClusterImage.ImageStreamTag.Name)
 				pod.Annotations[fmt.Sprintf(LabelContainerFormat, c, "imagestreamTagNamespace")] = fmt.Sprintf("%v", rep.ClusterImage.ImageStreamTag.Namespace)
 			}
-			if err := p.client.Update(context.TODO(), pod); err != nil {
+			if err := p.client.Update(ctx, pod); err != nil {
 				labelErrors = append(labelErrors, errors.Wrap(err, "failed to update pod with labels"))
 				continue
 			}
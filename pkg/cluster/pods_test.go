@@ -176,7 +176,7 @@ func TestPods_LabelPods(t *testing.T) {
 		t.Run(tc.Name, func(t *testing.T) {
 			client := fakeclient.NewFakeClient(tc.Pod)
 			pods := cluster.NewPods(client)
-			err := pods.LabelPods(tc.Report)
+			err := pods.LabelPods(context.TODO(), tc.Report)
 			if tc.ExpectErr && err == nil {
 				t.Fatal("expected and error but got none")
 			}
@@ -0,0 +1,94 @@
+package cluster
+
+import (
+	"regexp"
+
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// The kinds of workload that can be monitored
+const (
+	KindDeploymentConfig = "DeploymentConfig"
+	KindDeployment       = "Deployment"
+	KindStatefulSet      = "StatefulSet"
+	KindDaemonSet        = "DaemonSet"
+	KindCronJob          = "CronJob"
+	KindJob              = "Job"
+)
+
+// WorkloadSelection decides which workloads in a namespace are monitored
+type WorkloadSelection struct {
+	selector labels.Selector
+	include  *regexp.Regexp
+	exclude  *regexp.Regexp
+	kinds    *v1alpha1.WorkloadKinds
+}
+
+// NewWorkloadSelection creates a WorkloadSelection from the exclude pattern and workload filter of an ImageMonitor or
+// ClusterImageMonitor. A workload is selected when its kind is enabled, it matches the selector and include pattern
+// and it does not match the exclude pattern.
+func NewWorkloadSelection(excludePattern string, filter v1alpha1.WorkloadFilter) (*WorkloadSelection, error) {
+	s := &WorkloadSelection{selector: labels.Everything(), kinds: filter.Kinds}
+	if filter.WorkloadSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(filter.WorkloadSelector)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid workload selector")
+		}
+		s.selector = selector
+	}
+	var err error
+	if filter.IncludePattern != "" {
+		if s.include, err = regexp.Compile(filter.IncludePattern); err != nil {
+			return nil, errors.Wrap(err, "failed to compile pattern to include "+filter.IncludePattern)
+		}
+	}
+	if excludePattern != "" {
+		if s.exclude, err = regexp.Compile(excludePattern); err != nil {
+			return nil, errors.Wrap(err, "failed to compile pattern to exclude "+excludePattern)
+		}
+	}
+	return s, nil
+}
+
+// Selects reports whether the workload of kind should be monitored. Jobs created by a CronJob are not selected as they
+// are checked along with the CronJob.
+func (s *WorkloadSelection) Selects(kind string, obj metav1.Object) bool {
+	if !s.kindEnabled(kind) {
+		return false
+	}
+	if kind == KindJob && createdByCronJob(obj) {
+		return false
+	}
+	if !s.selector.Matches(labels.Set(obj.GetLabels())) {
+		return false
+	}
+	if s.include != nil && !s.include.MatchString(obj.GetName()) {
+		return false
+	}
+	return s.exclude == nil || !s.exclude.MatchString(obj.GetName())
+}
+
+func (s *WorkloadSelection) kindEnabled(kind string) bool {
+	if s.kinds == nil {
+		return true
+	}
+	var enabled *bool
+	switch kind {
+	case KindDeploymentConfig:
+		enabled = s.kinds.DeploymentConfigs
+	case KindDeployment:
+		enabled = s.kinds.Deployments
+	case KindStatefulSet:
+		enabled = s.kinds.StatefulSets
+	case KindDaemonSet:
+		enabled = s.kinds.DaemonSets
+	case KindCronJob:
+		enabled = s.kinds.CronJobs
+	case KindJob:
+		enabled = s.kinds.Jobs
+	}
+	return enabled == nil || *enabled
+}
@@ -0,0 +1,109 @@
+package cluster_test
+
+import (
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWorkloadSelection_Selects(t *testing.T) {
+	disabled := false
+	cases := []struct {
+		Name           string
+		ExcludePattern string
+		Filter         v1alpha1.WorkloadFilter
+		Kind           string
+		Workload       metav1.ObjectMeta
+		Expect         bool
+		ExpectErr      bool
+	}{
+		{
+			Name:     "test every workload is selected by default",
+			Kind:     cluster.KindDeployment,
+			Workload: metav1.ObjectMeta{Name: "syndesis-server"},
+			Expect:   true,
+		},
+		{
+			Name:           "test workloads matching the exclude pattern are not selected",
+			ExcludePattern: "^syndesis-",
+			Kind:           cluster.KindDeployment,
+			Workload:       metav1.ObjectMeta{Name: "syndesis-server"},
+		},
+		{
+			Name:     "test workloads must match the include pattern",
+			Filter:   v1alpha1.WorkloadFilter{IncludePattern: "^amq-"},
+			Kind:     cluster.KindStatefulSet,
+			Workload: metav1.ObjectMeta{Name: "syndesis-db"},
+		},
+		{
+			Name:           "test exclude pattern takes precedence over the include pattern",
+			ExcludePattern: "-build$",
+			Filter:         v1alpha1.WorkloadFilter{IncludePattern: "^amq-"},
+			Kind:           cluster.KindStatefulSet,
+			Workload:       metav1.ObjectMeta{Name: "amq-build"},
+		},
+		{
+			Name:     "test workloads must match the selector",
+			Filter:   v1alpha1.WorkloadFilter{WorkloadSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "fuse"}}},
+			Kind:     cluster.KindDeploymentConfig,
+			Workload: metav1.ObjectMeta{Name: "syndesis-server", Labels: map[string]string{"app": "fuse"}},
+			Expect:   true,
+		},
+		{
+			Name:     "test workloads of a disabled kind are not selected",
+			Filter:   v1alpha1.WorkloadFilter{Kinds: &v1alpha1.WorkloadKinds{DeploymentConfigs: &disabled}},
+			Kind:     cluster.KindDeploymentConfig,
+			Workload: metav1.ObjectMeta{Name: "syndesis-server"},
+		},
+		{
+			Name:     "test kinds that are not set are selected",
+			Filter:   v1alpha1.WorkloadFilter{Kinds: &v1alpha1.WorkloadKinds{DeploymentConfigs: &disabled}},
+			Kind:     cluster.KindDeployment,
+			Workload: metav1.ObjectMeta{Name: "syndesis-server"},
+			Expect:   true,
+		},
+		{
+			Name:     "test daemon sets can be disabled",
+			Filter:   v1alpha1.WorkloadFilter{Kinds: &v1alpha1.WorkloadKinds{DaemonSets: &disabled}},
+			Kind:     cluster.KindDaemonSet,
+			Workload: metav1.ObjectMeta{Name: "node-exporter"},
+		},
+		{
+			Name: "test jobs created by a cron job are checked with the cron job",
+			Kind: cluster.KindJob,
+			Workload: metav1.ObjectMeta{Name: "nightly-1589414400", OwnerReferences: []metav1.OwnerReference{
+				{Kind: cluster.KindCronJob, Name: "nightly"},
+			}},
+		},
+		{
+			Name:     "test jobs without a cron job are selected",
+			Kind:     cluster.KindJob,
+			Workload: metav1.ObjectMeta{Name: "migrate-db"},
+			Expect:   true,
+		},
+		{
+			Name:      "test error when the include pattern is invalid",
+			Filter:    v1alpha1.WorkloadFilter{IncludePattern: "("},
+			ExpectErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			selection, err := cluster.NewWorkloadSelection(tc.ExcludePattern, tc.Filter)
+			if tc.ExpectErr {
+				if err == nil {
+					t.Fatal("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if selected := selection.Selects(tc.Kind, &tc.Workload); selected != tc.Expect {
+				t.Fatal("expected selected to be ", tc.Expect, " but got ", selected)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NewWorkloadStatus converts the reports from checking the images of a workload to the status shown on its
+// ImageMonitor. checkErr is the failure of the images that could not be checked, if any.
+func NewWorkloadStatus(reports []domain.ReportResult, checkErr error, checked time.Time) v1alpha1.WorkloadStatus {
+	status := v1alpha1.WorkloadStatus{LastChecked: &metav1.Time{Time: checked}}
+	if checkErr != nil {
+		status.Error = checkErr.Error()
+	}
+	for _, rep := range reports {
+		status.Images = append(status.Images, v1alpha1.ImageStatus{
+			Containers:              rep.ClusterImage.ContainerNames(domain.ContainerTypeContainer),
+			InitContainers:          rep.ClusterImage.ContainerNames(domain.ContainerTypeInit),
+			Image:                   rep.ClusterImage.FullPath,
+			CurrentTag:              rep.CurrentVersion,
+			LatestPatchTag:          rep.LatestAvailablePatchVersion,
+			FloatingTag:             rep.FloatingTag,
+			UsingFloatingTag:        rep.UsingFloatingTag,
+			UpToDateWithFloatingTag: rep.UpToDateWithFloatingTag,
+			ResolvableCVEs: v1alpha1.CVECounts{
+				Critical:  len(rep.GetResolvableCriticalCVEs()),
+				Important: len(rep.GetResolvableImportantCVEs()),
+				Moderate:  len(rep.GetResolvableModerateCVEs()),
+			},
+		})
+	}
+	return status
+}
+
+// SetWorkloadStatus records the status on the annotations of the workload for the ImageMonitor controller to collect
+func SetWorkloadStatus(obj metav1.Object, status v1alpha1.WorkloadStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the status of "+obj.GetName())
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[domain.HeimdallStatus] = string(data)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// SetWorkloadFailure records a check of the workload that failed before any image was checked. The images and time of
+// its last check are kept so a failure does not hide what was found before.
+func SetWorkloadFailure(obj metav1.Object, checkErr error) error {
+	status, err := workloadStatus(obj)
+	if err != nil {
+		log.Error(err, "ignoring invalid status annotation", "name", obj.GetName(), "namespace", obj.GetNamespace())
+		status = v1alpha1.WorkloadStatus{}
+	}
+	status.Error = checkErr.Error()
+	return SetWorkloadStatus(obj, status)
+}
+
+// workloadStatus reads the status recorded on the annotations of the workload, an unchecked workload has an empty status
+func workloadStatus(obj metav1.Object) (v1alpha1.WorkloadStatus, error) {
+	status := v1alpha1.WorkloadStatus{}
+	data, ok := obj.GetAnnotations()[domain.HeimdallStatus]
+	if !ok {
+		return status, nil
+	}
+	if err := json.Unmarshal([]byte(data), &status); err != nil {
+		return v1alpha1.WorkloadStatus{}, errors.Wrap(err, "failed to decode the status of "+obj.GetName())
+	}
+	return status, nil
+}
+
+// WorkloadStatuses collects the statuses recorded on the monitored workloads in ns ordered by kind and name.
+// Workloads that have not been checked yet are included without a LastChecked time.
+func (m *Monitors) WorkloadStatuses(ctx context.Context, ns string) ([]v1alpha1.WorkloadStatus, error) {
+	objects, kinds, err := listWorkloads(ctx, m.client, ns)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []v1alpha1.WorkloadStatus
+	for i, obj := range objects {
+		if _, ok := obj.GetLabels()[domain.HeimdallMonitored]; !ok {
+			continue
+		}
+		status, err := workloadStatus(obj)
+		if err != nil {
+			log.Error(err, "ignoring invalid status annotation", "kind", kinds[i], "name", obj.GetName(), "namespace", ns)
+		}
+		status.Kind = kinds[i]
+		status.Name = obj.GetName()
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Kind != statuses[j].Kind {
+			return statuses[i].Kind < statuses[j].Kind
+		}
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses, nil
+}
+
+// SetStatus replaces the workloads of the status and updates its conditions to reflect them
+func SetStatus(status *v1alpha1.ImageMonitorStatus, workloads []v1alpha1.WorkloadStatus, now time.Time) {
+	status.Workloads = workloads
+	for _, c := range workloadConditions(workloads, now) {
+		status.SetCondition(c)
+	}
+}
+
+// SetClusterStatus summarises the workloads of each namespace selected by a ClusterImageMonitor and updates its
+// conditions to reflect the workloads of all of them
+func SetClusterStatus(status *v1alpha1.ClusterImageMonitorStatus, namespaces map[string][]v1alpha1.WorkloadStatus, now time.Time) {
+	var names []string
+	for ns := range namespaces {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+	var all []v1alpha1.WorkloadStatus
+	status.Namespaces = nil
+	for _, ns := range names {
+		status.Namespaces = append(status.Namespaces, v1alpha1.NamespaceSummary{Namespace: ns, ScanSummary: summarise(namespaces[ns])})
+		for _, w := range namespaces[ns] {
+			w.Name = ns + "/" + w.Name
+			all = append(all, w)
+		}
+	}
+	status.Summary = summarise(all)
+	for _, c := range workloadConditions(all, now) {
+		status.SetCondition(c)
+	}
+}
+
+func summarise(workloads []v1alpha1.WorkloadStatus) v1alpha1.ScanSummary {
+	summary := v1alpha1.ScanSummary{Workloads: len(workloads)}
+	for _, w := range workloads {
+		// a workload that failed its first check has not been checked but still failed
+		if w.Error != "" {
+			summary.Failed++
+		}
+		if w.LastChecked == nil {
+			continue
+		}
+		summary.Checked++
+		for _, i := range w.Images {
+			if i.CurrentTag != i.LatestPatchTag {
+				summary.OutdatedImages++
+			}
+			summary.ResolvableCVEs.Critical += i.ResolvableCVEs.Critical
+			summary.ResolvableCVEs.Important += i.ResolvableCVEs.Important
+			summary.ResolvableCVEs.Moderate += i.ResolvableCVEs.Moderate
+		}
+	}
+	return summary
+}
+
+// workloadConditions returns the Scanning, UpToDate, VulnerabilitiesFound and Degraded conditions for the workloads
+func workloadConditions(workloads []v1alpha1.WorkloadStatus, now time.Time) []v1alpha1.Condition {
+	var pending, failed []string
+	for _, w := range workloads {
+		ref := w.Kind + "/" + w.Name
+		if w.Error != "" {
+			failed = append(failed, ref)
+			continue
+		}
+		if w.LastChecked == nil {
+			pending = append(pending, ref)
+		}
+	}
+	summary := summarise(workloads)
+	cves := summary.ResolvableCVEs
+	transition := metav1.Time{Time: now}
+
+	scanning := v1alpha1.Condition{Type: v1alpha1.ConditionScanning, Status: corev1.ConditionFalse, Reason: "AllWorkloadsChecked", LastTransitionTime: transition}
+	if len(pending) > 0 {
+		scanning.Status, scanning.Reason = corev1.ConditionTrue, "WorkloadsPendingCheck"
+		scanning.Message = fmt.Sprintf("%d of %d monitored workloads have not been checked yet: %s", len(pending), len(workloads), strings.Join(pending, ", "))
+	}
+
+	upToDate := v1alpha1.Condition{Type: v1alpha1.ConditionUpToDate, Status: corev1.ConditionTrue, Reason: "AllImagesUpToDate", LastTransitionTime: transition}
+	if summary.OutdatedImages > 0 {
+		upToDate.Status, upToDate.Reason = corev1.ConditionFalse, "PatchUpdatesAvailable"
+		upToDate.Message = fmt.Sprintf("%d images have a newer patch tag available", summary.OutdatedImages)
+	}
+
+	vulnerable := v1alpha1.Condition{Type: v1alpha1.ConditionVulnerabilitiesFound, Status: corev1.ConditionFalse, Reason: "NoResolvableCVEs", LastTransitionTime: transition}
+	if cves.Critical+cves.Important+cves.Moderate > 0 {
+		vulnerable.Status, vulnerable.Reason = corev1.ConditionTrue, "ResolvableCVEs"
+		vulnerable.Message = fmt.Sprintf("updating to the latest patch tags would resolve %d critical, %d important and %d moderate CVEs", cves.Critical, cves.Important, cves.Moderate)
+	}
+
+	degraded := v1alpha1.Condition{Type: v1alpha1.ConditionDegraded, Status: corev1.ConditionFalse, Reason: "ChecksSucceeded", LastTransitionTime: transition}
+	if len(failed) > 0 {
+		degraded.Status, degraded.Reason = corev1.ConditionTrue, "ChecksFailed"
+		degraded.Message = fmt.Sprintf("the last check of %d workloads failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return []v1alpha1.Condition{scanning, upToDate, vulnerable, degraded}
+}
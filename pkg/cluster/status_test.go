@@ -0,0 +1,280 @@
+package cluster_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	appsv1 "github.com/openshift/api/apps/v1"
+	v12 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNewWorkloadStatus(t *testing.T) {
+	checked := time.Now()
+	reports := []domain.ReportResult{
+		{
+			CurrentVersion:              "1.0.0",
+			LatestAvailablePatchVersion: "1.0.1",
+			FloatingTag:                 "1.0",
+			ResolvableCVEs: []domain.CVE{
+				{Severity: "Critical"},
+				{Severity: "moderate"},
+				{Severity: "moderate"},
+			},
+			ClusterImage: &domain.ClusterImage{
+				FullPath: "registry.redhat.io/test/image:1.0.0",
+				Pods: []domain.PodAndContainerRef{
+					{Name: "pod-a", Containers: []string{"web", "sidecar"}},
+					{Name: "pod-b", Containers: []string{"web"}},
+					{Name: "pod-b", Containers: []string{"setup"}, ContainerType: domain.ContainerTypeInit},
+				},
+			},
+		},
+	}
+	status := cluster.NewWorkloadStatus(reports, nil, checked)
+	if status.LastChecked == nil || !status.LastChecked.Time.Equal(checked) {
+		t.Fatal("expected the last checked time to be set")
+	}
+	if len(status.Images) != 1 {
+		t.Fatal("expected one image but got ", len(status.Images))
+	}
+	image := status.Images[0]
+	if len(image.Containers) != 2 || image.Containers[0] != "sidecar" || image.Containers[1] != "web" {
+		t.Fatal("expected the distinct container names in order but got ", image.Containers)
+	}
+	if len(image.InitContainers) != 1 || image.InitContainers[0] != "setup" {
+		t.Fatal("expected the init containers to be listed separately but got ", image.InitContainers)
+	}
+	if image.CurrentTag != "1.0.0" || image.LatestPatchTag != "1.0.1" || image.FloatingTag != "1.0" {
+		t.Fatal("expected the tags from the report but got ", image)
+	}
+	if image.ResolvableCVEs != (v1alpha1.CVECounts{Critical: 1, Moderate: 2}) {
+		t.Fatal("expected the cves to be counted by severity but got ", image.ResolvableCVEs)
+	}
+
+	failed := cluster.NewWorkloadStatus(reports, errors.New("registry unavailable"), checked)
+	if failed.Error != "registry unavailable" || len(failed.Images) != 1 {
+		t.Fatal("expected the error to be recorded with the images that were checked but got ", failed)
+	}
+}
+
+func TestSetWorkloadFailure(t *testing.T) {
+	checked := time.Now().Add(-time.Hour)
+	d := &v12.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "test"}}
+	if err := cluster.SetWorkloadStatus(d, v1alpha1.WorkloadStatus{
+		LastChecked: &metav1.Time{Time: checked},
+		Images:      []v1alpha1.ImageStatus{{Image: "registry.redhat.io/test/image:1.0.0"}},
+	}); err != nil {
+		t.Fatal("did not expect an error recording the status ", err)
+	}
+	if err := cluster.SetWorkloadFailure(d, errors.New("registry unavailable")); err != nil {
+		t.Fatal("did not expect an error recording the failure ", err)
+	}
+	status := v1alpha1.WorkloadStatus{}
+	if err := json.Unmarshal([]byte(d.Annotations[domain.HeimdallStatus]), &status); err != nil {
+		t.Fatal("did not expect an error decoding the status ", err)
+	}
+	if status.Error != "registry unavailable" || len(status.Images) != 1 || status.LastChecked == nil || !status.LastChecked.Time.Equal(checked.Truncate(time.Second)) {
+		t.Fatal("expected the error to be recorded keeping the last check but got ", status)
+	}
+
+	unchecked := &v12.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "test"}}
+	if err := cluster.SetWorkloadFailure(unchecked, errors.New("registry unavailable")); err != nil {
+		t.Fatal("did not expect an error recording the failure ", err)
+	}
+	if strings.Contains(unchecked.Annotations[domain.HeimdallStatus], "lastChecked") {
+		t.Fatal("expected a workload that was never checked to have no last checked time but got ", unchecked.Annotations[domain.HeimdallStatus])
+	}
+}
+
+func TestMonitors_WorkloadStatuses(t *testing.T) {
+	monitored := map[string]string{domain.HeimdallMonitored: "true"}
+	recorded := &v12.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "recorded", Namespace: "test", Labels: monitored},
+	}
+	if err := cluster.SetWorkloadStatus(recorded, v1alpha1.WorkloadStatus{
+		LastChecked: &metav1.Time{Time: time.Now()},
+		Images:      []v1alpha1.ImageStatus{{Image: "registry.redhat.io/test/image:1.0.0"}},
+	}); err != nil {
+		t.Fatal("did not expect an error recording the status ", err)
+	}
+	objects := []runtime.Object{
+		recorded,
+		&appsv1.DeploymentConfig{ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "test", Labels: monitored}},
+		&v12.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+			Name:        "invalid",
+			Namespace:   "test",
+			Labels:      monitored,
+			Annotations: map[string]string{domain.HeimdallStatus: "{"},
+		}},
+		&v12.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "node-agent", Namespace: "test", Labels: monitored}},
+		&v12.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "unmonitored", Namespace: "test"}},
+		&v12.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "other", Labels: monitored}},
+	}
+	s := runtime.NewScheme()
+	if err := scheme.AddToScheme(s); err != nil {
+		t.Fatal("did not expect an error building the scheme ", err)
+	}
+	if err := appsv1.Install(s); err != nil {
+		t.Fatal("did not expect an error building the scheme ", err)
+	}
+	monitors := cluster.NewMonitors(fakeclient.NewFakeClientWithScheme(s, objects...))
+	statuses, err := monitors.WorkloadStatuses(context.TODO(), "test")
+	if err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	if len(statuses) != 4 {
+		t.Fatal("expected the four monitored workloads in the namespace but got ", statuses)
+	}
+	expect := []struct{ Kind, Name string }{{"DaemonSet", "node-agent"}, {"Deployment", "recorded"}, {"DeploymentConfig", "pending"}, {"StatefulSet", "invalid"}}
+	for i, e := range expect {
+		if statuses[i].Kind != e.Kind || statuses[i].Name != e.Name {
+			t.Fatal("expected ", e.Kind, "/", e.Name, " at ", i, " but got ", statuses[i].Kind, "/", statuses[i].Name)
+		}
+	}
+	if statuses[1].LastChecked == nil || len(statuses[1].Images) != 1 {
+		t.Fatal("expected the recorded status to be read from the annotation but got ", statuses[1])
+	}
+	if statuses[0].LastChecked != nil || statuses[2].LastChecked != nil || statuses[3].LastChecked != nil {
+		t.Fatal("expected workloads without a valid status to be pending")
+	}
+}
+
+func TestSetStatus(t *testing.T) {
+	earlier := metav1.Time{Time: time.Now().Add(-time.Hour)}
+	now := time.Now()
+	checked := &metav1.Time{Time: now}
+	cases := []struct {
+		Name      string
+		Status    v1alpha1.ImageMonitorStatus
+		Workloads []v1alpha1.WorkloadStatus
+		Expect    map[v1alpha1.ConditionType]v1.ConditionStatus
+		Validate  func(t *testing.T, s v1alpha1.ImageMonitorStatus)
+	}{
+		{
+			Name: "test conditions when all images are up to date",
+			Workloads: []v1alpha1.WorkloadStatus{
+				{Kind: "Deployment", Name: "a", LastChecked: checked, Images: []v1alpha1.ImageStatus{{CurrentTag: "1.0.1", LatestPatchTag: "1.0.1"}}},
+			},
+			Expect: map[v1alpha1.ConditionType]v1.ConditionStatus{
+				v1alpha1.ConditionScanning:             v1.ConditionFalse,
+				v1alpha1.ConditionUpToDate:             v1.ConditionTrue,
+				v1alpha1.ConditionVulnerabilitiesFound: v1.ConditionFalse,
+				v1alpha1.ConditionDegraded:             v1.ConditionFalse,
+			},
+		},
+		{
+			Name: "test conditions when workloads are pending, failed and out of date",
+			Workloads: []v1alpha1.WorkloadStatus{
+				{Kind: "Deployment", Name: "pending"},
+				{Kind: "Deployment", Name: "failed", LastChecked: checked, Error: "registry unavailable"},
+				{Kind: "Deployment", Name: "never-checked", Error: "registry unavailable"},
+				{Kind: "StatefulSet", Name: "outdated", LastChecked: checked, Images: []v1alpha1.ImageStatus{
+					{CurrentTag: "1.0.0", LatestPatchTag: "1.0.1", ResolvableCVEs: v1alpha1.CVECounts{Important: 1}},
+				}},
+			},
+			Expect: map[v1alpha1.ConditionType]v1.ConditionStatus{
+				v1alpha1.ConditionScanning:             v1.ConditionTrue,
+				v1alpha1.ConditionUpToDate:             v1.ConditionFalse,
+				v1alpha1.ConditionVulnerabilitiesFound: v1.ConditionTrue,
+				v1alpha1.ConditionDegraded:             v1.ConditionTrue,
+			},
+			Validate: func(t *testing.T, s v1alpha1.ImageMonitorStatus) {
+				if len(s.Workloads) != 4 {
+					t.Fatal("expected the workloads to be set on the status")
+				}
+				if c := s.GetCondition(v1alpha1.ConditionDegraded); c.Reason != "ChecksFailed" || !strings.Contains(c.Message, "Deployment/never-checked") {
+					t.Fatal("expected the workloads that failed to be degraded but got ", c)
+				}
+				if c := s.GetCondition(v1alpha1.ConditionScanning); strings.Contains(c.Message, "never-checked") {
+					t.Fatal("expected a workload that failed its first check not to be pending but got ", c.Message)
+				}
+			},
+		},
+		{
+			Name: "test transition time is kept when the status of a condition does not change",
+			Status: v1alpha1.ImageMonitorStatus{Conditions: []v1alpha1.Condition{
+				{Type: v1alpha1.ConditionUpToDate, Status: v1.ConditionTrue, LastTransitionTime: earlier},
+				{Type: v1alpha1.ConditionDegraded, Status: v1.ConditionTrue, LastTransitionTime: earlier},
+			}},
+			Workloads: []v1alpha1.WorkloadStatus{{Kind: "Deployment", Name: "a", LastChecked: checked}},
+			Expect: map[v1alpha1.ConditionType]v1.ConditionStatus{
+				v1alpha1.ConditionUpToDate: v1.ConditionTrue,
+				v1alpha1.ConditionDegraded: v1.ConditionFalse,
+			},
+			Validate: func(t *testing.T, s v1alpha1.ImageMonitorStatus) {
+				if len(s.Conditions) != 4 {
+					t.Fatal("expected each condition once but got ", s.Conditions)
+				}
+				if c := s.GetCondition(v1alpha1.ConditionUpToDate); !c.LastTransitionTime.Equal(&earlier) {
+					t.Fatal("expected the transition time of an unchanged condition to be kept")
+				}
+				if c := s.GetCondition(v1alpha1.ConditionDegraded); !c.LastTransitionTime.Time.Equal(now) {
+					t.Fatal("expected the transition time of a changed condition to be updated")
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			status := tc.Status
+			cluster.SetStatus(&status, tc.Workloads, now)
+			for ct, expect := range tc.Expect {
+				c := status.GetCondition(ct)
+				if c == nil {
+					t.Fatal("expected condition ", ct, " to be set")
+				}
+				if c.Status != expect {
+					t.Fatal("expected condition ", ct, " to be ", expect, " but got ", c.Status)
+				}
+			}
+			if tc.Validate != nil {
+				tc.Validate(t, status)
+			}
+		})
+	}
+}
+
+func TestSetClusterStatus(t *testing.T) {
+	checked := &metav1.Time{Time: time.Now()}
+	status := &v1alpha1.ClusterImageMonitorStatus{}
+	cluster.SetClusterStatus(status, map[string][]v1alpha1.WorkloadStatus{
+		"fuse": {
+			{Kind: "Deployment", Name: "a", LastChecked: checked, Images: []v1alpha1.ImageStatus{
+				{CurrentTag: "1.0.0", LatestPatchTag: "1.0.1", ResolvableCVEs: v1alpha1.CVECounts{Critical: 1, Important: 2}},
+			}},
+			{Kind: "Deployment", Name: "b"},
+		},
+		"amq": {
+			{Kind: "StatefulSet", Name: "broker", LastChecked: checked, Error: "registry unavailable"},
+		},
+		"empty": nil,
+	}, time.Now())
+
+	expect := v1alpha1.ScanSummary{Workloads: 3, Checked: 2, Failed: 1, OutdatedImages: 1, ResolvableCVEs: v1alpha1.CVECounts{Critical: 1, Important: 2}}
+	if status.Summary != expect {
+		t.Fatal("expected summary ", expect, " but got ", status.Summary)
+	}
+	if len(status.Namespaces) != 3 || status.Namespaces[0].Namespace != "amq" || status.Namespaces[2].Namespace != "fuse" {
+		t.Fatal("expected a summary for each namespace in order but got ", status.Namespaces)
+	}
+	if status.Namespaces[2].Workloads != 2 || status.Namespaces[2].Checked != 1 {
+		t.Fatal("expected the summary of namespace fuse to only count its own workloads but got ", status.Namespaces[2])
+	}
+	degraded := status.GetCondition(v1alpha1.ConditionDegraded)
+	if degraded == nil || degraded.Status != v1.ConditionTrue || !strings.Contains(degraded.Message, "StatefulSet/amq/broker") {
+		t.Fatal("expected the failed workload to be named with its namespace but got ", degraded)
+	}
+}
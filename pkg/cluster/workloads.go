@@ -0,0 +1,69 @@
+package cluster
+
+import (
+	"context"
+
+	v1 "github.com/openshift/api/apps/v1"
+	"github.com/pkg/errors"
+	v12 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// workload is an object of one of the kinds that can be monitored
+type workload interface {
+	metav1.Object
+	runtime.Object
+}
+
+// workloadKinds are the kinds of workload that can be monitored and how to list them
+var workloadKinds = []struct {
+	kind        string
+	description string
+	newList     func() runtime.Object
+}{
+	{KindDeploymentConfig, "deployment configs", func() runtime.Object { return &v1.DeploymentConfigList{} }},
+	{KindDeployment, "deployments", func() runtime.Object { return &v12.DeploymentList{} }},
+	{KindStatefulSet, "stateful sets", func() runtime.Object { return &v12.StatefulSetList{} }},
+	{KindDaemonSet, "daemon sets", func() runtime.Object { return &v12.DaemonSetList{} }},
+	{KindCronJob, "cron jobs", func() runtime.Object { return &batchv1beta1.CronJobList{} }},
+	{KindJob, "jobs", func() runtime.Object { return &batchv1.JobList{} }},
+}
+
+// listWorkloads lists the workloads of every kind that can be monitored in ns along with the kind of each
+func listWorkloads(ctx context.Context, c client.Client, ns string) ([]workload, []string, error) {
+	var workloads []workload
+	var kinds []string
+	for _, k := range workloadKinds {
+		list := k.newList()
+		if err := c.List(ctx, list, &client.ListOptions{Namespace: ns}); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to list "+k.description+" in namespace "+ns)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read the list of "+k.description)
+		}
+		for _, item := range items {
+			w, ok := item.(workload)
+			if !ok {
+				return nil, nil, errors.Errorf("unexpected %T in the list of %s", item, k.description)
+			}
+			workloads, kinds = append(workloads, w), append(kinds, k.kind)
+		}
+	}
+	return workloads, kinds, nil
+}
+
+// createdByCronJob reports whether obj was created by a CronJob, such jobs are checked along with their CronJob
+func createdByCronJob(obj metav1.Object) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Kind == KindCronJob {
+			return true
+		}
+	}
+	return false
+}
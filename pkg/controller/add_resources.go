@@ -1,16 +1,24 @@
 package controller
 
 import (
+	"github.com/integr8ly/heimdall/pkg/controller/clusterimagemonitor"
+	"github.com/integr8ly/heimdall/pkg/controller/cronjob"
+	"github.com/integr8ly/heimdall/pkg/controller/daemonset"
 	"github.com/integr8ly/heimdall/pkg/controller/deploymentconfigs"
 	"github.com/integr8ly/heimdall/pkg/controller/deployments"
 	"github.com/integr8ly/heimdall/pkg/controller/imagemonitor"
+	"github.com/integr8ly/heimdall/pkg/controller/job"
 	"github.com/integr8ly/heimdall/pkg/controller/statefulset"
 )
 
 func init() {
 	// AddToManagerFuncs is a list of functions to create controllers and add them to a manager.
+	AddToManagerFuncs = append(AddToManagerFuncs, clusterimagemonitor.Add)
+	AddToManagerFuncs = append(AddToManagerFuncs, cronjob.Add)
+	AddToManagerFuncs = append(AddToManagerFuncs, daemonset.Add)
 	AddToManagerFuncs = append(AddToManagerFuncs, deploymentconfigs.Add)
 	AddToManagerFuncs = append(AddToManagerFuncs, deployments.Add)
 	AddToManagerFuncs = append(AddToManagerFuncs, imagemonitor.Add)
+	AddToManagerFuncs = append(AddToManagerFuncs, job.Add)
 	AddToManagerFuncs = append(AddToManagerFuncs, statefulset.Add)
 }
@@ -0,0 +1,258 @@
+package clusterimagemonitor
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/controller/lifecycle"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	v1 "github.com/openshift/api/apps/v1"
+	"github.com/pkg/errors"
+	v12 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// a cluster image monitor labels the workloads in every namespace it selects in the same way as an image monitor in
+// each of them, leaving namespaces with an image monitor of their own to it. The workload controllers then run the
+// scans and the results are summarised on the status.
+
+var log = logf.Log.WithName("controller_clusterimagemonitor")
+
+const finalizer = "heimdall.rhmi.org"
+
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("cluster-image-monitor-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	if err := c.Watch(&source.Kind{Type: &v1alpha1.ClusterImageMonitor{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+	toMonitors := &handler.EnqueueRequestsFromMapFunc{ToRequests: allClusterMonitors(mgr.GetClient())}
+	// new namespaces and changes to their labels change which namespaces are selected, and an image monitor being
+	// removed hands its namespace back to the cluster image monitor
+	for _, t := range []runtime.Object{&corev1.Namespace{}, &v1alpha1.ImageMonitor{}} {
+		if err := c.Watch(&source.Kind{Type: t}, toMonitors); err != nil {
+			return err
+		}
+	}
+	// new workloads need to be labelled and the results of checking monitored workloads summarised
+	workloads := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isMonitored(e.MetaNew.GetLabels())
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isMonitored(e.Meta.GetLabels())
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+	for _, t := range []runtime.Object{&v1.DeploymentConfig{}, &v12.Deployment{}, &v12.StatefulSet{}, &v12.DaemonSet{}, &batchv1beta1.CronJob{}, &batchv1.Job{}} {
+		if err := c.Watch(&source.Kind{Type: t}, toMonitors, workloads); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isMonitored(labels map[string]string) bool {
+	_, ok := labels[domain.HeimdallMonitored]
+	return ok
+}
+
+// allClusterMonitors maps any object to every ClusterImageMonitor
+func allClusterMonitors(c client.Client) handler.ToRequestsFunc {
+	return func(o handler.MapObject) []reconcile.Request {
+		monitors := &v1alpha1.ClusterImageMonitorList{}
+		if err := c.List(context.TODO(), monitors); err != nil {
+			log.Error(err, "failed to list cluster image monitors")
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, m := range monitors.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: m.Name}})
+		}
+		return requests
+	}
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	c := mgr.GetClient()
+	return &ReconcileClusterImageMonitor{
+		client:        c,
+		objectLabeler: cluster.NewObjectLabeler(c),
+		monitors:      cluster.NewMonitors(c),
+	}
+}
+
+type ReconcileClusterImageMonitor struct {
+	client        client.Client
+	objectLabeler *cluster.ObjectsLabeler
+	monitors      *cluster.Monitors
+}
+
+func (r *ReconcileClusterImageMonitor) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, cancel := lifecycle.ReconcileContext()
+	defer cancel()
+	clusterMon := &v1alpha1.ClusterImageMonitor{}
+	if err := r.client.Get(ctx, client.ObjectKey{Name: request.Name}, clusterMon); err != nil {
+		if errors2.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	finalizers := clusterMon.GetFinalizers()
+	if clusterMon.DeletionTimestamp != nil {
+		namespaces, err := r.monitors.SelectedNamespaces(ctx, clusterMon)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if err := r.release(ctx, namespaces); err != nil {
+			return reconcile.Result{}, err
+		}
+		for i, f := range finalizers {
+			if f == finalizer {
+				finalizers = append(finalizers[:i], finalizers[i+1:]...)
+				clusterMon.SetFinalizers(finalizers)
+				break
+			}
+		}
+		if err := r.client.Update(ctx, clusterMon); err != nil {
+			return reconcile.Result{}, errors.Wrap(err, "failed to remove finalizer")
+		}
+		return reconcile.Result{}, nil
+	}
+
+	// add our finalizer
+	foundFinalizer := false
+	for _, f := range finalizers {
+		if f == finalizer {
+			foundFinalizer = true
+			break
+		}
+	}
+	if !foundFinalizer {
+		finalizers = append(finalizers, finalizer)
+		clusterMon.SetFinalizers(finalizers)
+		if err := r.client.Update(ctx, clusterMon); err != nil {
+			return reconcile.Result{}, errors.Wrap(err, "failed to add finalizer")
+		}
+	}
+
+	namespaces, err := r.monitors.SelectedNamespaces(ctx, clusterMon)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	log.Info("have cluster image monitor "+clusterMon.Name, "namespaces", len(namespaces))
+	selection, err := cluster.NewWorkloadSelection(clusterMon.Spec.ExcludePattern, clusterMon.Spec.WorkloadFilter)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	selected := map[string]bool{}
+	var labelErr error
+	for _, ns := range namespaces {
+		selected[ns] = true
+		monitor, err := r.monitors.ForNamespace(ctx, ns)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if monitor != nil {
+			// the image monitor in the namespace decides which of its workloads are monitored
+			continue
+		}
+		if err := r.objectLabeler.LabelObjects(ctx, map[string]string{domain.HeimdallMonitored: "true"}, selection, ns); err != nil {
+			log.Error(err, "failed to label the workloads in namespace "+ns)
+			labelErr = err
+		}
+	}
+	// stop monitoring namespaces that are no longer selected
+	var deselected []string
+	for _, s := range clusterMon.Status.Namespaces {
+		if !selected[s.Namespace] {
+			deselected = append(deselected, s.Namespace)
+		}
+	}
+	if err := r.release(ctx, deselected); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := r.updateStatus(ctx, clusterMon, namespaces); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, labelErr
+}
+
+// release removes the labels and annotations from the workloads in the namespaces that are not monitored by an
+// image monitor or another cluster image monitor
+func (r *ReconcileClusterImageMonitor) release(ctx context.Context, namespaces []string) error {
+	for _, ns := range namespaces {
+		monitor, err := r.monitors.ForNamespace(ctx, ns)
+		if err != nil {
+			return err
+		}
+		if monitor != nil {
+			continue
+		}
+		other, err := r.monitors.ClusterMonitorFor(ctx, ns)
+		if err != nil {
+			if errors2.IsNotFound(errors.Cause(err)) {
+				// the namespace is gone along with its workloads
+				continue
+			}
+			return err
+		}
+		if other != nil {
+			continue
+		}
+		if err := r.objectLabeler.RemoveLabelsAnnotations(ctx, map[string]string{domain.HeimdallMonitored: "true"}, ns); err != nil {
+			return errors.Wrap(err, "failed to stop monitoring namespace "+ns)
+		}
+	}
+	return nil
+}
+
+// updateStatus summarises the results recorded on the monitored workloads of the namespaces on the status of the
+// ClusterImageMonitor
+func (r *ReconcileClusterImageMonitor) updateStatus(ctx context.Context, clusterMon *v1alpha1.ClusterImageMonitor, namespaces []string) error {
+	workloads := map[string][]v1alpha1.WorkloadStatus{}
+	for _, ns := range namespaces {
+		statuses, err := r.monitors.WorkloadStatuses(ctx, ns)
+		if err != nil {
+			return errors.Wrap(err, "failed to collect the status of the monitored workloads in namespace "+ns)
+		}
+		workloads[ns] = statuses
+	}
+	status := clusterMon.Status.DeepCopy()
+	cluster.SetClusterStatus(status, workloads, time.Now())
+	if reflect.DeepEqual(*status, clusterMon.Status) {
+		return nil
+	}
+	clusterMon.Status = *status
+	if err := r.client.Status().Update(ctx, clusterMon); err != nil {
+		return errors.Wrap(err, "failed to update the status of cluster image monitor "+clusterMon.Name)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"github.com/integr8ly/heimdall/pkg/controller/lifecycle"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
@@ -9,6 +10,9 @@ var AddToManagerFuncs []func(manager.Manager) error
 
 // AddToManager adds all Controllers to the Manager
 func AddToManager(m manager.Manager) error {
+	if err := lifecycle.AddToManager(m); err != nil {
+		return err
+	}
 	for _, f := range AddToManagerFuncs {
 		if err := f(m); err != nil {
 			return err
@@ -0,0 +1,19 @@
+package cronjob
+
+import (
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/controller/generic"
+	"github.com/integr8ly/heimdall/pkg/registry"
+	v1 "k8s.io/client-go/kubernetes/typed/batch/v1beta1"
+)
+
+// NewReport creates a generic.Reports that generates reports for cron jobs
+func NewReport(clusterImageService *cluster.ImageService, registryImageService *registry.ImageService, policies registry.PolicyGetter, client v1.BatchV1beta1Interface) *generic.Reports {
+	return generic.MakeGenericReports(
+		&objectInterface{client},
+		clusterImageService,
+		registryImageService,
+		policies,
+		"cron job",
+	)
+}
@@ -0,0 +1,118 @@
+package daemonset
+
+import (
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/controller/generic"
+	"github.com/integr8ly/heimdall/pkg/notify"
+	"github.com/integr8ly/heimdall/pkg/registry"
+	imagesv1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+	"github.com/pkg/errors"
+	v12 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	v1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_daemonset")
+
+// Add creates a new DaemonSet Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	client, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return errors.Wrap(err, "failed to create k8s client")
+	}
+
+	isClient, err := imagesv1.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return errors.Wrap(err, "failed to create images client")
+	}
+
+	registryImageService, err := registry.DefaultImagesService()
+	if err != nil {
+		return err
+	}
+
+	return add(mgr, newReconciler(mgr, client, isClient, registryImageService))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager, client kubernetes.Interface, isClient *imagesv1.ImageV1Client, registryImageService *registry.ImageService) reconcile.Reconciler {
+	clusterImageService := cluster.NewImageService(client, isClient)
+
+	impl := &objectInterface{
+		client: client.AppsV1(),
+	}
+
+	return generic.MakeGenericReconciler(
+		cluster.NewMonitors(mgr.GetClient()),
+		"daemon set",
+		log,
+		mgr.GetEventRecorderFor(cluster.EventRecorderName),
+		notify.NewNotifier(mgr.GetClient(), mgr.GetAPIReader()),
+		cluster.NewPods(mgr.GetClient()),
+		clusterImageService,
+		registryImageService,
+		cluster.NewMonitors(mgr.GetClient()),
+		impl,
+	)
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("daemonset-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource DaemonSet
+	return c.Watch(&source.Kind{Type: &v12.DaemonSet{}}, &handler.EnqueueRequestForObject{})
+}
+
+// blank assignment to verify that ReconcileDaemonSet implements HeimdallObjectInterface
+var _ generic.HeimdallObjectInterface = &objectInterface{}
+
+// objectInterface is an implementation of generic.HeimdallObjectInterface
+// that knows how to access daemon sets
+type objectInterface struct {
+	client v1.AppsV1Interface
+}
+
+// ListObjects gets the daemon sets in namespace as v1.Object types
+func (r *objectInterface) ListObjects(namespace string) ([]metav1.Object, error) {
+	daemonSets, err := r.client.DaemonSets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]metav1.Object, len(daemonSets.Items))
+	for i := range daemonSets.Items {
+		result[i] = &daemonSets.Items[i]
+	}
+
+	return result, nil
+}
+
+// GetPodTemplate gets the pod template for a given daemon set obj
+func (r *objectInterface) GetPodTemplate(obj metav1.Object) corev1.PodTemplateSpec {
+	return obj.(*v12.DaemonSet).Spec.Template
+}
+
+// GetObject gets a daemon set name in namespace
+func (r *objectInterface) GetObject(namespace, name string) (metav1.Object, error) {
+	return r.client.DaemonSets(namespace).Get(name, metav1.GetOptions{})
+}
+
+// UpdateObject updates a daemon set
+func (r *objectInterface) UpdateObject(obj metav1.Object) error {
+	_, err := r.client.DaemonSets(obj.GetNamespace()).Update(obj.(*v12.DaemonSet))
+	return err
+}
@@ -2,16 +2,20 @@ package deploymentconfigs
 
 import (
 	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/controller/generic"
+	"github.com/integr8ly/heimdall/pkg/controller/lifecycle"
 	"github.com/integr8ly/heimdall/pkg/controller/validation"
 	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/notify"
 	"github.com/integr8ly/heimdall/pkg/registry"
-	"github.com/integr8ly/heimdall/pkg/rhcc"
+	"github.com/integr8ly/heimdall/pkg/schedule"
 	v1 "github.com/openshift/api/apps/v1"
 	apps "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1"
 	v12 "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1"
 	imagesv1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
 	v13 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
 	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	v14 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
@@ -22,14 +26,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"sigs.k8s.io/controller-runtime/pkg/source"
-	"strings"
-	"time"
 )
 
 var log = logf.Log.WithName("controller_deploymentconfigs")
 
-const requeAfterFourHours = time.Hour * 4
-
 // Add creates a new ImageMonitor Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
@@ -49,7 +49,10 @@ func Add(mgr manager.Manager) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to create k8s client")
 	}
-	registryImageService := registry.NewImagesService(&registry.Client{}, &rhcc.Client{}, &rhcc.Client{})
+	registryImageService, err := registry.DefaultImagesService()
+	if err != nil {
+		return err
+	}
 
 	return add(mgr, newReconciler(mgr, client, dcClient, isClient, registryImageService))
 }
@@ -58,17 +61,23 @@ func Add(mgr manager.Manager) error {
 func newReconciler(mgr manager.Manager, k8sClient kubernetes.Interface, dcClient *v12.AppsV1Client, isClient *v13.ImageV1Client, riService *registry.ImageService) reconcile.Reconciler {
 	clusterImageService := cluster.NewImageService(k8sClient, isClient)
 	return &ReconcileDeploymentConfig{
-		client:     mgr.GetClient(),
-		scheme:     mgr.GetScheme(),
-		dcClient:   dcClient,
-		isClient:   isClient,
-		podService: cluster.NewPods(mgr.GetClient()),
+		client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		dcClient: dcClient,
+		isClient: isClient,
 		reportService: &Reports{
 			clusterImageService:  clusterImageService,
 			registryImageService: riService,
+			policies:             cluster.NewMonitors(mgr.GetClient()),
 			dcClient:             dcClient,
 		},
 		imageService: clusterImageService,
+		scans: generic.NewScans(
+			mgr.GetEventRecorderFor(cluster.EventRecorderName),
+			notify.NewNotifier(mgr.GetClient(), mgr.GetAPIReader()),
+			cluster.NewPods(mgr.GetClient()),
+		),
+		schedules: cluster.NewMonitors(mgr.GetClient()),
 	}
 }
 
@@ -92,8 +101,9 @@ type ReconcileDeploymentConfig struct {
 	scheme       *runtime.Scheme
 	dcClient     v12.AppsV1Interface
 	isClient     v13.ImageV1Interface
-	podService   *cluster.Pods
 	imageService *cluster.ImageService
+	schedules    schedule.Getter
+	scans        *generic.Scans
 	// turn into interfaces
 	reportService *Reports
 }
@@ -101,20 +111,31 @@ type ReconcileDeploymentConfig struct {
 func (r *ReconcileDeploymentConfig) Reconcile(request reconcile.Request) (reconcile.Result, error) {
 	// as we will watch all deployment configs we want to check if this is a deployment config we should care about.
 	// we can label the deployment with last run time and we will see it again immediately but will then reque it based on the next check time
+	ctx, cancel := lifecycle.ReconcileContext()
+	defer cancel()
 	dc, err := r.dcClient.DeploymentConfigs(request.Namespace).Get(request.Name, v14.GetOptions{})
 	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			generic.Forget("deployment config", request.Namespace, request.Name)
+		}
 		log.Error(err, "failed to get deployment config "+request.Namespace+"  "+request.Name)
 		return reconcile.Result{}, err
 	}
 	if _, ok := dc.Labels[domain.HeimdallMonitored]; !ok {
+		generic.Forget("deployment config", request.Namespace, request.Name)
 		return reconcile.Result{}, nil
 	}
-	images, err := r.reportService.GetImages(dc)
+	sched, err := r.schedules.ScheduleFor(ctx, request.Namespace)
+	if err != nil {
+		log.Error(err, "failed to get the check schedule for namespace "+request.Namespace)
+		return reconcile.Result{}, err
+	}
+	images, err := r.reportService.GetImages(ctx, dc)
 	if err != nil {
 		log.Error(err, "failed to get images for deployment config when checking if should run check again")
 		return reconcile.Result{}, err
 	}
-	should, err := validation.ShouldCheck(dc, images)
+	should, err := validation.ShouldCheck(dc, images, sched)
 	if err != nil {
 		if validation.IsParseErr(err) {
 			delete(dc.Annotations, domain.HeimdallLastChecked)
@@ -127,17 +148,20 @@ func (r *ReconcileDeploymentConfig) Reconcile(request reconcile.Request) (reconc
 		}
 	}
 	if !should {
-		//return and we will see it again once it changes or 4 hrs passes
+		//return and we will see it again once it changes or its next check is due
 		log.Info("critera for re checking " + dc.Name + " not met")
-		return reconcile.Result{RequeueAfter: requeAfterFourHours}, nil
+		return reconcile.Result{RequeueAfter: validation.RequeueAfter(dc, sched)}, nil
 	}
 
 	log.Info("deployment config " + dc.Name + " in namespace " + dc.Namespace + " is being monitored by heimdall")
 	// get the deployment config and work through the images we discover
-	reports, err := r.reportService.Generate(request.Namespace, request.Name)
-	if err != nil {
-		log.Error(err, "failed to generate a report for images in dc "+request.Name+" in namespace "+request.Namespace)
-		return reconcile.Result{RequeueAfter: requeAfterFourHours}, nil
+	reports, checkErr := r.reportService.Check(ctx, request.Name, images)
+	if checkErr != nil {
+		log.Error(checkErr, "failed to generate a report for images in dc "+request.Name+" in namespace "+request.Namespace)
+		if len(reports) == 0 {
+			r.recordFailure(request, checkErr)
+			return reconcile.Result{RequeueAfter: validation.RetryAfter(sched)}, nil
+		}
 	}
 	// after the report has been run we want to annotate our dc with information. If we fail here we may end up re running the report.
 	// reports can take some time so get a fresh dc copy
@@ -146,26 +170,33 @@ func (r *ReconcileDeploymentConfig) Reconcile(request reconcile.Request) (reconc
 		log.Error(err, "failed to get deployment config "+request.Namespace+"  "+request.Name)
 		return reconcile.Result{}, nil
 	}
-	// have to use annotation as labels have strict length and format
-	if dc.Annotations == nil {
-		dc.Annotations = map[string]string{}
-	}
 	log.Info("generated reports for deployment ", "reports", len(reports), "namespace", request.Namespace, "name", request.Name)
-	checked := []string{}
-	for _, rep := range reports {
-		checked = append(checked, rep.ClusterImage.SHA256Path)
-		if err := r.podService.LabelPods(&rep); err != nil {
-			log.Error(err, "failed to label pod ")
-			return reconcile.Result{}, nil
-		}
+	if err := r.scans.Checked(ctx, "deployment config", dc, reports, checkErr); err != nil {
+		log.Error(err, "failed to label pod ")
+		return reconcile.Result{}, nil
 	}
-	dc.Annotations[domain.HeimdallLastChecked] = time.Now().Format(domain.TimeFormat)
-	dc.Annotations[domain.HeimdallImagesChecked] = strings.Join(checked, ",")
 	if _, err := r.dcClient.DeploymentConfigs(request.Namespace).Update(dc); err != nil {
 		// in this case we will requeue log the error and requeue to ensure we dont keep retrying the checks
 		log.Error(err, " failed to label deployment config "+request.Namespace+" "+request.Name)
 		return reconcile.Result{}, nil
 	}
-	// ensure we see this dc 4 hours from now or when it next changes
-	return reconcile.Result{RequeueAfter: requeAfterFourHours}, nil
+	if checkErr != nil {
+		// retry the images that failed rather than waiting for the next check
+		return reconcile.Result{RequeueAfter: validation.RetryAfter(sched)}, nil
+	}
+	// ensure we see this dc when its next check is due or when it next changes
+	return reconcile.Result{RequeueAfter: validation.RequeueAfter(dc, sched)}, nil
+}
+
+// recordFailure records a failed check on the deployment config so it is shown on the status of the ImageMonitor
+func (r *ReconcileDeploymentConfig) recordFailure(request reconcile.Request, checkErr error) {
+	dc, err := r.dcClient.DeploymentConfigs(request.Namespace).Get(request.Name, v14.GetOptions{})
+	if err != nil {
+		log.Error(err, "failed to get deployment config "+request.Namespace+" "+request.Name+" to record the failed check")
+		return
+	}
+	r.scans.Failed("deployment config", dc, checkErr)
+	if _, err := r.dcClient.DeploymentConfigs(request.Namespace).Update(dc); err != nil {
+		log.Error(err, "failed to record the failed check on deployment config "+dc.Namespace+" "+dc.Name)
+	}
 }
@@ -1,7 +1,7 @@
 package deploymentconfigs
 
 import (
-	"fmt"
+	"context"
 	"github.com/integr8ly/heimdall/pkg/cluster"
 	"github.com/integr8ly/heimdall/pkg/domain"
 	"github.com/integr8ly/heimdall/pkg/registry"
@@ -9,7 +9,6 @@ import (
 	v12 "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1"
 	"github.com/pkg/errors"
 	v13 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"strings"
 )
 
 func getImageChangeParams(dc *v1.DeploymentConfig) []*v1.DeploymentTriggerImageChangeParams {
@@ -25,19 +24,21 @@ func getImageChangeParams(dc *v1.DeploymentConfig) []*v1.DeploymentTriggerImageC
 type Reports struct {
 	clusterImageService  *cluster.ImageService
 	registryImageService *registry.ImageService
+	policies             registry.PolicyGetter
 	dcClient             *v12.AppsV1Client
 }
 
 func NewReport(clusterImageService *cluster.ImageService,
-	registryImageService *registry.ImageService, dcClient *v12.AppsV1Client) *Reports {
+	registryImageService *registry.ImageService, policies registry.PolicyGetter, dcClient *v12.AppsV1Client) *Reports {
 	return &Reports{
 		clusterImageService:  clusterImageService,
 		registryImageService: registryImageService,
+		policies:             policies,
 		dcClient:             dcClient,
 	}
 }
 
-func (r *Reports) Generate(ns, deploymentConfig string) ([]domain.ReportResult, error) {
+func (r *Reports) Generate(ctx context.Context, ns, deploymentConfig string) ([]domain.ReportResult, error) {
 	var dcs []v1.DeploymentConfig
 
 	if deploymentConfig == "*" {
@@ -54,64 +55,48 @@ func (r *Reports) Generate(ns, deploymentConfig string) ([]domain.ReportResult,
 		dcs = append(dcs, *dc)
 	}
 
-	var reports []domain.ReportResult
-	var checked = map[string]domain.ReportResult{}
+	var toCheck []registry.ComponentImage
 	for _, dc := range dcs {
-		images, err := r.GetImages(&dc)
+		images, err := r.GetImages(ctx, &dc)
 		if err != nil {
-			return reports, errors.Wrap(err, "failed to get images for deployment config ")
+			return nil, errors.Wrap(err, "failed to get images for deployment config ")
 		}
-
-		for _, i := range images {
-			if !strings.Contains(i.FullPath, "redhat") {
-				log.Info("skipping image not in redhat registry " + i.FullPath)
-				continue
-			}
-			if _, ok := checked[i.SHA256Path]; ok {
-				log.Info("already checked ", i.SHA256Path, "skipping ")
-				rep := checked[i.SHA256Path]
-				rep.Component = dc.Name
-				reports = append(reports, rep)
-				continue
-			}
-			log.Info("looking at image after parsing ", "image full tag", i.FullPath)
-			result, err := r.registryImageService.Check(i)
-			if err != nil {
-				// log and carry on as we may have valid reports
-				fmt.Println("error creating report ", err)
-				fmt.Println("REPORTs ", len(reports))
-				log.Error(err, "a report failed ")
-			} else {
-				result.ClusterImage = i
-				result.Component = dc.Name
-				reports = append(reports, result)
-			}
-			checked[i.SHA256Path] = result
-		}
-
+		toCheck = append(toCheck, registry.ComponentImages(dc.Name, images)...)
 	}
-	return reports, nil
+	return r.registryImageService.CheckComponents(ctx, toCheck)
 }
 
-// get a list of the cluster images
-func (r *Reports) GetImages(dc *v1.DeploymentConfig) ([]*domain.ClusterImage, error) {
+// Check checks images found by GetImages for the deployment config with name. When some images could not be checked
+// the reports of the others are returned along with the errors.
+func (r *Reports) Check(ctx context.Context, name string, images []*domain.ClusterImage) ([]domain.ReportResult, error) {
+	return r.registryImageService.CheckComponents(ctx, registry.ComponentImages(name, images))
+}
+
+// get a list of the cluster images that are checked according to the registry policy, resolved to the repositories
+// they are checked against
+func (r *Reports) GetImages(ctx context.Context, dc *v1.DeploymentConfig) ([]*domain.ClusterImage, error) {
 	log.Info("checking deployment with resource version " + dc.ResourceVersion)
+	policy, err := r.policies.PolicyFor(ctx, dc.Namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the registry policy for namespace "+dc.Namespace)
+	}
 	var images []*domain.ClusterImage
 	log.Info("got deployment config ", "name", dc.Name)
 	icp := getImageChangeParams(dc)
 	if len(icp) > 0 {
-		is, err := r.clusterImageService.FindImagesFromImageChangeParams(dc.Namespace, icp, dc.Spec.Template.Labels)
+		is, err := r.clusterImageService.FindImagesFromImageChangeParams(ctx, dc, icp, dc.Spec.Template.Labels)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed find images in deploymentconfig via its image triggers ")
 		}
 		images = append(images, is...)
-	} else {
-		is, err := r.clusterImageService.FindImagesFromLabels(dc.Namespace, dc.Spec.Template.Labels)
+	} else if dc.Spec.Template != nil {
+		is, err := r.clusterImageService.FindImagesFromPodTemplate(ctx, dc, *dc.Spec.Template, policy, r.registryImageService)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed find images in deploymentconfig")
 		}
 		images = append(images, is...)
 	}
+	images = policy.Filter(images)
 	log.Info("found images ", "no:", len(images))
 	return images, nil
 }
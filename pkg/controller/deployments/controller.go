@@ -3,12 +3,16 @@ package deployments
 import (
 	"context"
 	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/controller/generic"
+	"github.com/integr8ly/heimdall/pkg/controller/lifecycle"
 	"github.com/integr8ly/heimdall/pkg/controller/validation"
 	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/notify"
 	"github.com/integr8ly/heimdall/pkg/registry"
-	"github.com/integr8ly/heimdall/pkg/rhcc"
+	"github.com/integr8ly/heimdall/pkg/schedule"
 	"github.com/pkg/errors"
 	v12 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -18,22 +22,21 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"sigs.k8s.io/controller-runtime/pkg/source"
-	"strings"
-	"time"
 )
 
 var log = logf.Log.WithName("controller_deployments")
 
 const labelFormat = "heimdall.%s"
 
-const requeAfterFourHours = time.Hour * 4
-
 func Add(mgr manager.Manager) error {
 	client, err := kubernetes.NewForConfig(mgr.GetConfig())
 	if err != nil {
 		return errors.Wrap(err, "failed to create k8s client")
 	}
-	registryImageService := registry.NewImagesService(&registry.Client{}, &rhcc.Client{}, &rhcc.Client{})
+	registryImageService, err := registry.DefaultImagesService()
+	if err != nil {
+		return err
+	}
 
 	return add(mgr, newReconciler(mgr, client, registryImageService))
 }
@@ -46,10 +49,16 @@ func newReconciler(mgr manager.Manager, k8sClient kubernetes.Interface, riServic
 		reportService: &Reports{
 			clusterImageService:  clusterImageService,
 			registryImageService: riService,
+			policies:             cluster.NewMonitors(mgr.GetClient()),
 			deploymentClient:     k8sClient.AppsV1(),
 		},
-		podService:   cluster.NewPods(mgr.GetClient()),
+		schedules:    cluster.NewMonitors(mgr.GetClient()),
 		imageService: clusterImageService,
+		scans: generic.NewScans(
+			mgr.GetEventRecorderFor(cluster.EventRecorderName),
+			notify.NewNotifier(mgr.GetClient(), mgr.GetAPIReader()),
+			cluster.NewPods(mgr.GetClient()),
+		),
 	}
 }
 
@@ -64,26 +73,36 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 }
 
 func (r *ReconcileDeployment) Reconcile(request reconcile.Request) (reconcile.Result, error) {
-	ctx := context.TODO()
+	ctx, cancel := lifecycle.ReconcileContext()
+	defer cancel()
 	d := &v12.Deployment{}
-	err := r.client.Get(context.TODO(), client.ObjectKey{Namespace: request.Namespace, Name: request.Name}, d)
+	err := r.client.Get(ctx, client.ObjectKey{Namespace: request.Namespace, Name: request.Name}, d)
 	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			generic.Forget("deployment", request.Namespace, request.Name)
+		}
 		log.Error(err, "failed to get deployment in namespace "+request.Namespace+" with name  "+d.Name)
 		return reconcile.Result{}, err
 	}
 	// ignore if not labeled
 	if _, ok := d.Labels[domain.HeimdallMonitored]; !ok {
+		generic.Forget("deployment", request.Namespace, request.Name)
 		return reconcile.Result{}, nil
 	}
-	images, err := r.reportService.GetImages(d)
+	sched, err := r.schedules.ScheduleFor(ctx, request.Namespace)
 	if err != nil {
+		log.Error(err, "failed to get the check schedule for namespace "+request.Namespace)
 		return reconcile.Result{}, err
 	}
-	should, err := validation.ShouldCheck(d, images)
+	images, err := r.reportService.GetImages(ctx, d)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	should, err := validation.ShouldCheck(d, images, sched)
 	if err != nil {
 		if validation.IsParseErr(err) {
 			delete(d.Annotations, domain.HeimdallLastChecked)
-			if err := r.client.Update(context.TODO(), d); err != nil {
+			if err := r.client.Update(ctx, d); err != nil {
 				// in this case we will requeue log the error and requeue to ensure we dont keep retrying the checks
 				log.Error(err, " failed to label deployment "+request.Namespace+" "+request.Name)
 				return reconcile.Result{}, err
@@ -93,41 +112,39 @@ func (r *ReconcileDeployment) Reconcile(request reconcile.Request) (reconcile.Re
 	}
 	if !should {
 		log.Info("critera for re checking " + d.Name + " not met")
-		return reconcile.Result{}, nil
+		return reconcile.Result{RequeueAfter: validation.RequeueAfter(d, sched)}, nil
 	}
 
 	log.Info("deployment " + d.Name + " in namespace " + d.Namespace + " is being monitored by heimdall")
 
-	report, err := r.reportService.Generate(request.Namespace, request.Name)
-	if err != nil {
-		log.Error(err, "failed to generate a report for images in dc "+request.Name+" in namespace "+request.Namespace)
-		return reconcile.Result{RequeueAfter: requeAfterFourHours}, nil
+	report, checkErr := r.reportService.Check(ctx, request.Name, images)
+	if checkErr != nil {
+		log.Error(checkErr, "failed to generate a report for images in deployment "+request.Name+" in namespace "+request.Namespace)
+		if len(report) == 0 {
+			r.recordFailure(ctx, request, checkErr)
+			return reconcile.Result{RequeueAfter: validation.RetryAfter(sched)}, nil
+		}
 	}
 	log.Info("generated reports for deployment ", "reports", len(report), "namespace", request.Namespace, "name", request.Name)
 	// make sure we are upto date
-	err = r.client.Get(context.TODO(), client.ObjectKey{Namespace: request.Namespace, Name: request.Name}, d)
+	err = r.client.Get(ctx, client.ObjectKey{Namespace: request.Namespace, Name: request.Name}, d)
 	if err != nil {
 		log.Info("failed to get deployment in namespace " + request.Namespace + " with name  " + d.Name)
 		return reconcile.Result{}, nil
 	}
-	if d.Annotations == nil {
-		d.Annotations = map[string]string{}
-	}
-	checked := []string{}
-	for _, rep := range report {
-		checked = append(checked, rep.ClusterImage.SHA256Path)
-		if err := r.podService.LabelPods(&rep); err != nil {
-			log.Error(err, "failed to label pod will retry as soon as possible")
-			return reconcile.Result{}, nil
-		}
+	if err := r.scans.Checked(ctx, "deployment", d, report, checkErr); err != nil {
+		log.Error(err, "failed to label pod will retry as soon as possible")
+		return reconcile.Result{}, nil
 	}
-	d.Annotations[domain.HeimdallLastChecked] = time.Now().Format(domain.TimeFormat)
-	d.Annotations[domain.HeimdallImagesChecked] = strings.Join(checked, ",")
 	if err := r.client.Update(ctx, d); err != nil {
 		log.Error(err, "failed to annotate deployment "+d.Namespace+" "+d.Name)
 		return reconcile.Result{}, nil
 	}
-	return reconcile.Result{RequeueAfter: requeAfterFourHours}, nil
+	if checkErr != nil {
+		// retry the images that failed rather than waiting for the next check
+		return reconcile.Result{RequeueAfter: validation.RetryAfter(sched)}, nil
+	}
+	return reconcile.Result{RequeueAfter: validation.RequeueAfter(d, sched)}, nil
 }
 
 var _ reconcile.Reconciler = &ReconcileDeployment{}
@@ -140,6 +157,20 @@ type ReconcileDeployment struct {
 	scheme *runtime.Scheme
 	// turn into interfaces
 	reportService *Reports
-	podService    *cluster.Pods
 	imageService  *cluster.ImageService
+	schedules     schedule.Getter
+	scans         *generic.Scans
+}
+
+// recordFailure records a failed check on the deployment so it is shown on the status of the ImageMonitor
+func (r *ReconcileDeployment) recordFailure(ctx context.Context, request reconcile.Request, checkErr error) {
+	d := &v12.Deployment{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: request.Namespace, Name: request.Name}, d); err != nil {
+		log.Error(err, "failed to get deployment "+request.Namespace+" "+request.Name+" to record the failed check")
+		return
+	}
+	r.scans.Failed("deployment", d, checkErr)
+	if err := r.client.Update(ctx, d); err != nil {
+		log.Error(err, "failed to record the failed check on deployment "+d.Namespace+" "+d.Name)
+	}
 }
@@ -1,6 +1,7 @@
 package deployments
 
 import (
+	"context"
 	"fmt"
 	"github.com/integr8ly/heimdall/pkg/cluster"
 	"github.com/integr8ly/heimdall/pkg/domain"
@@ -10,28 +11,28 @@ import (
 	v12 "k8s.io/api/apps/v1"
 	v13 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/client-go/kubernetes/typed/apps/v1"
-	"strings"
 )
 
 type Reports struct {
 	clusterImageService  *cluster.ImageService
 	registryImageService *registry.ImageService
+	policies             registry.PolicyGetter
 	deploymentClient     v1.AppsV1Interface
 }
 
 func NewReport(clusterImageService *cluster.ImageService,
-	registryImageService *registry.ImageService, deploymentClient v1.AppsV1Interface) *Reports {
+	registryImageService *registry.ImageService, policies registry.PolicyGetter, deploymentClient v1.AppsV1Interface) *Reports {
 	return &Reports{
 		clusterImageService:  clusterImageService,
 		registryImageService: registryImageService,
+		policies:             policies,
 		deploymentClient:     deploymentClient,
 	}
 }
 
-func (r *Reports) Generate(ns, name string) ([]domain.ReportResult, error) {
-	var reports []domain.ReportResult
+func (r *Reports) Generate(ctx context.Context, ns, name string) ([]domain.ReportResult, error) {
 	var deployments []v12.Deployment
-	var checked = map[string]domain.ReportResult{}
+	var toCheck []registry.ComponentImage
 	if name == "*" {
 		dl, err := r.deploymentClient.Deployments(ns).List(v13.ListOptions{})
 		if err != nil {
@@ -46,40 +47,38 @@ func (r *Reports) Generate(ns, name string) ([]domain.ReportResult, error) {
 		deployments = append(deployments, *d)
 	}
 
+	var errs registry.CheckErrors
 	for _, d := range deployments {
-		images, err := r.GetImages(&d)
+		images, err := r.GetImages(ctx, &d)
 		if err != nil {
-			// log
-			fmt.Println("error finding images", err)
-		}
-		for _, i := range images {
-			if !strings.Contains(i.FullPath, "redhat") {
-				continue
-			}
-			if _, ok := checked[i.SHA256Path]; ok {
-				rep := checked[i.SHA256Path]
-				rep.Component = d.Name
-				reports = append(reports, rep)
-				continue
-			}
-			result, err := r.registryImageService.Check(i)
-			if err != nil {
-				// log and carry on as we may have valid reports
-				fmt.Println(err, "a report failed ")
-			} else {
-				result.Component = d.Name
-				reports = append(reports, result)
-			}
-			checked[i.FullPath] = result
+			log.Error(err, "error finding images")
+			errs = append(errs, err)
 		}
+		toCheck = append(toCheck, registry.ComponentImages(d.Name, images)...)
+	}
+	reports, err := r.registryImageService.CheckComponents(ctx, toCheck)
+	if err != nil {
+		errs = append(errs, err)
 	}
-	return reports, nil
+	return reports, errs.Err()
 }
 
-func (r *Reports) GetImages(d *v12.Deployment) ([]*domain.ClusterImage, error) {
-	images, err := r.clusterImageService.FindImagesFromLabels(d.Namespace, d.Spec.Template.Labels)
+// Check checks images found by GetImages for the deployment with name. When some images could not be checked the
+// reports of the others are returned along with the errors.
+func (r *Reports) Check(ctx context.Context, name string, images []*domain.ClusterImage) ([]domain.ReportResult, error) {
+	return r.registryImageService.CheckComponents(ctx, registry.ComponentImages(name, images))
+}
+
+// GetImages gets the images used by d that are checked according to the registry policy of its namespace, resolved to
+// the repositories they are checked against
+func (r *Reports) GetImages(ctx context.Context, d *v12.Deployment) ([]*domain.ClusterImage, error) {
+	policy, err := r.policies.PolicyFor(ctx, d.Namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the registry policy for namespace "+d.Namespace)
+	}
+	images, err := r.clusterImageService.FindImagesFromPodTemplate(ctx, d, d.Spec.Template, policy, r.registryImageService)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get images for deployment "+d.Name+" in namespace "+d.Namespace)
 	}
-	return images, nil
+	return policy.Filter(images), nil
 }
@@ -2,14 +2,18 @@ package generic
 
 import (
 	"fmt"
-	"strings"
-	"time"
 
 	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/controller/lifecycle"
 	"github.com/integr8ly/heimdall/pkg/controller/validation"
 	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/notify"
 	"github.com/integr8ly/heimdall/pkg/registry"
+	"github.com/integr8ly/heimdall/pkg/schedule"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -21,27 +25,31 @@ type logger interface {
 // MakeGenericReconciler creates a generic reconciler that delegates the object
 // access to an impl HeimdallObjectInterface
 func MakeGenericReconciler(
-	requeueInterval time.Duration,
+	schedules schedule.Getter,
 	resourceName string,
 	log logger,
+	recorder record.EventRecorder,
+	notifier *notify.Notifier,
 	podService *cluster.Pods,
 	clusterImageService *cluster.ImageService,
 	registryImageService *registry.ImageService,
+	policies registry.PolicyGetter,
 	impl HeimdallObjectInterface,
 ) *Reconciler {
 	return &Reconciler{
 		HeimdallObjectInterface: impl,
 
-		requeueInterval: requeueInterval,
-		resourceName:    resourceName,
-		log:             log,
+		schedules:    schedules,
+		resourceName: resourceName,
+		log:          log,
+		scans:        NewScans(recorder, notifier, podService),
 		reportService: &Reports{
 			HeimdallObjectInterface: impl,
 			resourceName:            resourceName,
 			clusterImageService:     clusterImageService,
 			registryImageService:    registryImageService,
+			policies:                policies,
 		},
-		podService: podService,
 	}
 }
 
@@ -51,12 +59,12 @@ func MakeGenericReconciler(
 type Reconciler struct {
 	HeimdallObjectInterface
 
-	requeueInterval time.Duration
-	resourceName    string
-	log             logger
+	schedules    schedule.Getter
+	resourceName string
+	log          logger
+	scans        *Scans
 
 	reportService *Reports
-	podService    *cluster.Pods
 }
 
 // HeimdallObjectInterface knows how to access resources watched by Heimdall
@@ -64,29 +72,42 @@ type HeimdallObjectInterface interface {
 	GetObject(namespace, name string) (v1.Object, error)
 	UpdateObject(v1.Object) error
 	ListObjects(namespace string) ([]v1.Object, error)
-	GetPodTemplateLabels(obj v1.Object) map[string]string
+	GetPodTemplate(obj v1.Object) corev1.PodTemplateSpec
 }
 
 // Reconcile checks the images of an object managed by r's HeimdallObjectInterface
 // and labels the pods accordingly
 func (r *Reconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, cancel := lifecycle.ReconcileContext()
+	defer cancel()
+
 	obj, err := r.GetObject(request.Namespace, request.Name)
 	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			Forget(r.resourceName, request.Namespace, request.Name)
+		}
 		r.log.Error(err, fmt.Sprintf("failed to get %s in namespace %s with name %s",
 			r.resourceName, request.Name, request.Namespace))
 		return reconcile.Result{}, err
 	}
 
 	if _, ok := obj.GetLabels()[domain.HeimdallMonitored]; !ok {
+		Forget(r.resourceName, request.Namespace, request.Name)
 		return reconcile.Result{}, nil
 	}
 
-	images, err := r.reportService.GetImages(obj)
+	sched, err := r.schedules.ScheduleFor(ctx, request.Namespace)
+	if err != nil {
+		r.log.Error(err, "failed to get the check schedule for namespace "+request.Namespace)
+		return reconcile.Result{}, err
+	}
+
+	images, err := r.reportService.GetImages(ctx, obj)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
-	should, err := validation.ShouldCheck(obj, images)
+	should, err := validation.ShouldCheck(obj, images, sched)
 	if err != nil && validation.IsParseErr(err) {
 		delete(obj.GetAnnotations(), domain.HeimdallImagesChecked)
 		if err := r.UpdateObject(obj); err != nil {
@@ -103,7 +124,7 @@ func (r *Reconciler) Reconcile(request reconcile.Request) (reconcile.Result, err
 
 	if !should {
 		r.log.Info(fmt.Sprintf("criteria for re checking %s no met", request.Name))
-		return reconcile.Result{}, nil
+		return reconcile.Result{RequeueAfter: validation.RequeueAfter(obj, sched)}, nil
 	}
 
 	r.log.Info(fmt.Sprintf("%s %s in namespace %s is being monitored by heimdall",
@@ -112,14 +133,17 @@ func (r *Reconciler) Reconcile(request reconcile.Request) (reconcile.Result, err
 		request.Namespace,
 	))
 
-	report, err := r.reportService.Generate(request.Namespace, request.Name)
-	if err != nil {
-		r.log.Error(err, "failed to generate a report for images in %s %s in namespace %s",
+	report, checkErr := r.reportService.Check(ctx, request.Name, images)
+	if checkErr != nil {
+		r.log.Error(checkErr, fmt.Sprintf("failed to generate a report for images in %s %s in namespace %s",
 			r.resourceName,
 			request.Name,
 			request.Namespace,
-		)
-		return reconcile.Result{RequeueAfter: r.requeueInterval}, nil
+		))
+		if len(report) == 0 {
+			r.recordFailure(request, checkErr)
+			return reconcile.Result{RequeueAfter: validation.RetryAfter(sched)}, nil
+		}
 	}
 
 	r.log.Info(fmt.Sprintf("generated reports for %s", r.resourceName),
@@ -138,23 +162,11 @@ func (r *Reconciler) Reconcile(request reconcile.Request) (reconcile.Result, err
 		return reconcile.Result{}, nil
 	}
 
-	if obj.GetAnnotations() == nil {
-		obj.SetAnnotations(map[string]string{})
-	}
-	annotations := obj.GetAnnotations()
-
-	checked := []string{}
-	for _, rep := range report {
-		checked = append(checked, rep.ClusterImage.SHA256Path)
-		if err := r.podService.LabelPods(&rep); err != nil {
-			r.log.Error(err, "failed to label pod, will retry as soon as possible")
-			return reconcile.Result{}, nil
-		}
+	if err := r.scans.Checked(ctx, r.resourceName, obj, report, checkErr); err != nil {
+		r.log.Error(err, "failed to label pod, will retry as soon as possible")
+		return reconcile.Result{}, nil
 	}
 
-	annotations[domain.HeimdallLastChecked] = time.Now().Format(domain.TimeFormat)
-	annotations[domain.HeimdallImagesChecked] = strings.Join(checked, ",")
-
 	if err := r.UpdateObject(obj); err != nil {
 		r.log.Error(err, fmt.Sprintf("failed to annotate %s %s %s",
 			r.resourceName,
@@ -164,5 +176,31 @@ func (r *Reconciler) Reconcile(request reconcile.Request) (reconcile.Result, err
 		return reconcile.Result{}, nil
 	}
 
-	return reconcile.Result{RequeueAfter: r.requeueInterval}, nil
+	if checkErr != nil {
+		// retry the images that failed rather than waiting for the next check
+		return reconcile.Result{RequeueAfter: validation.RetryAfter(sched)}, nil
+	}
+	return reconcile.Result{RequeueAfter: validation.RequeueAfter(obj, sched)}, nil
+}
+
+// recordFailure records a failed check on the object so it is shown on the
+// status of the ImageMonitor
+func (r *Reconciler) recordFailure(request reconcile.Request, checkErr error) {
+	obj, err := r.GetObject(request.Namespace, request.Name)
+	if err != nil {
+		r.log.Error(err, fmt.Sprintf("failed to get %s %s/%s to record the failed check",
+			r.resourceName,
+			request.Namespace,
+			request.Name,
+		))
+		return
+	}
+	r.scans.Failed(r.resourceName, obj, checkErr)
+	if err := r.UpdateObject(obj); err != nil {
+		r.log.Error(err, fmt.Sprintf("failed to record the failed check on %s %s/%s",
+			r.resourceName,
+			request.Namespace,
+			request.Name,
+		))
+	}
 }
@@ -1,16 +1,18 @@
 package generic
 
 import (
-	"fmt"
-	"strings"
+	"context"
 
 	"github.com/integr8ly/heimdall/pkg/cluster"
 	"github.com/integr8ly/heimdall/pkg/domain"
 	"github.com/integr8ly/heimdall/pkg/registry"
 	"github.com/pkg/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
 
+var log = logf.Log.WithName("generic")
+
 // Reports contains the generic logic to create reports for the images of objects
 // managed by its HeimdallObjectInterface implementation
 type Reports struct {
@@ -18,6 +20,7 @@ type Reports struct {
 
 	clusterImageService  *cluster.ImageService
 	registryImageService *registry.ImageService
+	policies             registry.PolicyGetter
 
 	resourceName string
 }
@@ -28,24 +31,34 @@ func MakeGenericReports(
 	impl HeimdallObjectInterface,
 	clusterImageService *cluster.ImageService,
 	registryImageService *registry.ImageService,
+	policies registry.PolicyGetter,
 	resourceName string,
 ) *Reports {
 	return &Reports{
 		HeimdallObjectInterface: impl,
 		clusterImageService:     clusterImageService,
 		registryImageService:    registryImageService,
+		policies:                policies,
 		resourceName:            resourceName,
 	}
 }
 
-// GetImages gets a list of images used by obj
-func (r *Reports) GetImages(obj v1.Object) ([]*domain.ClusterImage, error) {
-	images, err := r.clusterImageService.FindImagesFromLabels(
-		obj.GetNamespace(),
-		r.GetPodTemplateLabels(obj),
+// GetImages gets the images used by obj that are checked according to the registry policy of its namespace, resolved
+// to the repositories they are checked against
+func (r *Reports) GetImages(ctx context.Context, obj v1.Object) ([]*domain.ClusterImage, error) {
+	policy, err := r.policies.PolicyFor(ctx, obj.GetNamespace())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the registry policy for namespace %s", obj.GetNamespace())
+	}
+	images, err := r.clusterImageService.FindImagesFromPodTemplate(
+		ctx,
+		obj,
+		r.GetPodTemplate(obj),
+		policy,
+		r.registryImageService,
 	)
 	if err == nil {
-		return images, nil
+		return policy.Filter(images), nil
 	}
 
 	return nil, errors.Wrapf(
@@ -57,11 +70,12 @@ func (r *Reports) GetImages(obj v1.Object) ([]*domain.ClusterImage, error) {
 }
 
 // Generate generates a report for an object with a given name and namespace,
-// delegating the object access logic to r's HeimdallObjectInterface
-func (r *Reports) Generate(namespace, name string) ([]domain.ReportResult, error) {
-	var reports []domain.ReportResult
+// delegating the object access logic to r's HeimdallObjectInterface. Checks still
+// in progress when ctx is done are abandoned. When some images could not be found or
+// checked the reports of the others are returned along with the errors.
+func (r *Reports) Generate(ctx context.Context, namespace, name string) ([]domain.ReportResult, error) {
 	var objects []v1.Object
-	checked := map[string]domain.ReportResult{}
+	var toCheck []registry.ComponentImage
 
 	if name == "*" {
 		objectsList, err := r.ListObjects(namespace)
@@ -84,34 +98,25 @@ func (r *Reports) Generate(namespace, name string) ([]domain.ReportResult, error
 		objects = []v1.Object{object}
 	}
 
+	var errs registry.CheckErrors
 	for _, obj := range objects {
-		images, err := r.GetImages(obj)
+		images, err := r.GetImages(ctx, obj)
 		if err != nil {
-			fmt.Print("error finding images", err)
+			log.Error(err, "error finding images")
+			errs = append(errs, err)
 		}
+		toCheck = append(toCheck, registry.ComponentImages(obj.GetName(), images)...)
+	}
 
-		for _, i := range images {
-			if !strings.Contains(i.FullPath, "redhat") {
-				continue
-			}
-
-			if rep, ok := checked[i.SHA256Path]; ok {
-				rep.Component = obj.GetName()
-				reports = append(reports, rep)
-				continue
-			}
-
-			result, err := r.registryImageService.Check(i)
-			if err != nil {
-				fmt.Println(err, "a report failed ")
-			} else {
-				result.Component = obj.GetName()
-				reports = append(reports, result)
-			}
-
-			checked[i.FullPath] = result
-		}
+	reports, err := r.registryImageService.CheckComponents(ctx, toCheck)
+	if err != nil {
+		errs = append(errs, err)
 	}
+	return reports, errs.Err()
+}
 
-	return reports, nil
+// Check checks images found by GetImages for the object with name. Checks still in progress when ctx is done are
+// abandoned. When some images could not be checked the reports of the others are returned along with the errors.
+func (r *Reports) Check(ctx context.Context, name string, images []*domain.ClusterImage) ([]domain.ReportResult, error) {
+	return r.registryImageService.CheckComponents(ctx, registry.ComponentImages(name, images))
 }
@@ -0,0 +1,85 @@
+package generic
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/customMetrics"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/export"
+	"github.com/integr8ly/heimdall/pkg/notify"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// Scans records the outcome of checking the images of a workload everywhere it is reported: the labels of its pods, the
+// export store, the scan metrics, events, notifications and the annotations the ImageMonitor controller collects. Every
+// reconciler of a kind of workload records its checks through it so they are reported the same way.
+type Scans struct {
+	recorder record.EventRecorder
+	notifier *notify.Notifier
+	pods     *cluster.Pods
+}
+
+func NewScans(recorder record.EventRecorder, notifier *notify.Notifier, pods *cluster.Pods) *Scans {
+	return &Scans{recorder: recorder, notifier: notifier, pods: pods}
+}
+
+// Checked labels the pods using the checked images and records the reports of a check of obj, a workload of kind.
+// checkErr is the failure of the images that could not be checked, if any. The annotations of obj are updated so obj
+// still has to be saved.
+func (s *Scans) Checked(ctx context.Context, kind string, obj v1.Object, reports []domain.ReportResult, checkErr error) error {
+	checked := []string{}
+	for _, rep := range reports {
+		checked = append(checked, rep.ClusterImage.SHA256Path)
+		if err := s.pods.LabelPods(ctx, &rep); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	// the results of the images that failed to be checked are kept until they can be checked again
+	complete := checkErr == nil
+	if complete {
+		export.Results.Set(kind, obj.GetNamespace(), obj.GetName(), reports)
+	} else {
+		export.Results.Merge(kind, obj.GetNamespace(), obj.GetName(), reports)
+	}
+	customMetrics.RecordScan(kind, obj.GetNamespace(), obj.GetName(), reports, now, complete)
+	if ro, ok := obj.(runtime.Object); ok {
+		cluster.RecordScanEvents(s.recorder, ro, reports, checkErr)
+	}
+	s.notifier.Annotate(ctx, obj, reports, complete)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[domain.HeimdallLastChecked] = now.Format(domain.TimeFormat)
+	annotations[domain.HeimdallImagesChecked] = strings.Join(checked, ",")
+	obj.SetAnnotations(annotations)
+	if err := cluster.SetWorkloadStatus(obj, cluster.NewWorkloadStatus(reports, checkErr, now)); err != nil {
+		log.Error(err, "failed to record the status of "+kind+" "+obj.GetName())
+	}
+	return nil
+}
+
+// Failed records a check of obj, a workload of kind, that failed before any image was checked. The results of its last
+// check and its last checked annotation are left as they are so it is checked again and nothing is reported as
+// resolved. The annotations of obj are updated so obj still has to be saved.
+func (s *Scans) Failed(kind string, obj v1.Object, checkErr error) {
+	if ro, ok := obj.(runtime.Object); ok {
+		cluster.RecordScanFailure(s.recorder, ro, checkErr)
+	}
+	if err := cluster.SetWorkloadFailure(obj, checkErr); err != nil {
+		log.Error(err, "failed to record the status of "+kind+" "+obj.GetName())
+	}
+}
+
+// Forget removes the results of the last check of a workload of kind that is deleted or no longer monitored
+func Forget(kind, namespace, name string) {
+	export.Results.Delete(kind, namespace, name)
+	customMetrics.DeleteScan(kind, namespace, name)
+}
@@ -2,13 +2,23 @@ package imagemonitor
 
 import (
 	"context"
+	"reflect"
+	"time"
 
 	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
 	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/controller/lifecycle"
 	"github.com/integr8ly/heimdall/pkg/domain"
+	v1 "github.com/openshift/api/apps/v1"
 	"github.com/pkg/errors"
+	v12 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -42,7 +52,36 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	if err != nil {
 		return err
 	}
-	return c.Watch(&source.Kind{Type: &v1alpha1.ImageMonitor{}}, &handler.EnqueueRequestForObject{})
+	if err := c.Watch(&source.Kind{Type: &v1alpha1.ImageMonitor{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+	// the workload controllers record the result of each check on the workload, watch them to update the status
+	toMonitors := &handler.EnqueueRequestsFromMapFunc{ToRequests: monitorsInNamespace(mgr.GetClient())}
+	for _, t := range []runtime.Object{&v1.DeploymentConfig{}, &v12.Deployment{}, &v12.StatefulSet{}, &v12.DaemonSet{}, &batchv1beta1.CronJob{}, &batchv1.Job{}} {
+		if err := c.Watch(&source.Kind{Type: t}, toMonitors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// monitorsInNamespace maps a monitored workload to the ImageMonitors in its namespace
+func monitorsInNamespace(c client.Client) handler.ToRequestsFunc {
+	return func(o handler.MapObject) []reconcile.Request {
+		if _, ok := o.Meta.GetLabels()[domain.HeimdallMonitored]; !ok {
+			return nil
+		}
+		monitors := &v1alpha1.ImageMonitorList{}
+		if err := c.List(context.TODO(), monitors, &client.ListOptions{Namespace: o.Meta.GetNamespace()}); err != nil {
+			log.Error(err, "failed to list image monitors in namespace "+o.Meta.GetNamespace())
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, m := range monitors.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: m.Namespace, Name: m.Name}})
+		}
+		return requests
+	}
 }
 
 // newReconciler returns a new reconcile.Reconciler
@@ -51,6 +90,9 @@ func newReconciler(mgr manager.Manager, k8sClient kubernetes.Interface) reconcil
 	r := &ReconcileImageMonitor{
 		client:        c,
 		objectLabeler: cluster.NewObjectLabeler(c),
+		monitors:      cluster.NewMonitors(c),
+		alerts:        cluster.NewAlerts(c, mgr.GetAPIReader()),
+		recorder:      mgr.GetEventRecorderFor(cluster.EventRecorderName),
 	}
 	return r
 }
@@ -58,12 +100,16 @@ func newReconciler(mgr manager.Manager, k8sClient kubernetes.Interface) reconcil
 type ReconcileImageMonitor struct {
 	client        client.Client
 	objectLabeler *cluster.ObjectsLabeler
+	monitors      *cluster.Monitors
+	alerts        *cluster.Alerts
+	recorder      record.EventRecorder
 }
 
 func (r *ReconcileImageMonitor) Reconcile(request reconcile.Request) (reconcile.Result, error) {
-	ctx := context.TODO()
+	ctx, cancel := lifecycle.ReconcileContext()
+	defer cancel()
 	imageMon := &v1alpha1.ImageMonitor{}
-	err := r.client.Get(context.TODO(), client.ObjectKey{Namespace: request.Namespace, Name: request.Name}, imageMon)
+	err := r.client.Get(ctx, client.ObjectKey{Namespace: request.Namespace, Name: request.Name}, imageMon)
 	if err != nil {
 		if errors2.IsNotFound(err) {
 			return reconcile.Result{}, nil
@@ -105,8 +151,35 @@ func (r *ReconcileImageMonitor) Reconcile(request reconcile.Request) (reconcile.
 	}
 	log.Info("have image monitor for namespace " + imageMon.Namespace + " with name " + imageMon.Name)
 	// find deployment configs and deployments that match in the namespace and label them
-	if err := r.objectLabeler.LabelObjects(ctx, map[string]string{domain.HeimdallMonitored: "true"}, imageMon.Spec.ExcludePattern, imageMon.Namespace); err != nil {
+	selection, err := cluster.NewWorkloadSelection(imageMon.Spec.ExcludePattern, imageMon.Spec.WorkloadFilter)
+	if err != nil {
 		return reconcile.Result{}, err
 	}
-	return reconcile.Result{}, nil
+	if err := r.objectLabeler.LabelObjects(ctx, map[string]string{domain.HeimdallMonitored: "true"}, selection, imageMon.Namespace); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := r.alerts.Ensure(ctx, imageMon); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, r.updateStatus(ctx, imageMon)
+}
+
+// updateStatus collects the results recorded on the monitored workloads into the status of the ImageMonitor
+func (r *ReconcileImageMonitor) updateStatus(ctx context.Context, imageMon *v1alpha1.ImageMonitor) error {
+	workloads, err := r.monitors.WorkloadStatuses(ctx, imageMon.Namespace)
+	if err != nil {
+		return errors.Wrap(err, "failed to collect the status of the monitored workloads")
+	}
+	status := imageMon.Status.DeepCopy()
+	cluster.SetStatus(status, workloads, time.Now())
+	if reflect.DeepEqual(*status, imageMon.Status) {
+		return nil
+	}
+	previous := imageMon.Status.Conditions
+	imageMon.Status = *status
+	if err := r.client.Status().Update(ctx, imageMon); err != nil {
+		return errors.Wrap(err, "failed to update the status of image monitor "+imageMon.Name)
+	}
+	cluster.RecordConditionEvents(r.recorder, imageMon, previous, status.Conditions)
+	return nil
 }
@@ -0,0 +1,122 @@
+package job
+
+import (
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/controller/generic"
+	"github.com/integr8ly/heimdall/pkg/notify"
+	"github.com/integr8ly/heimdall/pkg/registry"
+	imagesv1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+	"github.com/pkg/errors"
+	v12 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	v1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_job")
+
+// Add creates a new Job Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	client, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return errors.Wrap(err, "failed to create k8s client")
+	}
+
+	isClient, err := imagesv1.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return errors.Wrap(err, "failed to create images client")
+	}
+
+	registryImageService, err := registry.DefaultImagesService()
+	if err != nil {
+		return err
+	}
+
+	return add(mgr, newReconciler(mgr, client, isClient, registryImageService))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager, client kubernetes.Interface, isClient *imagesv1.ImageV1Client, registryImageService *registry.ImageService) reconcile.Reconciler {
+	clusterImageService := cluster.NewImageService(client, isClient)
+
+	impl := &objectInterface{
+		client: client.BatchV1(),
+	}
+
+	return generic.MakeGenericReconciler(
+		cluster.NewMonitors(mgr.GetClient()),
+		"job",
+		log,
+		mgr.GetEventRecorderFor(cluster.EventRecorderName),
+		notify.NewNotifier(mgr.GetClient(), mgr.GetAPIReader()),
+		cluster.NewPods(mgr.GetClient()),
+		clusterImageService,
+		registryImageService,
+		cluster.NewMonitors(mgr.GetClient()),
+		impl,
+	)
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("job-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource Job
+	return c.Watch(&source.Kind{Type: &v12.Job{}}, &handler.EnqueueRequestForObject{})
+}
+
+// blank assignment to verify that ReconcileJob implements HeimdallObjectInterface
+var _ generic.HeimdallObjectInterface = &objectInterface{}
+
+// objectInterface is an implementation of generic.HeimdallObjectInterface
+// that knows how to access jobs
+type objectInterface struct {
+	client v1.BatchV1Interface
+}
+
+// ListObjects gets the jobs in namespace as v1.Object types. Jobs created by a
+// cron job are left out as they are checked along with the cron job.
+func (r *objectInterface) ListObjects(namespace string) ([]metav1.Object, error) {
+	jobs, err := r.client.Jobs(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []metav1.Object
+	for i := range jobs.Items {
+		if owner := metav1.GetControllerOf(&jobs.Items[i]); owner != nil && owner.Kind == "CronJob" {
+			continue
+		}
+		result = append(result, &jobs.Items[i])
+	}
+
+	return result, nil
+}
+
+// GetPodTemplate gets the pod template for a given job obj
+func (r *objectInterface) GetPodTemplate(obj metav1.Object) corev1.PodTemplateSpec {
+	return obj.(*v12.Job).Spec.Template
+}
+
+// GetObject gets a job name in namespace
+func (r *objectInterface) GetObject(namespace, name string) (metav1.Object, error) {
+	return r.client.Jobs(namespace).Get(name, metav1.GetOptions{})
+}
+
+// UpdateObject updates a job
+func (r *objectInterface) UpdateObject(obj metav1.Object) error {
+	_, err := r.client.Jobs(obj.GetNamespace()).Update(obj.(*v12.Job))
+	return err
+}
@@ -0,0 +1,51 @@
+package lifecycle
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("lifecycle")
+
+const (
+	EnvReconcileTimeout     = "HEIMDALL_RECONCILE_TIMEOUT"
+	DefaultReconcileTimeout = 15 * time.Minute
+)
+
+var (
+	root, stop       = context.WithCancel(context.Background())
+	reconcileTimeout time.Duration
+	timeoutOnce      sync.Once
+)
+
+// AddToManager cancels the context of every reconcile in progress when the manager stops so that checks waiting on a
+// registry or the rhcc api do not hold up the shutdown
+func AddToManager(mgr manager.Manager) error {
+	return mgr.Add(manager.RunnableFunc(func(s <-chan struct{}) error {
+		<-s
+		stop()
+		return nil
+	}))
+}
+
+// ReconcileContext returns the context a reconcile should run with. It is cancelled when the manager stops or after
+// the reconcile timeout set in HEIMDALL_RECONCILE_TIMEOUT so a stuck check does not block a worker forever.
+func ReconcileContext() (context.Context, context.CancelFunc) {
+	timeoutOnce.Do(func() {
+		reconcileTimeout = DefaultReconcileTimeout
+		if v := os.Getenv(EnvReconcileTimeout); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Error(err, "failed to parse "+EnvReconcileTimeout+", using the default", "default", DefaultReconcileTimeout.String())
+				return
+			}
+			reconcileTimeout = d
+		}
+	})
+	return context.WithTimeout(root, reconcileTimeout)
+}
@@ -0,0 +1,92 @@
+package operators
+
+import (
+	"context"
+	"strings"
+
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/registry"
+	"github.com/pkg/errors"
+)
+
+const componentPrefix = cluster.KindClusterServiceVersion + "/"
+
+// Reports generates reports for the images of the operators installed by OLM. It is only used by the cli, the operator
+// does not watch ClusterServiceVersions and has no access to them.
+type Reports struct {
+	operators            *cluster.Operators
+	registryImageService *registry.ImageService
+	policies             registry.PolicyGetter
+}
+
+// NewReport creates a Reports for the operators operators finds
+func NewReport(operators *cluster.Operators, registryImageService *registry.ImageService, policies registry.PolicyGetter) *Reports {
+	return &Reports{
+		operators:            operators,
+		registryImageService: registryImageService,
+		policies:             policies,
+	}
+}
+
+// Generate generates a report for the operators installed in namespace ns. The component of each report is
+// ClusterServiceVersion/ and the name of the cluster service version. A name other than * only reports the cluster
+// service version with that name.
+func (r *Reports) Generate(ctx context.Context, ns, name string) ([]domain.ReportResult, error) {
+	policy, err := r.policies.PolicyFor(ctx, ns)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the registry policy for namespace "+ns)
+	}
+	installed, err := r.operators.FindInstalled(ctx, ns, policy, r.registryImageService)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find the operators installed in namespace "+ns)
+	}
+
+	var toCheck []registry.ComponentImage
+	for _, op := range installed {
+		if name != "*" && name != op.Name {
+			continue
+		}
+		for _, i := range op.Images {
+			if i = policy.Exclude(i); i == nil {
+				continue
+			}
+			i = policy.Resolve(i)
+			if !policy.Allowed(i) {
+				continue
+			}
+			toCheck = append(toCheck, registry.ComponentImage{Component: componentPrefix + op.Name, Image: i})
+		}
+	}
+	return r.registryImageService.CheckComponents(ctx, toCheck)
+}
+
+// Update is an installed operator version with images that have newer patch builds fixing CVEs
+type Update struct {
+	// Operator is the name of the cluster service version
+	Operator string
+	Images   []domain.ReportResult
+}
+
+// Updates returns the operators in reports generated by Reports that have images with a newer patch build resolving
+// CVEs, in the order they are first reported
+func Updates(reports []domain.ReportResult) []Update {
+	var updates []Update
+	index := map[string]int{}
+	for _, rep := range reports {
+		if !strings.HasPrefix(rep.Component, componentPrefix) {
+			continue
+		}
+		if rep.LatestAvailablePatchVersion == rep.CurrentVersion || len(rep.ResolvableCVEs) == 0 {
+			continue
+		}
+		i, ok := index[rep.Component]
+		if !ok {
+			i = len(updates)
+			index[rep.Component] = i
+			updates = append(updates, Update{Operator: strings.TrimPrefix(rep.Component, componentPrefix)})
+		}
+		updates[i].Images = append(updates[i].Images, rep)
+	}
+	return updates
+}
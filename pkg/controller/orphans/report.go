@@ -0,0 +1,59 @@
+package orphans
+
+import (
+	"context"
+
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/registry"
+	"github.com/pkg/errors"
+)
+
+// Reports generates reports for the images of pods that are not controlled by a workload Heimdall monitors, such as
+// pods created on their own and the pods of replica sets created without a deployment
+type Reports struct {
+	clusterImageService  *cluster.ImageService
+	registryImageService *registry.ImageService
+	policies             registry.PolicyGetter
+}
+
+// NewReport creates a Reports for orphan pods
+func NewReport(clusterImageService *cluster.ImageService, registryImageService *registry.ImageService, policies registry.PolicyGetter) *Reports {
+	return &Reports{
+		clusterImageService:  clusterImageService,
+		registryImageService: registryImageService,
+		policies:             policies,
+	}
+}
+
+// Generate generates a report for the orphan pods in namespace ns. The component of each report is the kind and name of
+// the top most controller of the pods, or Pod and the pod name for a pod without one, so orphans can not be mistaken for
+// workloads with the same name. A name other than * only reports the orphans of the object with that name.
+func (r *Reports) Generate(ctx context.Context, ns, name string) ([]domain.ReportResult, error) {
+	policy, err := r.policies.PolicyFor(ctx, ns)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the registry policy for namespace "+ns)
+	}
+	orphans, err := r.clusterImageService.FindOrphanImages(ctx, ns)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find the images of orphan pods in namespace "+ns)
+	}
+
+	var toCheck []registry.ComponentImage
+	for _, o := range orphans {
+		if name != "*" && name != o.Name {
+			continue
+		}
+		for _, i := range o.Images {
+			if i = policy.Exclude(i); i == nil {
+				continue
+			}
+			i = policy.Resolve(i)
+			if !policy.Allowed(i) {
+				continue
+			}
+			toCheck = append(toCheck, registry.ComponentImage{Component: o.Kind + "/" + o.Name, Image: i})
+		}
+	}
+	return r.registryImageService.CheckComponents(ctx, toCheck)
+}
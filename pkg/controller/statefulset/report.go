@@ -8,11 +8,12 @@ import (
 )
 
 // NewReport creates a generic.Reports that generates reports for stateful sets
-func NewReport(clusterImageService *cluster.ImageService, registryImageService *registry.ImageService, client v1.AppsV1Interface) *generic.Reports {
+func NewReport(clusterImageService *cluster.ImageService, registryImageService *registry.ImageService, policies registry.PolicyGetter, client v1.AppsV1Interface) *generic.Reports {
 	return generic.MakeGenericReports(
 		&objectInterface{client},
 		clusterImageService,
 		registryImageService,
+		policies,
 		"stateful set",
 	)
 }
@@ -1,15 +1,14 @@
 package statefulset
 
 import (
-	"time"
-
 	"github.com/integr8ly/heimdall/pkg/cluster"
 	"github.com/integr8ly/heimdall/pkg/controller/generic"
+	"github.com/integr8ly/heimdall/pkg/notify"
 	"github.com/integr8ly/heimdall/pkg/registry"
-	"github.com/integr8ly/heimdall/pkg/rhcc"
 	imagesv1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
 	"github.com/pkg/errors"
 	v12 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	v1 "k8s.io/client-go/kubernetes/typed/apps/v1"
@@ -25,8 +24,6 @@ var log = logf.Log.WithName("controller_statefulset")
 
 const labelFormat = "heimdall.%s"
 
-const requeueInterval = time.Hour * 4
-
 // Add creates a new StatefulSet Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
@@ -40,25 +37,32 @@ func Add(mgr manager.Manager) error {
 		return errors.Wrap(err, "failed to create images client")
 	}
 
-	return add(mgr, newReconciler(mgr, client, isClient))
+	registryImageService, err := registry.DefaultImagesService()
+	if err != nil {
+		return err
+	}
+
+	return add(mgr, newReconciler(mgr, client, isClient, registryImageService))
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager, client kubernetes.Interface, isClient *imagesv1.ImageV1Client) reconcile.Reconciler {
+func newReconciler(mgr manager.Manager, client kubernetes.Interface, isClient *imagesv1.ImageV1Client, registryImageService *registry.ImageService) reconcile.Reconciler {
 	clusterImageService := cluster.NewImageService(client, isClient)
-	registryImageService := registry.NewImagesService(&registry.Client{}, &rhcc.Client{}, &rhcc.Client{})
 
 	impl := &objectInterface{
 		client: client.AppsV1(),
 	}
 
 	return generic.MakeGenericReconciler(
-		requeueInterval,
+		cluster.NewMonitors(mgr.GetClient()),
 		"stateful set",
 		log,
+		mgr.GetEventRecorderFor(cluster.EventRecorderName),
+		notify.NewNotifier(mgr.GetClient(), mgr.GetAPIReader()),
 		cluster.NewPods(mgr.GetClient()),
 		clusterImageService,
 		registryImageService,
+		cluster.NewMonitors(mgr.GetClient()),
 		impl,
 	)
 }
@@ -99,9 +103,9 @@ func (r *objectInterface) ListObjects(namespace string) ([]metav1.Object, error)
 	return result, nil
 }
 
-// GetPodTemplateLabels gets the pod template labels for a given stateful set obj
-func (r *objectInterface) GetPodTemplateLabels(obj metav1.Object) map[string]string {
-	return obj.(*v12.StatefulSet).Spec.Template.Labels
+// GetPodTemplate gets the pod template for a given stateful set obj
+func (r *objectInterface) GetPodTemplate(obj metav1.Object) corev1.PodTemplateSpec {
+	return obj.(*v12.StatefulSet).Spec.Template
 }
 
 // GetObject gets a stateful set name in namespace
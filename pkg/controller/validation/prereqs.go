@@ -1,26 +1,22 @@
 package validation
 
 import (
-	"fmt"
 	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/schedule"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"os"
-	"strconv"
 	"strings"
 	"time"
 )
 
-func ShouldCheck(meta v1.Object, images []*domain.ClusterImage) (bool, error) {
-	minsBeforeCheckEnv := os.Getenv("HEIMDALL_RECHECK_MINS")
-	var recheckAfter time.Duration = time.Duration(domain.MinRecheckIntervalMins)
-	var parseErr error
-	if minsBeforeCheckEnv != "" {
-		v, err := strconv.ParseInt(minsBeforeCheckEnv, 10, 32)
-		if err != nil {
-			parseErr = &ParseErr{Message: fmt.Sprintf(" failed to parse env var to int HEIMDALL_RECHECK_MINS: %s  using default value %v ", err.Error(), recheckAfter)}
-		} else {
-			recheckAfter = time.Duration(v)
-		}
+// minRequeue stops a workload being requeued in a tight loop when its next check is due right away
+const minRequeue = time.Minute
+
+// ShouldCheck decides if the images of a workload should be checked now according to sched. Nothing is checked during
+// a maintenance window, otherwise a workload is checked when its images have changed or its next check is due.
+func ShouldCheck(meta v1.Object, images []*domain.ClusterImage, sched *schedule.Schedule) (bool, error) {
+	now := time.Now()
+	if _, ok := sched.InMaintenance(now); ok {
+		return false, nil
 	}
 	annotations := meta.GetAnnotations()
 	// If the images have changed we always want to check
@@ -46,5 +42,37 @@ func ShouldCheck(meta v1.Object, images []*domain.ClusterImage) (bool, error) {
 	if err != nil {
 		return false, &ParseErr{Message: err.Error()}
 	}
-	return checkedTime.Add(time.Minute * recheckAfter).Before(time.Now()), parseErr
+	return sched.Due(checkedTime, now), nil
+}
+
+// RequeueAfter returns how long to wait before looking at a workload again so it is seen when its next check is due
+// according to sched, or once the current maintenance window ends. Zero is returned if it is never due again.
+func RequeueAfter(meta v1.Object, sched *schedule.Schedule) time.Duration {
+	now := time.Now()
+	lastChecked, err := time.Parse(time.RFC822Z, meta.GetAnnotations()[domain.HeimdallLastChecked])
+	if err != nil {
+		// not checked yet, so the check is due as soon as any maintenance window ends
+		if end, ok := sched.InMaintenance(now); ok {
+			return end.Sub(now)
+		}
+		return minRequeue
+	}
+	return requeueAfter(sched.NextCheck(lastChecked, now), now)
+}
+
+// RetryAfter returns how long to wait before retrying a failed check, which is when the next check would be due if the
+// workload had been checked now
+func RetryAfter(sched *schedule.Schedule) time.Duration {
+	now := time.Now()
+	return requeueAfter(sched.NextCheck(now, now), now)
+}
+
+func requeueAfter(next, now time.Time) time.Duration {
+	if next.IsZero() {
+		return 0
+	}
+	if after := next.Sub(now); after > minRequeue {
+		return after
+	}
+	return minRequeue
 }
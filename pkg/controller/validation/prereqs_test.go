@@ -3,12 +3,29 @@ package validation_test
 import (
 	"github.com/integr8ly/heimdall/pkg/controller/validation"
 	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/schedule"
 	v12 "k8s.io/api/apps/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"testing"
 	"time"
 )
 
+func mustSchedule(t *testing.T, interval time.Duration, cron string, windows ...schedule.Window) *schedule.Schedule {
+	s, err := schedule.New(interval, cron, windows...)
+	if err != nil {
+		t.Fatal("failed to create schedule ", err)
+	}
+	return s
+}
+
+func mustWindow(t *testing.T, start string, duration time.Duration) schedule.Window {
+	w, err := schedule.NewWindow(start, duration)
+	if err != nil {
+		t.Fatal("failed to create maintenance window ", err)
+	}
+	return w
+}
+
 func TestShouldCheck(t *testing.T) {
 	cases := []struct {
 		Name      string
@@ -16,6 +33,7 @@ func TestShouldCheck(t *testing.T) {
 		Expect    bool
 		ExpectErr bool
 		Images    []*domain.ClusterImage
+		Schedule  func(t *testing.T) *schedule.Schedule
 	}{
 		{
 			Name: "test should not check when not enough time has passed",
@@ -92,11 +110,55 @@ func TestShouldCheck(t *testing.T) {
 			Expect:    true,
 			ExpectErr: false,
 		},
+		{
+			Name: "test should check when the cron schedule has matched since the last check",
+			Object: &v12.Deployment{
+				ObjectMeta: v1.ObjectMeta{
+					Annotations: map[string]string{
+						domain.HeimdallLastChecked: time.Now().Add(-2 * time.Hour).Format(domain.TimeFormat),
+					},
+				},
+			},
+			Schedule: func(t *testing.T) *schedule.Schedule {
+				return mustSchedule(t, 0, "@hourly")
+			},
+			Expect: true,
+		},
+		{
+			Name: "test should not check during a maintenance window even when images have changed",
+			Object: &v12.Deployment{
+				ObjectMeta: v1.ObjectMeta{
+					Annotations: map[string]string{
+						domain.HeimdallImagesChecked: "someimage",
+					},
+				},
+			},
+			Images: []*domain.ClusterImage{{SHA256Path: "someimage2"}},
+			Schedule: func(t *testing.T) *schedule.Schedule {
+				return mustSchedule(t, time.Hour, "", mustWindow(t, "* * * * *", time.Hour))
+			},
+			Expect: false,
+		},
+		{
+			Name: "test error when last checked can not be parsed",
+			Object: &v12.Deployment{
+				ObjectMeta: v1.ObjectMeta{
+					Annotations: map[string]string{
+						domain.HeimdallLastChecked: "yesterday",
+					},
+				},
+			},
+			ExpectErr: true,
+		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
-			should, err := validation.ShouldCheck(tc.Object, tc.Images)
+			sched := schedule.Every(24 * time.Hour)
+			if tc.Schedule != nil {
+				sched = tc.Schedule(t)
+			}
+			should, err := validation.ShouldCheck(tc.Object, tc.Images, sched)
 			if tc.ExpectErr && err == nil {
 				t.Fatal("expected and error but got none")
 			}
@@ -109,3 +171,61 @@ func TestShouldCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestRequeueAfter(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Object   v1.Object
+		Schedule func(t *testing.T) *schedule.Schedule
+		Min      time.Duration
+		Max      time.Duration
+	}{
+		{
+			Name: "test requeue when the interval next passes",
+			Object: &v12.Deployment{
+				ObjectMeta: v1.ObjectMeta{
+					Annotations: map[string]string{
+						domain.HeimdallLastChecked: time.Now().Add(-time.Hour).Format(domain.TimeFormat),
+					},
+				},
+			},
+			Schedule: func(t *testing.T) *schedule.Schedule {
+				return schedule.Every(4 * time.Hour)
+			},
+			Min: 2*time.Hour + 58*time.Minute,
+			Max: 3 * time.Hour,
+		},
+		{
+			Name: "test requeue soon when the check is overdue",
+			Object: &v12.Deployment{
+				ObjectMeta: v1.ObjectMeta{
+					Annotations: map[string]string{
+						domain.HeimdallLastChecked: time.Now().AddDate(0, 0, -2).Format(domain.TimeFormat),
+					},
+				},
+			},
+			Schedule: func(t *testing.T) *schedule.Schedule {
+				return schedule.Every(4 * time.Hour)
+			},
+			Min: time.Minute,
+			Max: time.Minute,
+		},
+		{
+			Name:   "test requeue when the maintenance window ends when never checked",
+			Object: &v12.Deployment{},
+			Schedule: func(t *testing.T) *schedule.Schedule {
+				return mustSchedule(t, time.Hour, "", mustWindow(t, "* * * * *", time.Hour))
+			},
+			Min: 58 * time.Minute,
+			Max: time.Hour,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			after := validation.RequeueAfter(tc.Object, tc.Schedule(t))
+			if after < tc.Min || after > tc.Max {
+				t.Fatal("expected requeue after between ", tc.Min, " and ", tc.Max, " but got ", after)
+			}
+		})
+	}
+}
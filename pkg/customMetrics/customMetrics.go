@@ -21,10 +21,44 @@ var (
 			Name: "heimdall_registry_calls_failure",
 			Help: "Number of failed registry calls",
 		})
+	CacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "heimdall_cache_hits_total",
+			Help: "Number of registry and rhcc lookups served from the cache",
+		}, []string{"cache"})
+	CacheMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "heimdall_cache_misses_total",
+			Help: "Number of registry and rhcc lookups not found in the cache",
+		}, []string{"cache"})
+	HTTPRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "heimdall_http_retries_total",
+			Help: "Number of registry, rhcc and CVE source requests retried after a transient failure",
+		}, []string{"host"})
+	HTTPThrottled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "heimdall_http_throttled_total",
+			Help: "Number of 429 Too Many Requests responses received",
+		}, []string{"host"})
+	HTTPRateLimited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "heimdall_http_rate_limited_total",
+			Help: "Number of requests delayed by the per host rate limit",
+		}, []string{"host"})
 )
 
 func init() {
 	metrics.Registry.MustRegister(RegistryCallsTotal)
 	metrics.Registry.MustRegister(RegistryCallsSuccess)
 	metrics.Registry.MustRegister(RegistryCallsFailure)
+	metrics.Registry.MustRegister(CacheHits)
+	metrics.Registry.MustRegister(CacheMisses)
+	metrics.Registry.MustRegister(HTTPRetries)
+	metrics.Registry.MustRegister(HTTPThrottled)
+	metrics.Registry.MustRegister(HTTPRateLimited)
+	metrics.Registry.MustRegister(ImageResolvableCVEs)
+	metrics.Registry.MustRegister(ImageUpToDate)
+	metrics.Registry.MustRegister(ImageFreshnessGrade)
+	metrics.Registry.MustRegister(LastScanTimestamp)
 }
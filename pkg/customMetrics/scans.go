@@ -0,0 +1,178 @@
+package customMetrics
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// kind is the kind of the workload so workloads of different kinds with the same name have their own series
+var imageLabels = []string{"namespace", "kind", "component", "container", "image"}
+
+var (
+	ImageResolvableCVEs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "heimdall_image_resolvable_cves",
+			Help: "Number of CVEs in the image of a container that updating to the latest patch build resolves",
+		}, append(imageLabels, "severity"))
+	ImageUpToDate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "heimdall_image_up_to_date",
+			Help: "Whether the image of a container is the latest build of its own tag or of its floating tag, or on the latest patch tag, 1 when it is",
+		}, append(imageLabels, "tag"))
+	ImageFreshnessGrade = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "heimdall_image_freshness_grade",
+			Help: "Freshness grade of the image of a container, 0 for A through 5 for F",
+		}, imageLabels)
+	LastScanTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "heimdall_last_scan_timestamp_seconds",
+			Help: "Time of the last successful check of the images of a component",
+		}, []string{"namespace", "kind", "component"})
+)
+
+// the severities a resolvable CVE count is always set for so alerts do not depend on a series existing
+var scanSeverities = []string{"critical", "important", "moderate", "low"}
+
+const grades = "ABCDEF"
+
+type series struct {
+	vec    *prometheus.GaugeVec
+	labels prometheus.Labels
+}
+
+// String identifies the series, the labels of a map are printed in key order
+func (s series) String() string {
+	return fmt.Sprintf("%p%v", s.vec, s.labels)
+}
+
+// scans are the series set for each workload. The series a workload's next scan does not set are deleted so images
+// and containers that are no longer used stop being reported, the others are kept so alerts pending on them are not
+// reset.
+var scans = struct {
+	sync.Mutex
+	series map[string][]series
+}{series: map[string][]series{}}
+
+func scanKey(kind, namespace, name string) string {
+	return namespace + "/" + kind + "/" + name
+}
+
+// RecordScan sets the scan result gauges of the workload of kind with name in namespace from its reports. A scan that is
+// not complete, as some images failed to be checked, keeps the series of the previous scan and its time so the images
+// that could not be checked are still reported.
+func RecordScan(kind, namespace, name string, reports []domain.ReportResult, at time.Time, complete bool) {
+	var set []series
+	gauge := func(vec *prometheus.GaugeVec, labels prometheus.Labels, value float64) {
+		vec.With(labels).Set(value)
+		set = append(set, series{vec: vec, labels: labels})
+	}
+	withLabels := func(base prometheus.Labels, name, value string) prometheus.Labels {
+		labels := prometheus.Labels{name: value}
+		for k, v := range base {
+			labels[k] = v
+		}
+		return labels
+	}
+
+	scans.Lock()
+	defer scans.Unlock()
+	for _, r := range reports {
+		counts := map[string]int{}
+		for _, severity := range scanSeverities {
+			counts[severity] = 0
+		}
+		for _, c := range r.ResolvableCVEs {
+			counts[strings.ToLower(c.Severity)]++
+		}
+		for _, container := range containers(namespace, r.ClusterImage) {
+			labels := prometheus.Labels{
+				"namespace": namespace,
+				"kind":      kind,
+				"component": r.Component,
+				"container": container,
+				"image":     r.ClusterImage.FullPath,
+			}
+			for severity, count := range counts {
+				gauge(ImageResolvableCVEs, withLabels(labels, "severity", severity), float64(count))
+			}
+			gauge(ImageUpToDate, withLabels(labels, "tag", "own_tag"), boolValue(r.UpToDateWithOwnTag))
+			// the same comparison the status and events use to report an image as outdated
+			gauge(ImageUpToDate, withLabels(labels, "tag", "latest_patch"), boolValue(r.CurrentVersion == r.LatestAvailablePatchVersion))
+			if r.FloatingTag != "" {
+				gauge(ImageUpToDate, withLabels(labels, "tag", "floating_tag"), boolValue(r.UpToDateWithFloatingTag))
+			}
+			if grade := strings.Index(grades, strings.ToUpper(r.CurrentGrade)); r.CurrentGrade != "" && grade >= 0 {
+				gauge(ImageFreshnessGrade, labels, float64(grade))
+			}
+		}
+	}
+	if complete {
+		gauge(LastScanTimestamp, prometheus.Labels{"namespace": namespace, "kind": kind, "component": name}, float64(at.Unix()))
+	}
+
+	key := scanKey(kind, namespace, name)
+	current := map[string]bool{}
+	for _, s := range set {
+		current[s.String()] = true
+	}
+	for _, s := range scans.series[key] {
+		if current[s.String()] {
+			continue
+		}
+		if complete {
+			s.vec.Delete(s.labels)
+		} else {
+			set = append(set, s)
+		}
+	}
+	scans.series[key] = set
+}
+
+// DeleteScan removes the scan result gauges of a workload that is no longer monitored
+func DeleteScan(kind, namespace, name string) {
+	scans.Lock()
+	defer scans.Unlock()
+	deleteScan(scanKey(kind, namespace, name))
+}
+
+func deleteScan(key string) {
+	for _, s := range scans.series[key] {
+		s.vec.Delete(s.labels)
+	}
+	delete(scans.series, key)
+}
+
+// containers returns the names of the containers in namespace using image, an image without pods is reported without
+// a container
+func containers(namespace string, image *domain.ClusterImage) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, p := range image.Pods {
+		if p.Namespace != "" && p.Namespace != namespace {
+			continue
+		}
+		for _, c := range p.Containers {
+			if !seen[c] {
+				seen[c] = true
+				names = append(names, c)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return []string{""}
+	}
+	return names
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
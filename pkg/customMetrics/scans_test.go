@@ -0,0 +1,128 @@
+package customMetrics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/customMetrics"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	dto "github.com/prometheus/client_model/go"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// gathered returns the value of each series of the metric name keyed by its labels
+func gathered(t *testing.T, name string) map[string]float64 {
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatal("did not expect an error gathering the metrics ", err)
+	}
+	values := map[string]float64{}
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			values[labelString(m)] = m.GetGauge().GetValue()
+		}
+	}
+	return values
+}
+
+func labelString(m *dto.Metric) string {
+	s := ""
+	for _, l := range m.GetLabel() {
+		s += l.GetName() + "=" + l.GetValue() + ","
+	}
+	return s
+}
+
+func image(fullPath string, containers ...string) *domain.ClusterImage {
+	return &domain.ClusterImage{
+		FullPath: fullPath,
+		Pods:     []domain.PodAndContainerRef{{Name: "pod-1", Namespace: "ns1", Containers: containers}},
+	}
+}
+
+func TestRecordScan(t *testing.T) {
+	at := time.Unix(1600000000, 0)
+	customMetrics.RecordScan("deployment", "ns1", "api", []domain.ReportResult{
+		{
+			Component: "api",
+			ResolvableCVEs: []domain.CVE{
+				{ID: "CVE-1", Severity: "Critical"},
+				{ID: "CVE-2", Severity: "critical"},
+				{ID: "CVE-3", Severity: "Moderate"},
+			},
+			FloatingTag:             "2",
+			UpToDateWithOwnTag:      false,
+			UpToDateWithFloatingTag: true,
+			CurrentGrade:            "C",
+			ClusterImage:            image("registry.redhat.io/ubi8/ubi:8.1", "server", "sidecar"),
+		},
+	}, at, true)
+
+	cves := gathered(t, "heimdall_image_resolvable_cves")
+	expected := map[string]float64{
+		"component=api,container=server,image=registry.redhat.io/ubi8/ubi:8.1,kind=deployment,namespace=ns1,severity=critical,":  2,
+		"component=api,container=server,image=registry.redhat.io/ubi8/ubi:8.1,kind=deployment,namespace=ns1,severity=important,": 0,
+		"component=api,container=sidecar,image=registry.redhat.io/ubi8/ubi:8.1,kind=deployment,namespace=ns1,severity=moderate,": 1,
+	}
+	for labels, value := range expected {
+		if v, ok := cves[labels]; !ok || v != value {
+			t.Fatal("expected ", value, " for ", labels, " but got ", cves)
+		}
+	}
+	if len(cves) != 8 {
+		t.Fatal("expected a series per severity for each container but got ", len(cves))
+	}
+	upToDate := gathered(t, "heimdall_image_up_to_date")
+	if upToDate["component=api,container=server,image=registry.redhat.io/ubi8/ubi:8.1,kind=deployment,namespace=ns1,tag=own_tag,"] != 0 ||
+		upToDate["component=api,container=server,image=registry.redhat.io/ubi8/ubi:8.1,kind=deployment,namespace=ns1,tag=floating_tag,"] != 1 {
+		t.Fatal("expected the image to be out of date with its own tag only but got ", upToDate)
+	}
+	if grade := gathered(t, "heimdall_image_freshness_grade"); grade["component=api,container=server,image=registry.redhat.io/ubi8/ubi:8.1,kind=deployment,namespace=ns1,"] != 2 {
+		t.Fatal("expected grade C to be 2 but got ", grade)
+	}
+	if scanned := gathered(t, "heimdall_last_scan_timestamp_seconds"); scanned["component=api,kind=deployment,namespace=ns1,"] != 1600000000 {
+		t.Fatal("expected the scan time but got ", scanned)
+	}
+
+	// the next scan replaces the series of images that are no longer used
+	customMetrics.RecordScan("deployment", "ns1", "api", []domain.ReportResult{
+		{Component: "api", UpToDateWithOwnTag: true, ClusterImage: image("registry.redhat.io/ubi8/ubi:8.2", "server")},
+	}, at, true)
+	cves = gathered(t, "heimdall_image_resolvable_cves")
+	if len(cves) != 4 {
+		t.Fatal("expected only the series of the new image but got ", cves)
+	}
+	if _, ok := gathered(t, "heimdall_image_freshness_grade")["component=api,container=server,image=registry.redhat.io/ubi8/ubi:8.1,kind=deployment,namespace=ns1,"]; ok {
+		t.Fatal("expected the grade of the old image to be removed")
+	}
+
+	// a scan that failed to check some images keeps the series of the previous scan and its time
+	customMetrics.RecordScan("deployment", "ns1", "api", []domain.ReportResult{
+		{Component: "api", UpToDateWithOwnTag: true, ClusterImage: image("registry.redhat.io/ubi8/ubi-minimal:8.2", "init")},
+	}, at.Add(time.Hour), false)
+	if cves = gathered(t, "heimdall_image_resolvable_cves"); len(cves) != 8 {
+		t.Fatal("expected the series of the previous scan to be kept but got ", cves)
+	}
+	if scanned := gathered(t, "heimdall_last_scan_timestamp_seconds"); scanned["component=api,kind=deployment,namespace=ns1,"] != 1600000000 {
+		t.Fatal("expected the time of the last complete scan to be kept but got ", scanned)
+	}
+
+	// a workload of another kind with the same name has its own series
+	customMetrics.RecordScan("cron job", "ns1", "api", []domain.ReportResult{
+		{Component: "api", ClusterImage: image("registry.redhat.io/ubi8/ubi:8.1", "job")},
+	}, at, true)
+	customMetrics.DeleteScan("cron job", "ns1", "api")
+	if scanned := gathered(t, "heimdall_last_scan_timestamp_seconds"); len(scanned) != 1 || scanned["component=api,kind=deployment,namespace=ns1,"] != 1600000000 {
+		t.Fatal("expected only the series of the cron job to be deleted but got ", scanned)
+	}
+
+	customMetrics.DeleteScan("deployment", "ns1", "api")
+	for _, name := range []string{"heimdall_image_resolvable_cves", "heimdall_image_up_to_date", "heimdall_last_scan_timestamp_seconds"} {
+		if series := gathered(t, name); len(series) != 0 {
+			t.Fatal("expected the series of ", name, " to be deleted but got ", series)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package domain
 import (
 	v1 "github.com/openshift/api/image/v1"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -21,6 +22,8 @@ type ClusterImage struct {
 	Pods            []PodAndContainerRef
 	FromImageStream bool
 	ImageStreamTag  *v1.ImageStreamTag
+	// the mirror repository the image was pulled from when it has been resolved to its upstream repository
+	MirrorPath string
 }
 
 func (ci *ClusterImage) GetSHAFromPath() string {
@@ -34,10 +37,84 @@ func (ci *ClusterImage) GetSHAFromPath() string {
 	return parts[1]
 }
 
+// RegistryHost returns the host of the registry the image is pulled from e.g registry.redhat.io
+func (ci *ClusterImage) RegistryHost() string {
+	return strings.Split(ci.RegistryPath, "/")[0]
+}
+
+// PulledRegistryPath returns the repository the image is pulled from, the mirror when it has been resolved to its
+// upstream repository. Digests are looked up there as the cluster may not be able to reach the upstream registry.
+func (ci *ClusterImage) PulledRegistryPath() string {
+	if ci.MirrorPath == "" {
+		return ci.RegistryPath
+	}
+	return ci.MirrorPath
+}
+
+// PulledFullPath returns the tagged reference of the image in the repository it is pulled from
+func (ci *ClusterImage) PulledFullPath() string {
+	return ci.PulledRegistryPath() + strings.TrimPrefix(ci.FullPath, ci.RegistryPath)
+}
+
+// ContainerType is the kind of container in a pod that uses an image
+type ContainerType string
+
+const (
+	ContainerTypeContainer ContainerType = "container"
+	ContainerTypeInit      ContainerType = "init"
+)
+
+// PodAndContainerRef is a pod and the containers of one type in it using an image. Name is empty when the image was
+// found in the pod template of a workload without running pods.
 type PodAndContainerRef struct {
-	Name       string
-	Namespace  string
-	Containers []string
+	Name          string
+	Namespace     string
+	Containers    []string
+	ContainerType ContainerType
+}
+
+// Type returns the type of the containers, references without one are to regular containers
+func (p PodAndContainerRef) Type() ContainerType {
+	if p.ContainerType == "" {
+		return ContainerTypeContainer
+	}
+	return p.ContainerType
+}
+
+// AddContainer records that the container of containerType in the pod uses the image
+func (ci *ClusterImage) AddContainer(pod, namespace string, containerType ContainerType, container string) {
+	for i, p := range ci.Pods {
+		if p.Name != pod || p.Namespace != namespace || p.Type() != containerType {
+			continue
+		}
+		for _, c := range p.Containers {
+			if c == container {
+				return
+			}
+		}
+		ci.Pods[i].Containers = append(ci.Pods[i].Containers, container)
+		return
+	}
+	ci.Pods = append(ci.Pods, PodAndContainerRef{Name: pod, Namespace: namespace, Containers: []string{container}, ContainerType: containerType})
+}
+
+// ContainerNames returns the names of the containers using the image of containerType in sorted order
+func (ci *ClusterImage) ContainerNames(containerType ContainerType) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, p := range ci.Pods {
+		if p.Type() != containerType {
+			continue
+		}
+		for _, c := range p.Containers {
+			if !seen[c] {
+				seen[c] = true
+				names = append(names, c)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
 }
 
 func (i *ClusterImage) IsSHATag() bool {
@@ -67,6 +144,22 @@ type CVE struct {
 	AdvisoryID string
 }
 
+// NormaliseSeverity maps the severity names used by the different CVE sources onto the severities used by the
+// Red Hat container catalog (critical, important, moderate and low)
+func NormaliseSeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "critical"
+	case "high", "important":
+		return "important"
+	case "medium", "moderate":
+		return "moderate"
+	case "low", "negligible":
+		return "low"
+	}
+	return "unknown"
+}
+
 type ReportResult struct {
 	Component                   string
 	ActualImageRef              string
@@ -119,6 +212,8 @@ const (
 	HeimdallMonitored      = "heimdall.monitored"
 	HeimdallLastChecked    = "heimdall.lastcheck"
 	HeimdallImagesChecked  = "heimdall.imageschecked"
+	HeimdallStatus         = "heimdall.status"
+	HeimdallNotified       = "heimdall.notified"
 	TimeFormat             = time.RFC822Z
 	MinRecheckIntervalMins = 24 * 60
 )
@@ -0,0 +1,209 @@
+package export
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const cycloneDXSpecVersion = "1.4"
+
+// CycloneDXBOM is a CycloneDX 1.4 document listing the cluster images as components along with the vulnerabilities
+// that updating them would resolve, which makes it usable as a VEX document
+type CycloneDXBOM struct {
+	BOMFormat       string                   `json:"bomFormat"`
+	SpecVersion     string                   `json:"specVersion"`
+	SerialNumber    string                   `json:"serialNumber"`
+	Version         int                      `json:"version"`
+	Metadata        CycloneDXMetadata        `json:"metadata"`
+	Components      []CycloneDXComponent     `json:"components"`
+	Vulnerabilities []CycloneDXVulnerability `json:"vulnerabilities"`
+}
+
+type CycloneDXMetadata struct {
+	Timestamp string          `json:"timestamp"`
+	Tools     []CycloneDXTool `json:"tools"`
+}
+
+type CycloneDXTool struct {
+	Name string `json:"name"`
+}
+
+// CycloneDXComponent is an image used in the cluster. The workloads using it are recorded as heimdall:workload
+// properties.
+type CycloneDXComponent struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	Properties []CycloneDXProperty `json:"properties,omitempty"`
+}
+
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CycloneDXVulnerability is a CVE in an image with the tag fixing it as the recommendation
+type CycloneDXVulnerability struct {
+	BOMRef         string              `json:"bom-ref"`
+	ID             string              `json:"id"`
+	Ratings        []CycloneDXRating   `json:"ratings"`
+	Advisories     []CycloneDXAdvisory `json:"advisories,omitempty"`
+	Recommendation string              `json:"recommendation"`
+	Analysis       CycloneDXAnalysis   `json:"analysis"`
+	Affects        []CycloneDXAffects  `json:"affects"`
+}
+
+type CycloneDXRating struct {
+	Severity string `json:"severity"`
+	Method   string `json:"method"`
+}
+
+type CycloneDXAdvisory struct {
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url"`
+}
+
+type CycloneDXAnalysis struct {
+	State    string   `json:"state"`
+	Response []string `json:"response"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+type CycloneDXAffects struct {
+	Ref string `json:"ref"`
+}
+
+// cycloneDXSeverity maps the severity of a CVE onto a CycloneDX severity
+func cycloneDXSeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "critical"
+	case "important":
+		return "high"
+	case "moderate":
+		return "medium"
+	case "low":
+		return "low"
+	}
+	return "unknown"
+}
+
+// imagePURL returns the package url of an image pinned to its digest, or an empty string for an image without one
+func imagePURL(imageRef, registryPath, tag string) string {
+	parts := strings.SplitN(imageRef, "@", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	name := registryPath[strings.LastIndex(registryPath, "/")+1:]
+	query := url.Values{}
+	query.Set("repository_url", registryPath)
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	return "pkg:oci/" + strings.ToLower(name) + "@" + url.QueryEscape(parts[1]) + "?" + query.Encode()
+}
+
+// CycloneDX creates a CycloneDX document generated at the given time with a component for each image in reports and a
+// vulnerability for each resolvable CVE of an image
+func CycloneDX(reports []Report, generated time.Time) *CycloneDXBOM {
+	bom := &CycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXSpecVersion,
+		SerialNumber: serialNumber(),
+		Version:      1,
+		Metadata: CycloneDXMetadata{
+			Timestamp: generated.UTC().Format(time.RFC3339),
+			Tools:     []CycloneDXTool{{Name: toolName}},
+		},
+		Components:      []CycloneDXComponent{},
+		Vulnerabilities: []CycloneDXVulnerability{},
+	}
+	components := map[string]int{}
+	vulnerabilities := map[string]bool{}
+	for _, r := range reports {
+		image := r.ClusterImage
+		ref := image.SHA256Path
+		if ref == "" {
+			ref = image.FullPath
+		}
+		i, ok := components[ref]
+		if !ok {
+			version := r.CurrentVersion
+			if version == "" {
+				version = image.Tag
+			}
+			i = len(bom.Components)
+			components[ref] = i
+			bom.Components = append(bom.Components, CycloneDXComponent{
+				Type:    "container",
+				BOMRef:  ref,
+				Name:    image.RegistryPath,
+				Version: version,
+				PURL:    imagePURL(image.SHA256Path, image.RegistryPath, version),
+			})
+			if r.LatestAvailablePatchVersion != "" {
+				bom.Components[i].Properties = append(bom.Components[i].Properties,
+					CycloneDXProperty{Name: "heimdall:latestPatchTag", Value: r.LatestAvailablePatchVersion})
+			}
+		}
+		bom.Components[i].Properties = append(bom.Components[i].Properties,
+			CycloneDXProperty{Name: "heimdall:workload", Value: r.Namespace + "/" + r.Component})
+
+		for _, c := range r.ResolvableCVEs {
+			id := cveID(c)
+			vulnRef := id + "/" + ref
+			// an image used by several workloads has the same vulnerabilities
+			if vulnerabilities[vulnRef] {
+				continue
+			}
+			vulnerabilities[vulnRef] = true
+			v := CycloneDXVulnerability{
+				BOMRef:         vulnRef,
+				ID:             id,
+				Ratings:        []CycloneDXRating{{Severity: cycloneDXSeverity(c.Severity), Method: "other"}},
+				Recommendation: recommendation(r),
+				Analysis: CycloneDXAnalysis{
+					State:    "in_triage",
+					Response: []string{"update"},
+					Detail:   "A newer patch build of the image resolves the vulnerability, its exploitability has not been analysed",
+				},
+				Affects: []CycloneDXAffects{{Ref: ref}},
+			}
+			// CVEs without a linkable advisory have none as an advisory requires a url
+			switch u := advisoryURL(c.AdvisoryID); {
+			case u == "":
+			case u == c.AdvisoryID:
+				v.Advisories = []CycloneDXAdvisory{{URL: u}}
+			default:
+				v.Advisories = []CycloneDXAdvisory{{Title: c.AdvisoryID, URL: u}}
+			}
+			bom.Vulnerabilities = append(bom.Vulnerabilities, v)
+		}
+	}
+	return bom
+}
+
+// serialNumber returns a random urn:uuid serial number identifying a generated document
+func serialNumber() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// WriteCycloneDX writes the reports to w as a CycloneDX document
+func WriteCycloneDX(w io.Writer, reports []Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(CycloneDX(reports, time.Now()))
+}
@@ -0,0 +1,74 @@
+package export_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/export"
+)
+
+func TestCycloneDX(t *testing.T) {
+	shared := report("ns1", "Deployment/api", "registry.redhat.io/ubi8/ubi:8.1",
+		domain.CVE{ID: "CVE-2020-1", Severity: "Important", AdvisoryID: "RHSA-2020:1234"})
+	other := shared
+	other.Component = "StatefulSet/db"
+	reports := []export.Report{
+		shared,
+		other,
+		report("ns1", "Deployment/web", "registry.redhat.io/rhscl/nginx-114-rhel7:1",
+			domain.CVE{ID: "CVE-2020-2", Severity: "unknown", AdvisoryID: "https://example.com/advisory"},
+			domain.CVE{ID: "CVE-2020-3", Severity: "Low"}),
+	}
+	generated := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	bom := export.CycloneDX(reports, generated)
+	if bom.BOMFormat != "CycloneDX" || bom.SpecVersion != "1.4" {
+		t.Fatal("expected a CycloneDX 1.4 document but got ", bom.BOMFormat, bom.SpecVersion)
+	}
+	if !strings.HasPrefix(bom.SerialNumber, "urn:uuid:") {
+		t.Fatal("expected a urn:uuid serial number but got ", bom.SerialNumber)
+	}
+	if bom.Metadata.Timestamp != "2020-01-02T03:04:05Z" {
+		t.Fatal("expected the generated time as timestamp but got ", bom.Metadata.Timestamp)
+	}
+	if len(bom.Components) != 2 {
+		t.Fatal("expected a component for each image but got ", len(bom.Components))
+	}
+	workloads := 0
+	for _, p := range bom.Components[0].Properties {
+		if p.Name == "heimdall:workload" {
+			workloads++
+		}
+	}
+	if workloads != 2 {
+		t.Fatal("expected both workloads using the image to be recorded but got ", workloads)
+	}
+	if !strings.HasPrefix(bom.Components[0].PURL, "pkg:oci/ubi@sha256%3A") {
+		t.Fatal("expected an oci package url pinned to the digest but got ", bom.Components[0].PURL)
+	}
+	if len(bom.Vulnerabilities) != 3 {
+		t.Fatal("expected a vulnerability for each CVE of each image but got ", len(bom.Vulnerabilities))
+	}
+	v := bom.Vulnerabilities[0]
+	if v.Ratings[0].Severity != "high" || v.Affects[0].Ref != bom.Components[0].BOMRef {
+		t.Fatal("expected a high vulnerability affecting the first image but got ", v.Ratings[0].Severity, v.Affects[0].Ref)
+	}
+	if v.Recommendation != "Update to tag 8.1-2" || v.Analysis.Response[0] != "update" {
+		t.Fatal("expected an update to the patch tag to be recommended but got ", v.Recommendation)
+	}
+	if v.Advisories[0].URL != "https://access.redhat.com/errata/RHSA-2020:1234" {
+		t.Fatal("expected the advisory link but got ", v.Advisories[0].URL)
+	}
+	if bom.Vulnerabilities[1].Ratings[0].Severity != "unknown" || bom.Vulnerabilities[1].Advisories[0].Title != "" {
+		t.Fatal("expected an unknown severity and an untitled advisory link but got ", bom.Vulnerabilities[1])
+	}
+	data, err := json.Marshal(bom.Vulnerabilities[2])
+	if err != nil {
+		t.Fatal("did not expect an error encoding the vulnerability ", err)
+	}
+	if strings.Contains(string(data), "advisories") {
+		t.Fatal("expected no advisories for a CVE without an advisory but got ", string(data))
+	}
+}
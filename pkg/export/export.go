@@ -0,0 +1,45 @@
+// Package export writes the results of image checks in the formats security tooling ingests, SARIF and CycloneDX,
+// as html and markdown reports for people, and serves the latest results of the operator's checks over http.
+package export
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+)
+
+const (
+	toolName = "heimdall"
+	toolURI  = "https://github.com/integr8ly/heimdall"
+)
+
+var redHatAdvisory = regexp.MustCompile(`^RH[SBE]A-\d{4}:\d+$`)
+
+// advisoryURL returns a link to the advisory of a CVE. The rhcc api gives Red Hat advisory IDs, Clair gives links and
+// OSV feeds give their own IDs that do not have a well known link.
+func advisoryURL(advisoryID string) string {
+	if strings.HasPrefix(advisoryID, "https://") || strings.HasPrefix(advisoryID, "http://") {
+		return advisoryID
+	}
+	if redHatAdvisory.MatchString(advisoryID) {
+		return "https://access.redhat.com/errata/" + advisoryID
+	}
+	return ""
+}
+
+// cveID returns the ID a CVE is reported under, the advisory ID is used for vulnerabilities without a CVE ID
+func cveID(c domain.CVE) string {
+	if c.ID != "" {
+		return c.ID
+	}
+	return c.AdvisoryID
+}
+
+// recommendation describes how to fix the resolvable CVEs of r
+func recommendation(r Report) string {
+	if r.LatestAvailablePatchVersion == "" {
+		return "Update to the latest patch build of the image"
+	}
+	return "Update to tag " + r.LatestAvailablePatchVersion
+}
@@ -0,0 +1,65 @@
+package export
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// htmlReport is a self contained page, the styles are inline so it can be attached to an email or stored as an
+// artifact. Each component is a details element that expands to the CVEs of its images.
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Heimdall report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 0.5em 0 1em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+summary { cursor: pointer; padding: 0.2em 0; }
+.critical { color: #a30000; font-weight: bold; }
+.important { color: #c9190b; }
+.moderate { color: #b36b00; }
+.low, .other { color: #555; }
+.none { color: #3e8635; }
+</style>
+</head>
+<body>
+<h1>Heimdall report</h1>
+<p>Generated {{ .Generated }} for {{ .Images }} images in {{ len .Namespaces }} namespaces.</p>
+<table>
+<tr>{{ range .Totals }}<th class="{{ .Severity }}">{{ .Severity }}</th>{{ end }}</tr>
+<tr>{{ range .Totals }}<td>{{ .Count }}</td>{{ end }}</tr>
+</table>
+{{- range .Namespaces }}
+<h2>{{ .Name }} <small>({{ .CVEs }} resolvable CVEs)</small></h2>
+{{- range .Components }}
+<details>
+<summary>{{ .Name }} {{ if .CVEs }}<span class="important">{{ .CVEs }} resolvable CVEs</span>{{ else }}<span class="none">no resolvable CVEs</span>{{ end }}</summary>
+{{- range .Images }}
+<h4>{{ .Image }}</h4>
+<p>Image ref <code>{{ .Ref }}</code>, tag {{ .CurrentTag }}{{ if .FixedTag }}, fixed in {{ .FixedTag }}{{ end }}</p>
+{{- if .CVEs }}
+<table>
+<tr><th>CVE</th><th>Severity</th><th>Advisory</th></tr>
+{{- range .CVEs }}
+<tr><td>{{ .ID }}</td><td class="{{ .Severity }}">{{ .Severity }}</td><td>{{ if .URL }}<a href="{{ .URL }}">{{ .AdvisoryID }}</a>{{ else }}{{ .AdvisoryID }}{{ end }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- end }}
+</details>
+{{- end }}
+{{- end }}
+</body>
+</html>
+`))
+
+// WriteHTML writes the reports to w as a self contained html page grouped by namespace and component
+func WriteHTML(w io.Writer, reports []Report) error {
+	return htmlReport.Execute(w, struct {
+		summary
+		Generated string
+	}{summarise(reports), time.Now().UTC().Format(time.RFC1123)})
+}
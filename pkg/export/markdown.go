@@ -0,0 +1,48 @@
+package export
+
+import (
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// markdownReport renders the CVEs of each component in a collapsed details block, which GitHub and GitLab render
+// as a drill down
+var markdownReport = template.Must(template.New("report").Funcs(template.FuncMap{"cell": markdownCell}).Parse(
+	`# Heimdall report
+
+Generated {{ .Generated }} for {{ .Images }} images in {{ len .Namespaces }} namespaces.
+
+|{{ range .Totals }} {{ .Severity }} |{{ end }}
+|{{ range .Totals }} --- |{{ end }}
+|{{ range .Totals }} {{ .Count }} |{{ end }}
+{{ range .Namespaces }}
+## {{ .Name }} ({{ .CVEs }} resolvable CVEs)
+{{ range .Components }}
+<details>
+<summary>{{ .Name }}: {{ .CVEs }} resolvable CVEs</summary>
+{{ range .Images }}
+#### {{ cell .Image }}
+
+Image ref ` + "`{{ .Ref }}`" + `, tag {{ cell .CurrentTag }}{{ if .FixedTag }}, fixed in {{ cell .FixedTag }}{{ end }}
+{{ if .CVEs }}
+| CVE | Severity | Advisory |
+| --- | --- | --- |
+{{ range .CVEs }}| {{ cell .ID }} | {{ .Severity }} | {{ if .URL }}[{{ cell .AdvisoryID }}]({{ .URL }}){{ else }}{{ cell .AdvisoryID }}{{ end }} |
+{{ end }}{{ end }}{{ end }}
+</details>
+{{ end }}{{ end }}`))
+
+// markdownCell escapes the characters that would break a table cell or be read as formatting
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "*", `\*`, "_", `\_`, "<", "&lt;", ">", "&gt;", "\n", " ").Replace(s)
+}
+
+// WriteMarkdown writes the reports to w as a markdown document grouped by namespace and component
+func WriteMarkdown(w io.Writer, reports []Report) error {
+	return markdownReport.Execute(w, struct {
+		summary
+		Generated string
+	}{summarise(reports), time.Now().UTC().Format(time.RFC1123)})
+}
@@ -0,0 +1,95 @@
+package export_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/export"
+)
+
+func TestReports(t *testing.T) {
+	reports := []export.Report{
+		report("ns2", "Deployment/web", "registry.redhat.io/ubi8/ubi:8.1",
+			domain.CVE{ID: "CVE-2020-3", Severity: "Low", AdvisoryID: "GHSA-xxxx"}),
+		report("ns1", "Deployment/api", "registry.redhat.io/amq7/amq-online-1-api-server:2.0.0",
+			domain.CVE{ID: "CVE-2020-1", Severity: "Critical", AdvisoryID: "RHSA-2020:1234"},
+			domain.CVE{ID: "CVE-2020-2", Severity: "Important", AdvisoryID: "https://example.com/advisory?id=<2>"}),
+		report("ns1", "StatefulSet/db", "registry.redhat.io/rhscl/postgresql-10-rhel7:1"),
+	}
+	cases := []struct {
+		Name          string
+		Write         func(*bytes.Buffer, []export.Report) error
+		ExpectContain []string
+		ExpectOrder   []string
+	}{
+		{
+			Name: "test html report links advisories and totals severities",
+			Write: func(b *bytes.Buffer, r []export.Report) error {
+				return export.WriteHTML(b, r)
+			},
+			ExpectContain: []string{
+				`<th class="critical">critical</th><th class="important">important</th><th class="moderate">moderate</th><th class="low">low</th>`,
+				`<td>1</td><td>1</td><td>0</td><td>1</td>`,
+				`<a href="https://access.redhat.com/errata/RHSA-2020:1234">RHSA-2020:1234</a>`,
+				`<a href="https://example.com/advisory?id=%3c2%3e">https://example.com/advisory?id=&lt;2&gt;</a>`,
+				`<td>GHSA-xxxx</td>`,
+				`no resolvable CVEs`,
+				`fixed in 2.0.0-2`,
+			},
+			ExpectOrder: []string{"<h2>ns1", "Deployment/api", "StatefulSet/db", "<h2>ns2", "Deployment/web"},
+		},
+		{
+			Name: "test markdown report links advisories and totals severities",
+			Write: func(b *bytes.Buffer, r []export.Report) error {
+				return export.WriteMarkdown(b, r)
+			},
+			ExpectContain: []string{
+				"| critical | important | moderate | low |",
+				"| 1 | 1 | 0 | 1 |",
+				"| CVE-2020-1 | critical | [RHSA-2020:1234](https://access.redhat.com/errata/RHSA-2020:1234) |",
+				"| CVE-2020-3 | low | GHSA-xxxx |",
+				"<summary>StatefulSet/db: 0 resolvable CVEs</summary>",
+			},
+			ExpectOrder: []string{"## ns1", "Deployment/api", "StatefulSet/db", "## ns2", "Deployment/web"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			if err := tc.Write(b, reports); err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			out := b.String()
+			for _, s := range tc.ExpectContain {
+				if !strings.Contains(out, s) {
+					t.Fatal("expected the report to contain ", s, " but got\n", out)
+				}
+			}
+			last := -1
+			for _, s := range tc.ExpectOrder {
+				i := strings.Index(out, s)
+				if i < last {
+					t.Fatal("expected ", s, " to be grouped in order ", tc.ExpectOrder)
+				}
+				last = i
+			}
+		})
+	}
+}
+
+func TestReports_CountsSharedImagesOnce(t *testing.T) {
+	api := report("ns1", "Deployment/api", "registry.redhat.io/ubi8/ubi:8.1")
+	worker := report("ns1", "Deployment/worker", "registry.redhat.io/ubi8/ubi:8.1")
+	worker.ClusterImage.SHA256Path = api.ClusterImage.SHA256Path
+	reports := []export.Report{api, worker, report("ns1", "StatefulSet/db", "registry.redhat.io/rhscl/postgresql-10-rhel7:1")}
+
+	b := &bytes.Buffer{}
+	if err := export.WriteMarkdown(b, reports); err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	if !strings.Contains(b.String(), "for 2 images in 1 namespaces") {
+		t.Fatal("expected the image shared by two components to be counted once but got\n", b.String())
+	}
+}
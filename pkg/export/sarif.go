@@ -0,0 +1,147 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// SARIFLog is a SARIF 2.1.0 log with a single run of heimdall
+type SARIFLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+// SARIFRule describes a CVE, each CVE found is a rule so tools can group the results by it
+type SARIFRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     SARIFMessage       `json:"shortDescription"`
+	HelpURI              string             `json:"helpUri,omitempty"`
+	DefaultConfiguration SARIFConfiguration `json:"defaultConfiguration"`
+}
+
+type SARIFConfiguration struct {
+	Level string `json:"level"`
+}
+
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFResult is a resolvable CVE in an image used by a component
+type SARIFResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             SARIFMessage      `json:"message"`
+	Locations           []SARIFLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]string `json:"properties"`
+}
+
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []SARIFLogicalLocation `json:"logicalLocations"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+}
+
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type SARIFLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifLevel maps the severity of a CVE onto a SARIF level
+func sarifLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "important":
+		return "error"
+	case "moderate":
+		return "warning"
+	}
+	return "note"
+}
+
+// SARIF creates a SARIF log with a result for each resolvable CVE of each report
+func SARIF(reports []Report) *SARIFLog {
+	driver := SARIFDriver{Name: toolName, InformationURI: toolURI, Rules: []SARIFRule{}}
+	results := []SARIFResult{}
+	rules := map[string]bool{}
+	for _, r := range reports {
+		workload := r.Namespace + "/" + r.Component
+		for _, c := range r.ResolvableCVEs {
+			id := cveID(c)
+			level := sarifLevel(c.Severity)
+			if !rules[id] {
+				rules[id] = true
+				driver.Rules = append(driver.Rules, SARIFRule{
+					ID:                   id,
+					ShortDescription:     SARIFMessage{Text: id},
+					HelpURI:              advisoryURL(c.AdvisoryID),
+					DefaultConfiguration: SARIFConfiguration{Level: level},
+				})
+			}
+			results = append(results, SARIFResult{
+				RuleID: id,
+				Level:  level,
+				Message: SARIFMessage{Text: fmt.Sprintf("%s (%s) in image %s used by %s. %s.",
+					id, c.Severity, r.ClusterImage.FullPath, workload, recommendation(r))},
+				Locations: []SARIFLocation{{
+					PhysicalLocation: SARIFPhysicalLocation{ArtifactLocation: SARIFArtifactLocation{URI: r.ClusterImage.FullPath}},
+					LogicalLocations: []SARIFLogicalLocation{{Name: r.Component, FullyQualifiedName: workload, Kind: "module"}},
+				}},
+				PartialFingerprints: map[string]string{"heimdall/v1": workload + "/" + r.ClusterImage.FullPath + "/" + id},
+				Properties: map[string]string{
+					"namespace":  r.Namespace,
+					"component":  r.Component,
+					"image":      r.ClusterImage.FullPath,
+					"imageRef":   r.ClusterImage.SHA256Path,
+					"severity":   c.Severity,
+					"advisoryID": c.AdvisoryID,
+					"currentTag": r.CurrentVersion,
+					"fixedTag":   r.LatestAvailablePatchVersion,
+				},
+			})
+		}
+	}
+	return &SARIFLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []SARIFRun{{Tool: SARIFTool{Driver: driver}, Results: results}},
+	}
+}
+
+// WriteSARIF writes the reports to w as a SARIF log
+func WriteSARIF(w io.Writer, reports []Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(SARIF(reports))
+}
@@ -0,0 +1,81 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/export"
+)
+
+func report(ns, component, image string, cves ...domain.CVE) export.Report {
+	img := cluster.MustParseImage(image)
+	img.SHA256Path = img.RegistryPath + "@sha256:" + component
+	return export.Report{
+		Namespace: ns,
+		ReportResult: domain.ReportResult{
+			Component:                   component,
+			ResolvableCVEs:              cves,
+			CurrentVersion:              img.Tag,
+			LatestAvailablePatchVersion: img.Tag + "-2",
+			ClusterImage:                img,
+		},
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	reports := []export.Report{
+		report("ns1", "Deployment/api", "registry.redhat.io/amq7/amq-online-1-api-server:2.0.0",
+			domain.CVE{ID: "CVE-2020-1", Severity: "Critical", AdvisoryID: "RHSA-2020:1234"},
+			domain.CVE{ID: "CVE-2020-2", Severity: "moderate", AdvisoryID: "https://example.com/advisory"}),
+		report("ns2", "Deployment/web", "registry.redhat.io/ubi8/ubi:8.1",
+			domain.CVE{ID: "CVE-2020-1", Severity: "Critical", AdvisoryID: "RHSA-2020:1234"},
+			domain.CVE{Severity: "low", AdvisoryID: "GHSA-xxxx"}),
+	}
+	log := export.SARIF(reports)
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatal("expected a single 2.1.0 run but got ", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 3 {
+		t.Fatal("expected a rule for each distinct CVE but got ", len(run.Tool.Driver.Rules))
+	}
+	if run.Tool.Driver.Rules[0].HelpURI != "https://access.redhat.com/errata/RHSA-2020:1234" {
+		t.Fatal("expected the advisory link as help uri but got ", run.Tool.Driver.Rules[0].HelpURI)
+	}
+	if len(run.Results) != 4 {
+		t.Fatal("expected a result for each CVE of each report but got ", len(run.Results))
+	}
+	levels := map[string]string{}
+	for _, r := range run.Results {
+		levels[r.RuleID] = r.Level
+	}
+	expected := map[string]string{"CVE-2020-1": "error", "CVE-2020-2": "warning", "GHSA-xxxx": "note"}
+	for id, level := range expected {
+		if levels[id] != level {
+			t.Fatal("expected level ", level, " for ", id, " but got ", levels[id])
+		}
+	}
+	last := run.Results[3]
+	if last.Properties["namespace"] != "ns2" || last.Properties["fixedTag"] != "8.1-2" {
+		t.Fatal("expected the namespace and fixed tag properties but got ", last.Properties)
+	}
+	if last.Locations[0].LogicalLocations[0].FullyQualifiedName != "ns2/Deployment/web" {
+		t.Fatal("expected the workload as logical location but got ", last.Locations[0].LogicalLocations[0].FullyQualifiedName)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := export.WriteSARIF(buf, nil); err != nil {
+		t.Fatal("did not expect an error writing the log ", err)
+	}
+	decoded := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal("expected the log to be valid json ", err)
+	}
+	results := decoded["runs"].([]interface{})[0].(map[string]interface{})["results"]
+	if results == nil {
+		t.Fatal("expected an empty results array for no reports")
+	}
+}
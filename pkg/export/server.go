@@ -0,0 +1,58 @@
+package export
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("export")
+
+// EnvReportsAddress is the address the operator serves the reports on. The reports are not served when it is unset as
+// anyone able to reach the address can read them.
+const EnvReportsAddress = "HEIMDALL_REPORTS_ADDRESS"
+
+// Handler serves the reports in s as a SARIF log at /reports/sarif and as a CycloneDX document at /reports/cyclonedx.
+// The namespace query parameter limits the reports to a single namespace.
+func Handler(s *Store) http.Handler {
+	mux := http.NewServeMux()
+	serve := func(write func(io.Writer, []Report) error) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := write(w, s.Reports(req.URL.Query().Get("namespace"))); err != nil {
+				log.Error(err, "failed to write the reports", "path", req.URL.Path)
+			}
+		}
+	}
+	mux.HandleFunc("/reports/sarif", serve(WriteSARIF))
+	mux.HandleFunc("/reports/cyclonedx", serve(WriteCycloneDX))
+	return mux
+}
+
+// AddToManager serves the reports the controllers record in Results on addr while the manager runs
+func AddToManager(mgr manager.Manager, addr string) error {
+	return mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		server := &http.Server{Addr: addr, Handler: Handler(Results)}
+		errs := make(chan error, 1)
+		go func() {
+			log.Info("serving reports", "address", addr)
+			errs <- server.ListenAndServe()
+		}()
+		select {
+		case <-stop:
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return server.Shutdown(ctx)
+		case err := <-errs:
+			return err
+		}
+	}))
+}
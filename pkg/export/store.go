@@ -0,0 +1,94 @@
+package export
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+)
+
+// Report is the result of checking an image used by a component in a namespace
+type Report struct {
+	Namespace string
+	domain.ReportResult
+}
+
+// Store keeps the reports of the latest successful check of each workload so they can be exported
+type Store struct {
+	mu      sync.RWMutex
+	reports map[string][]Report
+}
+
+func NewStore() *Store {
+	return &Store{reports: map[string][]Report{}}
+}
+
+// Results is the store the controllers record the reports of their checks in
+var Results = NewStore()
+
+func storeKey(kind, namespace, name string) string {
+	return namespace + "/" + kind + "/" + name
+}
+
+// Set replaces the reports of the workload of kind with name in namespace
+func (s *Store) Set(kind, namespace, name string, reports []domain.ReportResult) {
+	stored := make([]Report, 0, len(reports))
+	for _, r := range reports {
+		stored = append(stored, Report{Namespace: namespace, ReportResult: r})
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports[storeKey(kind, namespace, name)] = stored
+}
+
+// Merge updates the reports of the workload of kind with name in namespace from a check that failed to check some of its
+// images. The previous reports of the images that were not checked are kept.
+func (s *Store) Merge(kind, namespace, name string, reports []domain.ReportResult) {
+	checked := map[string]bool{}
+	merged := make([]Report, 0, len(reports))
+	for _, r := range reports {
+		checked[r.Component+"/"+r.ClusterImage.FullPath] = true
+		merged = append(merged, Report{Namespace: namespace, ReportResult: r})
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := storeKey(kind, namespace, name)
+	for _, r := range s.reports[key] {
+		if !checked[r.Component+"/"+r.ClusterImage.FullPath] {
+			merged = append(merged, r)
+		}
+	}
+	s.reports[key] = merged
+}
+
+// Delete removes the reports of a workload that is no longer monitored
+func (s *Store) Delete(kind, namespace, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.reports, storeKey(kind, namespace, name))
+}
+
+// Reports returns the stored reports for namespace, or for every namespace when it is empty, ordered by namespace and
+// component
+func (s *Store) Reports(namespace string) []Report {
+	s.mu.RLock()
+	var reports []Report
+	for _, stored := range s.reports {
+		for _, r := range stored {
+			if namespace == "" || r.Namespace == namespace {
+				reports = append(reports, r)
+			}
+		}
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(reports, func(i, j int) bool {
+		if reports[i].Namespace != reports[j].Namespace {
+			return reports[i].Namespace < reports[j].Namespace
+		}
+		if reports[i].Component != reports[j].Component {
+			return reports[i].Component < reports[j].Component
+		}
+		return reports[i].ClusterImage.FullPath < reports[j].ClusterImage.FullPath
+	})
+	return reports
+}
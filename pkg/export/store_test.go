@@ -0,0 +1,84 @@
+package export_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/export"
+)
+
+func TestStore(t *testing.T) {
+	s := export.NewStore()
+	s.Set("deployment", "ns2", "web", []domain.ReportResult{report("", "Deployment/web", "registry.redhat.io/ubi8/ubi:8.1").ReportResult})
+	s.Set("deployment", "ns1", "api", []domain.ReportResult{report("", "Deployment/api", "registry.redhat.io/ubi8/ubi:8.1").ReportResult})
+	s.Set("statefulset", "ns1", "db", []domain.ReportResult{report("", "StatefulSet/db", "registry.redhat.io/ubi8/ubi:8.1").ReportResult})
+
+	all := s.Reports("")
+	if len(all) != 3 || all[0].Component != "Deployment/api" || all[2].Namespace != "ns2" {
+		t.Fatal("expected the reports of every namespace ordered by namespace and component but got ", all)
+	}
+	if ns1 := s.Reports("ns1"); len(ns1) != 2 {
+		t.Fatal("expected the reports of ns1 but got ", len(ns1))
+	}
+	s.Merge("deployment", "ns1", "api", []domain.ReportResult{report("", "Deployment/api", "registry.redhat.io/ubi8/ubi-minimal:8.1").ReportResult})
+	if ns1 := s.Reports("ns1"); len(ns1) != 3 {
+		t.Fatal("expected the reports of the images that were not checked to be kept but got ", len(ns1))
+	}
+	s.Set("deployment", "ns1", "api", nil)
+	s.Delete("statefulset", "ns1", "db")
+	if ns1 := s.Reports("ns1"); len(ns1) != 0 {
+		t.Fatal("expected the replaced and deleted reports to be gone but got ", len(ns1))
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s := export.NewStore()
+	s.Set("deployment", "ns1", "api", []domain.ReportResult{report("", "Deployment/api", "registry.redhat.io/ubi8/ubi:8.1",
+		domain.CVE{ID: "CVE-2020-1", Severity: "Critical"}).ReportResult})
+	server := httptest.NewServer(export.Handler(s))
+	defer server.Close()
+
+	cases := []struct {
+		Name         string
+		Path         string
+		ExpectStatus int
+		ExpectCVEs   int
+	}{
+		{Name: "test sarif is served", Path: "/reports/sarif", ExpectStatus: http.StatusOK, ExpectCVEs: 1},
+		{Name: "test cyclonedx is served", Path: "/reports/cyclonedx", ExpectStatus: http.StatusOK, ExpectCVEs: 1},
+		{Name: "test reports are filtered by namespace", Path: "/reports/sarif?namespace=ns2", ExpectStatus: http.StatusOK},
+		{Name: "test unknown format is not found", Path: "/reports/html", ExpectStatus: http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + tc.Path)
+			if err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.ExpectStatus {
+				t.Fatal("expected status ", tc.ExpectStatus, " but got ", resp.StatusCode)
+			}
+			if tc.ExpectStatus != http.StatusOK {
+				return
+			}
+			doc := struct {
+				Runs            []export.SARIFRun               `json:"runs"`
+				Vulnerabilities []export.CycloneDXVulnerability `json:"vulnerabilities"`
+			}{}
+			if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+				t.Fatal("expected a json document ", err)
+			}
+			cves := len(doc.Vulnerabilities)
+			if len(doc.Runs) > 0 {
+				cves = len(doc.Runs[0].Results)
+			}
+			if cves != tc.ExpectCVEs {
+				t.Fatal("expected ", tc.ExpectCVEs, " CVEs but got ", cves)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package export
+
+import (
+	"sort"
+	"strings"
+)
+
+// severities are the severities totalled in the summary of a report, in the order they are shown
+var severities = []string{"critical", "important", "moderate", "low"}
+
+// summary groups reports by namespace and component for the html and markdown reports
+type summary struct {
+	Totals     []severityTotal
+	Images     int
+	Namespaces []namespaceSummary
+}
+
+type severityTotal struct {
+	Severity string
+	Count    int
+}
+
+type namespaceSummary struct {
+	Name       string
+	CVEs       int
+	Components []componentSummary
+}
+
+type componentSummary struct {
+	Name   string
+	CVEs   int
+	Images []imageSummary
+}
+
+type imageSummary struct {
+	Image      string
+	Ref        string
+	CurrentTag string
+	FixedTag   string
+	CVEs       []cveSummary
+}
+
+type cveSummary struct {
+	ID         string
+	Severity   string
+	AdvisoryID string
+	URL        string
+}
+
+// summarise groups reports by namespace and component and totals their CVEs by severity
+func summarise(reports []Report) summary {
+	reports = append([]Report{}, reports...)
+	sort.SliceStable(reports, func(i, j int) bool {
+		if reports[i].Namespace != reports[j].Namespace {
+			return reports[i].Namespace < reports[j].Namespace
+		}
+		return reports[i].Component < reports[j].Component
+	})
+	s := summary{}
+	counts := map[string]int{}
+	// an image used by several components is counted once
+	images := map[string]bool{}
+	for _, r := range reports {
+		if len(s.Namespaces) == 0 || s.Namespaces[len(s.Namespaces)-1].Name != r.Namespace {
+			s.Namespaces = append(s.Namespaces, namespaceSummary{Name: r.Namespace})
+		}
+		ns := &s.Namespaces[len(s.Namespaces)-1]
+		if len(ns.Components) == 0 || ns.Components[len(ns.Components)-1].Name != r.Component {
+			ns.Components = append(ns.Components, componentSummary{Name: r.Component})
+		}
+		component := &ns.Components[len(ns.Components)-1]
+
+		image := imageSummary{CurrentTag: r.CurrentVersion, FixedTag: r.LatestAvailablePatchVersion}
+		if r.ClusterImage != nil {
+			image.Image = r.ClusterImage.FullPath
+			image.Ref = r.ClusterImage.SHA256Path
+		}
+		for _, c := range r.ResolvableCVEs {
+			severity := strings.ToLower(c.Severity)
+			counts[severity]++
+			image.CVEs = append(image.CVEs, cveSummary{
+				ID:         cveID(c),
+				Severity:   severity,
+				AdvisoryID: c.AdvisoryID,
+				URL:        advisoryURL(c.AdvisoryID),
+			})
+		}
+		component.Images = append(component.Images, image)
+		component.CVEs += len(image.CVEs)
+		ns.CVEs += len(image.CVEs)
+		key := image.Ref
+		if key == "" {
+			key = image.Image
+		}
+		if key != "" && !images[key] {
+			images[key] = true
+			s.Images++
+		}
+	}
+	other := 0
+	for severity, count := range counts {
+		if !isSeverity(severity) {
+			other += count
+		}
+	}
+	for _, severity := range severities {
+		s.Totals = append(s.Totals, severityTotal{Severity: severity, Count: counts[severity]})
+	}
+	if other > 0 {
+		s.Totals = append(s.Totals, severityTotal{Severity: "other", Count: other})
+	}
+	return s
+}
+
+func isSeverity(severity string) bool {
+	for _, s := range severities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
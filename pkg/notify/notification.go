@@ -0,0 +1,161 @@
+// Package notify tells people about new findings in the monitored workloads through the channels configured by the
+// ImageNotifiers of their namespace: a webhook, a Slack compatible incoming webhook or email.
+package notify
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+)
+
+// The types of finding that are notified
+const (
+	FindingResolvableCVE = "ResolvableCVE"
+	FindingImageOutdated = "ImageOutdated"
+)
+
+// Notification lists the new findings of a scan of a workload
+type Notification struct {
+	Namespace string    `json:"namespace"`
+	Workload  string    `json:"workload"`
+	Findings  []Finding `json:"findings"`
+}
+
+// Finding is a resolvable CVE in an image or an image behind the latest patch build of its tag
+type Finding struct {
+	Type       string `json:"type"`
+	Image      string `json:"image"`
+	CurrentTag string `json:"currentTag,omitempty"`
+	FixedTag   string `json:"fixedTag,omitempty"`
+	CVE        string `json:"cve,omitempty"`
+	Severity   string `json:"severity,omitempty"`
+	AdvisoryID string `json:"advisoryID,omitempty"`
+}
+
+// key identifies the finding across scans. An outdated image is identified by the tag it can be updated to so a newer
+// patch build is notified again.
+func (f Finding) key() string {
+	if f.Type == FindingImageOutdated {
+		return f.Type + "|" + f.Image + "|" + f.FixedTag
+	}
+	return f.Type + "|" + f.Image + "|" + f.CVE
+}
+
+// Summary is a one line description of the notification
+func (n Notification) Summary() string {
+	cves, outdated := 0, 0
+	for _, f := range n.Findings {
+		if f.Type == FindingResolvableCVE {
+			cves++
+		} else {
+			outdated++
+		}
+	}
+	var parts []string
+	if cves > 0 {
+		parts = append(parts, fmt.Sprintf("%d new resolvable CVEs", cves))
+	}
+	if outdated > 0 {
+		parts = append(parts, fmt.Sprintf("%d newly outdated images", outdated))
+	}
+	return fmt.Sprintf("heimdall found %s in %s/%s", strings.Join(parts, " and "), n.Namespace, n.Workload)
+}
+
+// Lines describes each finding on a line of its own
+func (n Notification) Lines() []string {
+	var lines []string
+	for _, f := range n.Findings {
+		if f.Type == FindingImageOutdated {
+			lines = append(lines, fmt.Sprintf("%s is on tag %s, patch build %s is available", f.Image, f.CurrentTag, f.FixedTag))
+			continue
+		}
+		line := fmt.Sprintf("%s (%s) in %s is resolved by tag %s", f.CVE, f.Severity, f.Image, f.FixedTag)
+		if f.AdvisoryID != "" && f.AdvisoryID != f.CVE {
+			line += ", see " + f.AdvisoryID
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// severityRank orders the severities, CVEs of an unknown severity rank lowest
+var severityRank = map[string]int{"low": 1, "moderate": 2, "important": 3, "critical": 4}
+
+// Findings returns the findings of the reports of a scan that are at least minSeverity, and the outdated images if
+// outdated is set, ordered so the most severe come first
+func Findings(reports []domain.ReportResult, minSeverity string, outdated bool) []Finding {
+	min := severityRank[domain.NormaliseSeverity(minSeverity)]
+	if min == 0 {
+		min = severityRank["critical"]
+	}
+	var findings []Finding
+	for _, r := range reports {
+		image := r.ClusterImage.FullPath
+		if outdated && r.LatestAvailablePatchVersion != "" && r.CurrentVersion != r.LatestAvailablePatchVersion {
+			findings = append(findings, Finding{
+				Type:       FindingImageOutdated,
+				Image:      image,
+				CurrentTag: r.CurrentVersion,
+				FixedTag:   r.LatestAvailablePatchVersion,
+			})
+		}
+		for _, c := range r.ResolvableCVEs {
+			severity := domain.NormaliseSeverity(c.Severity)
+			if severityRank[severity] < min {
+				continue
+			}
+			id := c.ID
+			if id == "" {
+				id = c.AdvisoryID
+			}
+			findings = append(findings, Finding{
+				Type:       FindingResolvableCVE,
+				Image:      image,
+				CurrentTag: r.CurrentVersion,
+				FixedTag:   r.LatestAvailablePatchVersion,
+				CVE:        id,
+				Severity:   severity,
+				AdvisoryID: c.AdvisoryID,
+			})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank[findings[i].Severity] > severityRank[findings[j].Severity]
+	})
+	return findings
+}
+
+// fingerprint identifies a finding sent by a notifier in the notified annotation of a workload. It is short as the
+// annotation holds one for each current finding of each notifier.
+func fingerprint(notifier string, f Finding) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(notifier + "|" + f.key()))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// Fingerprints is the set of findings already notified for a workload, as recorded in its notified annotation
+type Fingerprints map[string]bool
+
+// ParseFingerprints reads the notified annotation of a workload
+func ParseFingerprints(annotation string) Fingerprints {
+	fps := Fingerprints{}
+	for _, fp := range strings.Split(annotation, ",") {
+		if fp != "" {
+			fps[fp] = true
+		}
+	}
+	return fps
+}
+
+// String formats the fingerprints for the notified annotation of a workload
+func (fps Fingerprints) String() string {
+	var list []string
+	for fp := range fps {
+		list = append(list, fp)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
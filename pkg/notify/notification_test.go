@@ -0,0 +1,94 @@
+package notify_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/notify"
+)
+
+func report(image, latest string, cves ...domain.CVE) domain.ReportResult {
+	img := cluster.MustParseImage(image)
+	return domain.ReportResult{
+		Component:                   "amq",
+		ResolvableCVEs:              cves,
+		CurrentVersion:              img.Tag,
+		LatestAvailablePatchVersion: latest,
+		ClusterImage:                img,
+	}
+}
+
+func TestFindings(t *testing.T) {
+	reports := []domain.ReportResult{
+		report("registry.redhat.io/amq7/amq-broker:7.5-2", "7.5-4",
+			domain.CVE{ID: "CVE-2020-1", Severity: "moderate"},
+			domain.CVE{ID: "CVE-2020-2", Severity: "Critical", AdvisoryID: "RHSA-2020:1"},
+			domain.CVE{ID: "CVE-2020-3", Severity: "high"}),
+		report("registry.redhat.io/amq7/amq-interconnect:1.6-1", "1.6-1"),
+	}
+	cases := []struct {
+		Name        string
+		MinSeverity string
+		Outdated    bool
+		Expect      []string
+	}{
+		{
+			Name:   "test only critical CVEs are found by default",
+			Expect: []string{"CVE-2020-2"},
+		},
+		{
+			Name:        "test CVEs of at least the minimum severity are found most severe first",
+			MinSeverity: "moderate",
+			Expect:      []string{"CVE-2020-2", "CVE-2020-3", "CVE-2020-1"},
+		},
+		{
+			Name:     "test images behind the latest patch build are found when outdated is set",
+			Outdated: true,
+			Expect:   []string{"CVE-2020-2", "7.5-4"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			findings := notify.Findings(reports, tc.MinSeverity, tc.Outdated)
+			var got []string
+			for _, f := range findings {
+				if f.Type == notify.FindingImageOutdated {
+					got = append(got, f.FixedTag)
+					continue
+				}
+				got = append(got, f.CVE)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.Expect, ",") {
+				t.Fatal("expected findings", tc.Expect, "got", got)
+			}
+		})
+	}
+}
+
+func TestNotification_Summary(t *testing.T) {
+	n := notify.Notification{Namespace: "fuse", Workload: "broker", Findings: notify.Findings([]domain.ReportResult{
+		report("registry.redhat.io/amq7/amq-broker:7.5-2", "7.5-4", domain.CVE{ID: "CVE-2020-2", Severity: "critical", AdvisoryID: "RHSA-2020:1"}),
+	}, "", true)}
+	if n.Summary() != "heimdall found 1 new resolvable CVEs and 1 newly outdated images in fuse/broker" {
+		t.Fatal("unexpected summary", n.Summary())
+	}
+	lines := n.Lines()
+	if len(lines) != 2 || !strings.Contains(lines[0], "CVE-2020-2 (critical)") || !strings.Contains(lines[0], "RHSA-2020:1") {
+		t.Fatal("expected a line for each finding with the CVE first, got", lines)
+	}
+}
+
+func TestParseFingerprints(t *testing.T) {
+	fps := notify.ParseFingerprints("b,a,,c")
+	if len(fps) != 3 || !fps["a"] {
+		t.Fatal("expected 3 fingerprints, got", fps)
+	}
+	if fps.String() != "a,b,c" {
+		t.Fatal("expected the fingerprints to be sorted, got", fps.String())
+	}
+	if notify.ParseFingerprints("").String() != "" {
+		t.Fatal("expected no fingerprints")
+	}
+}
@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/transport"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("notify")
+
+// Notifier sends the new findings of each scan to the channels of the ImageNotifiers in the namespace of the workload
+type Notifier struct {
+	client client.Client
+	// reader reads secrets straight from the API so the operator does not cache every secret in the cluster
+	reader client.Reader
+	http   *http.Client
+}
+
+// NewNotifier creates a Notifier posting through the shared http client
+func NewNotifier(c client.Client, reader client.Reader) *Notifier {
+	return &Notifier{client: c, reader: reader, http: transport.Client()}
+}
+
+// Notify sends the findings of the reports of a scan of a workload that the ImageNotifiers in its namespace have not
+// already sent. annotation is the notified annotation of the workload and the returned value is its new value: the
+// fingerprints of the current findings that have been sent. A finding that is resolved drops out so it is sent again
+// should it return, and a finding that failed to send is retried on the next scan. A scan that is not complete, as some
+// images failed to be checked, keeps the previous fingerprints so the findings of those images are not sent again once
+// they can be checked.
+func (n *Notifier) Notify(ctx context.Context, namespace, workload, annotation string, reports []domain.ReportResult, complete bool) string {
+	notifiers := &v1alpha1.ImageNotifierList{}
+	if err := n.client.List(ctx, notifiers, &client.ListOptions{Namespace: namespace}); err != nil {
+		log.Error(err, "failed to list image notifiers in namespace "+namespace)
+		return annotation
+	}
+	previous := ParseFingerprints(annotation)
+	notified := Fingerprints{}
+	if !complete {
+		for fp := range previous {
+			notified[fp] = true
+		}
+	}
+	for i := range notifiers.Items {
+		notifier := &notifiers.Items[i]
+		findings := Findings(reports, notifier.Spec.MinSeverity, notifier.Spec.NotifyOutdated)
+		if len(findings) == 0 {
+			continue
+		}
+		senders, err := n.senders(ctx, notifier)
+		if err != nil {
+			// keep what was sent before so it is not sent again once the notifier can send
+			for _, channel := range channels(notifier.Spec) {
+				for _, f := range findings {
+					if fp := fingerprint(notifier.Name+"/"+channel, f); previous[fp] {
+						notified[fp] = true
+					}
+				}
+			}
+			n.updateStatus(ctx, notifier, false, err)
+			continue
+		}
+		sent := false
+		var sendErrs []string
+		for _, s := range senders {
+			var unsent []Finding
+			var fps []string
+			for _, f := range findings {
+				fp := fingerprint(notifier.Name+"/"+s.name(), f)
+				if previous[fp] {
+					notified[fp] = true
+					continue
+				}
+				unsent = append(unsent, f)
+				fps = append(fps, fp)
+			}
+			if len(unsent) == 0 {
+				continue
+			}
+			if err := s.send(ctx, Notification{Namespace: namespace, Workload: workload, Findings: unsent}); err != nil {
+				log.Error(err, "failed to send notification", "notifier", notifier.Name, "channel", s.name())
+				sendErrs = append(sendErrs, s.name()+": "+err.Error())
+				continue
+			}
+			sent = true
+			for _, fp := range fps {
+				notified[fp] = true
+			}
+		}
+		if sent || len(sendErrs) > 0 {
+			var sendErr error
+			if len(sendErrs) > 0 {
+				sendErr = errors.New(strings.Join(sendErrs, "; "))
+			}
+			n.updateStatus(ctx, notifier, sent, sendErr)
+		}
+	}
+	return notified.String()
+}
+
+// Annotate notifies about the findings of the reports of a scan of obj and records what has been sent in its notified
+// annotation. complete is false when some images of obj failed to be checked. obj still has to be updated.
+func (n *Notifier) Annotate(ctx context.Context, obj metav1.Object, reports []domain.ReportResult, complete bool) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	notified := n.Notify(ctx, obj.GetNamespace(), obj.GetName(), annotations[domain.HeimdallNotified], reports, complete)
+	if notified == "" {
+		delete(annotations, domain.HeimdallNotified)
+	} else {
+		annotations[domain.HeimdallNotified] = notified
+	}
+	obj.SetAnnotations(annotations)
+}
+
+// senders creates a sender for each channel configured by the notifier
+func (n *Notifier) senders(ctx context.Context, notifier *v1alpha1.ImageNotifier) ([]sender, error) {
+	var senders []sender
+	spec := notifier.Spec
+	if spec.Webhook != nil {
+		url, err := n.url(ctx, notifier.Namespace, spec.Webhook.URL, spec.Webhook.URLSecret)
+		if err != nil {
+			return nil, errors.Wrap(err, "webhook")
+		}
+		w, err := newWebhook(n.http, url, spec.Webhook.Headers, spec.Webhook.BodyTemplate)
+		if err != nil {
+			return nil, err
+		}
+		senders = append(senders, w)
+	}
+	if spec.Slack != nil {
+		url, err := n.url(ctx, notifier.Namespace, spec.Slack.URL, spec.Slack.URLSecret)
+		if err != nil {
+			return nil, errors.Wrap(err, "slack")
+		}
+		senders = append(senders, &slack{client: n.http, url: url, channel: spec.Slack.Channel})
+	}
+	if spec.Email != nil {
+		if spec.Email.SMTPServer == "" || spec.Email.From == "" || len(spec.Email.To) == 0 {
+			return nil, errors.New("email: smtpServer, from and to are required")
+		}
+		e := &email{server: spec.Email.SMTPServer, from: spec.Email.From, to: spec.Email.To}
+		if spec.Email.CredentialsSecret != "" {
+			secret := &corev1.Secret{}
+			if err := n.reader.Get(ctx, client.ObjectKey{Namespace: notifier.Namespace, Name: spec.Email.CredentialsSecret}, secret); err != nil {
+				return nil, errors.Wrap(err, "email: failed to read credentials secret "+spec.Email.CredentialsSecret)
+			}
+			e.username = string(secret.Data["username"])
+			e.password = string(secret.Data["password"])
+		}
+		senders = append(senders, e)
+	}
+	return senders, nil
+}
+
+// channels returns the names of the channels configured by the notifier
+func channels(spec v1alpha1.ImageNotifierSpec) []string {
+	var names []string
+	if spec.Webhook != nil {
+		names = append(names, channelWebhook)
+	}
+	if spec.Slack != nil {
+		names = append(names, channelSlack)
+	}
+	if spec.Email != nil {
+		names = append(names, channelEmail)
+	}
+	return names
+}
+
+// url returns the key of the secret selected by fromSecret, or url when no secret is selected
+func (n *Notifier) url(ctx context.Context, namespace, url string, fromSecret *corev1.SecretKeySelector) (string, error) {
+	if fromSecret != nil {
+		secret := &corev1.Secret{}
+		if err := n.reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: fromSecret.Name}, secret); err != nil {
+			return "", errors.Wrap(err, "failed to read url secret "+fromSecret.Name)
+		}
+		url = string(secret.Data[fromSecret.Key])
+	}
+	if url == "" {
+		return "", errors.New("no url is set")
+	}
+	return url, nil
+}
+
+// updateStatus records the outcome of sending notifications on the status of the notifier. Failing to do so is only
+// logged as the notifications have been sent.
+func (n *Notifier) updateStatus(ctx context.Context, notifier *v1alpha1.ImageNotifier, sent bool, sendErr error) {
+	if sent {
+		now := metav1.NewTime(time.Now())
+		notifier.Status.LastSent = &now
+	}
+	notifier.Status.LastError = ""
+	if sendErr != nil {
+		log.Error(sendErr, "image notifier failed", "namespace", notifier.Namespace, "name", notifier.Name)
+		notifier.Status.LastError = sendErr.Error()
+	}
+	if err := n.client.Status().Update(ctx, notifier); err != nil {
+		log.Error(err, "failed to update the status of image notifier "+notifier.Name)
+	}
+}
@@ -0,0 +1,334 @@
+package notify_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/apis"
+	"github.com/integr8ly/heimdall/pkg/apis/imagemonitor/v1alpha1"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/notify"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// receiver records the bodies posted to it and answers with status
+type receiver struct {
+	sync.Mutex
+	status int
+	bodies []string
+	header http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+	body, _ := ioutil.ReadAll(req.Body)
+	r.bodies = append(r.bodies, string(body))
+	r.header = req.Header
+	w.WriteHeader(r.status)
+}
+
+func newNotifier(t *testing.T, objs ...runtime.Object) (*notify.Notifier, client.Client) {
+	s := runtime.NewScheme()
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal("failed to add the apis to the scheme", err)
+	}
+	if err := corev1.AddToScheme(s); err != nil {
+		t.Fatal("failed to add core to the scheme", err)
+	}
+	c := fakeclient.NewFakeClientWithScheme(s, objs...)
+	return notify.NewNotifier(c, c), c
+}
+
+func imageNotifier(spec v1alpha1.ImageNotifierSpec) *v1alpha1.ImageNotifier {
+	return &v1alpha1.ImageNotifier{
+		ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "fuse"},
+		Spec:       spec,
+	}
+}
+
+var vulnerable = []domain.ReportResult{
+	report("registry.redhat.io/amq7/amq-broker:7.5-2", "7.5-4", domain.CVE{ID: "CVE-2020-2", Severity: "critical"}),
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Spec     func(url string) v1alpha1.ImageNotifierSpec
+		Secret   *corev1.Secret
+		Validate func(t *testing.T, r *receiver)
+	}{
+		{
+			Name: "test the notification is posted as json to a webhook",
+			Spec: func(url string) v1alpha1.ImageNotifierSpec {
+				return v1alpha1.ImageNotifierSpec{Webhook: &v1alpha1.WebhookNotifier{URL: url, Headers: map[string]string{"Authorization": "Bearer token"}}}
+			},
+			Validate: func(t *testing.T, r *receiver) {
+				n := notify.Notification{}
+				if err := json.Unmarshal([]byte(r.bodies[0]), &n); err != nil {
+					t.Fatal("expected the notification as json", err)
+				}
+				if n.Namespace != "fuse" || n.Workload != "broker" || len(n.Findings) != 1 || n.Findings[0].CVE != "CVE-2020-2" {
+					t.Fatal("unexpected notification", n)
+				}
+				if r.header.Get("Authorization") != "Bearer token" {
+					t.Fatal("expected the configured header to be sent")
+				}
+			},
+		},
+		{
+			Name: "test the webhook body is rendered by the template",
+			Spec: func(url string) v1alpha1.ImageNotifierSpec {
+				return v1alpha1.ImageNotifierSpec{Webhook: &v1alpha1.WebhookNotifier{URL: url, BodyTemplate: `{"title": {{ json .Summary }}}`}}
+			},
+			Validate: func(t *testing.T, r *receiver) {
+				if r.bodies[0] != `{"title": "heimdall found 1 new resolvable CVEs in fuse/broker"}` {
+					t.Fatal("unexpected body", r.bodies[0])
+				}
+			},
+		},
+		{
+			Name: "test a slack message is posted to the url in the secret",
+			Spec: func(url string) v1alpha1.ImageNotifierSpec {
+				return v1alpha1.ImageNotifierSpec{Slack: &v1alpha1.SlackNotifier{
+					URL:       "http://unused.example.com",
+					URLSecret: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "slack"}, Key: "url"},
+					Channel:   "#security",
+				}}
+			},
+			Secret: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "fuse"}},
+			Validate: func(t *testing.T, r *receiver) {
+				msg := map[string]string{}
+				if err := json.Unmarshal([]byte(r.bodies[0]), &msg); err != nil {
+					t.Fatal("expected a json message", err)
+				}
+				if msg["channel"] != "#security" || !strings.HasPrefix(msg["text"], "*heimdall found 1 new resolvable CVEs") || !strings.Contains(msg["text"], "\n• CVE-2020-2") {
+					t.Fatal("unexpected slack message", msg)
+				}
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := &receiver{status: http.StatusOK}
+			server := httptest.NewServer(r)
+			defer server.Close()
+			objs := []runtime.Object{imageNotifier(tc.Spec(server.URL))}
+			if tc.Secret != nil {
+				tc.Secret.Data = map[string][]byte{"url": []byte(server.URL)}
+				objs = append(objs, tc.Secret)
+			}
+			n, c := newNotifier(t, objs...)
+			notified := n.Notify(context.TODO(), "fuse", "broker", "", vulnerable, true)
+			if notified == "" {
+				t.Fatal("expected the sent finding to be recorded")
+			}
+			if len(r.bodies) != 1 {
+				t.Fatal("expected a single notification, got", len(r.bodies))
+			}
+			tc.Validate(t, r)
+			got := &v1alpha1.ImageNotifier{}
+			if err := c.Get(context.TODO(), client.ObjectKey{Namespace: "fuse", Name: "team"}, got); err != nil {
+				t.Fatal("failed to get the image notifier", err)
+			}
+			if got.Status.LastSent == nil || got.Status.LastError != "" {
+				t.Fatal("expected the status to record the notification", got.Status)
+			}
+		})
+	}
+}
+
+func TestNotifier_NotifiesOnce(t *testing.T) {
+	r := &receiver{status: http.StatusOK}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	n, _ := newNotifier(t, imageNotifier(v1alpha1.ImageNotifierSpec{MinSeverity: "important", Webhook: &v1alpha1.WebhookNotifier{URL: server.URL}}))
+
+	notified := n.Notify(context.TODO(), "fuse", "broker", "", vulnerable, true)
+	notified = n.Notify(context.TODO(), "fuse", "broker", notified, vulnerable, true)
+	if len(r.bodies) != 1 {
+		t.Fatal("expected a finding to be sent once, got", len(r.bodies))
+	}
+
+	more := []domain.ReportResult{report("registry.redhat.io/amq7/amq-broker:7.5-2", "7.5-4",
+		domain.CVE{ID: "CVE-2020-2", Severity: "critical"}, domain.CVE{ID: "CVE-2020-3", Severity: "important"})}
+	notified = n.Notify(context.TODO(), "fuse", "broker", notified, more, true)
+	if len(r.bodies) != 2 || strings.Contains(r.bodies[1], "CVE-2020-2") || !strings.Contains(r.bodies[1], "CVE-2020-3") {
+		t.Fatal("expected only the new finding to be sent", r.bodies)
+	}
+
+	if n.Notify(context.TODO(), "fuse", "broker", notified, nil, false) != notified {
+		t.Fatal("expected the findings to be kept when the images failed to be checked")
+	}
+	notified = n.Notify(context.TODO(), "fuse", "broker", notified, nil, true)
+	if notified != "" {
+		t.Fatal("expected resolved findings to be forgotten, got", notified)
+	}
+	n.Notify(context.TODO(), "fuse", "broker", notified, vulnerable, true)
+	if len(r.bodies) != 3 {
+		t.Fatal("expected a finding that returns to be sent again, got", len(r.bodies))
+	}
+}
+
+func TestNotifier_RetriesFailedSends(t *testing.T) {
+	r := &receiver{status: http.StatusInternalServerError}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	n, c := newNotifier(t, imageNotifier(v1alpha1.ImageNotifierSpec{Webhook: &v1alpha1.WebhookNotifier{URL: server.URL}}))
+
+	notified := n.Notify(context.TODO(), "fuse", "broker", "", vulnerable, true)
+	if notified != "" {
+		t.Fatal("expected a finding that failed to send not to be recorded, got", notified)
+	}
+	got := &v1alpha1.ImageNotifier{}
+	if err := c.Get(context.TODO(), client.ObjectKey{Namespace: "fuse", Name: "team"}, got); err != nil {
+		t.Fatal("failed to get the image notifier", err)
+	}
+	if !strings.Contains(got.Status.LastError, "500") {
+		t.Fatal("expected the error to be recorded on the status, got", got.Status.LastError)
+	}
+
+	r.Lock()
+	r.status = http.StatusOK
+	r.Unlock()
+	if n.Notify(context.TODO(), "fuse", "broker", notified, vulnerable, true) == "" || len(r.bodies) != 2 {
+		t.Fatal("expected the finding to be sent on the next scan")
+	}
+}
+
+func TestNotifier_KeepsSentFindingsWhenTheNotifierFails(t *testing.T) {
+	r := &receiver{status: http.StatusOK}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "hook", Namespace: "fuse"},
+		Data:       map[string][]byte{"url": []byte(server.URL)},
+	}
+	n, c := newNotifier(t, imageNotifier(v1alpha1.ImageNotifierSpec{Webhook: &v1alpha1.WebhookNotifier{
+		URLSecret: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "hook"}, Key: "url"},
+	}}), secret.DeepCopy())
+
+	notified := n.Notify(context.TODO(), "fuse", "broker", "", vulnerable, true)
+	if notified == "" || len(r.bodies) != 1 {
+		t.Fatal("expected the finding to be sent")
+	}
+	if err := c.Delete(context.TODO(), secret.DeepCopy()); err != nil {
+		t.Fatal("failed to delete the url secret", err)
+	}
+	if kept := n.Notify(context.TODO(), "fuse", "broker", notified, vulnerable, true); kept != notified {
+		t.Fatal("expected the sent findings to be kept while the url secret is missing, got", kept)
+	}
+	if err := c.Create(context.TODO(), secret.DeepCopy()); err != nil {
+		t.Fatal("failed to recreate the url secret", err)
+	}
+	n.Notify(context.TODO(), "fuse", "broker", notified, vulnerable, true)
+	if len(r.bodies) != 1 {
+		t.Fatal("expected the finding not to be sent again once the secret is back, got", len(r.bodies))
+	}
+}
+
+func TestNotifier_Annotate(t *testing.T) {
+	n, _ := newNotifier(t)
+	obj := &metav1.ObjectMeta{Name: "broker", Namespace: "fuse", Annotations: map[string]string{domain.HeimdallNotified: "abcd1234"}}
+	n.Annotate(context.TODO(), obj, vulnerable, true)
+	if _, ok := obj.Annotations[domain.HeimdallNotified]; ok {
+		t.Fatal("expected the annotation to be removed when nothing is notified")
+	}
+}
+
+// smtpServer is a minimal SMTP server recording the messages it receives
+func smtpServer(t *testing.T) (string, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("failed to listen", err)
+	}
+	messages := make(chan string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+		reply := func(s string) {
+			_, _ = rw.WriteString(s + "\r\n")
+			_ = rw.Flush()
+		}
+		reply("220 localhost")
+		var msg strings.Builder
+		for {
+			line, err := rw.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.Fields(line + " x")[0])
+			switch cmd {
+			case "EHLO":
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case "AUTH":
+				creds, _ := base64.StdEncoding.DecodeString(strings.Fields(line)[2])
+				msg.WriteString("auth:" + strings.Replace(string(creds), "\x00", " ", -1) + "\n")
+				reply("235 ok")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					l, err := rw.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					msg.WriteString(l)
+				}
+				reply("250 ok")
+			case "QUIT":
+				reply("221 bye")
+				messages <- msg.String()
+				return
+			default:
+				msg.WriteString(line)
+				reply("250 ok")
+			}
+		}
+	}()
+	return l.Addr().String(), messages
+}
+
+func TestNotifier_Email(t *testing.T) {
+	addr, messages := smtpServer(t)
+	n, _ := newNotifier(t,
+		imageNotifier(v1alpha1.ImageNotifierSpec{Email: &v1alpha1.EmailNotifier{
+			SMTPServer:        addr,
+			From:              "heimdall@example.com",
+			To:                []string{"team@example.com"},
+			CredentialsSecret: "smtp",
+		}}),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "smtp", Namespace: "fuse"},
+			Data:       map[string][]byte{"username": []byte("heimdall"), "password": []byte("secret")},
+		},
+	)
+	if n.Notify(context.TODO(), "fuse", "broker", "", vulnerable, true) == "" {
+		t.Fatal("expected the email to be sent")
+	}
+	msg := <-messages
+	for _, expect := range []string{"auth: heimdall secret", "RCPT TO:<team@example.com>",
+		"Subject: heimdall found 1 new resolvable CVEs in fuse/broker", "CVE-2020-2 (critical)"} {
+		if !strings.Contains(msg, expect) {
+			t.Fatal("expected the email to contain", expect, "got", msg)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the names of the channels a notifier can send to, fingerprints of findings are recorded per channel
+const (
+	channelWebhook = "webhook"
+	channelSlack   = "slack"
+	channelEmail   = "email"
+)
+
+// sender delivers a notification to a channel
+type sender interface {
+	send(ctx context.Context, n Notification) error
+	// name is the channel of the sender, it is part of the fingerprints of the findings it has sent
+	name() string
+}
+
+// templateFuncs are available to the body templates of webhooks
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON so strings are quoted and escaped
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+type webhook struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+	body    *template.Template
+}
+
+// newWebhook creates a sender posting to url. The body is rendered by bodyTemplate or is the notification as JSON
+// when it is empty.
+func newWebhook(client *http.Client, url string, headers map[string]string, bodyTemplate string) (*webhook, error) {
+	w := &webhook{client: client, url: url, headers: headers}
+	if bodyTemplate != "" {
+		t, err := template.New("body").Funcs(templateFuncs).Parse(bodyTemplate)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid webhook body template")
+		}
+		w.body = t
+	}
+	return w, nil
+}
+
+func (w *webhook) name() string {
+	return channelWebhook
+}
+
+func (w *webhook) send(ctx context.Context, n Notification) error {
+	var body []byte
+	if w.body == nil {
+		b, err := json.Marshal(n)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode the notification")
+		}
+		body = b
+	} else {
+		buf := &bytes.Buffer{}
+		if err := w.body.Execute(buf, n); err != nil {
+			return errors.Wrap(err, "failed to render the webhook body")
+		}
+		if !json.Valid(buf.Bytes()) {
+			return errors.New("the webhook body template did not render valid JSON")
+		}
+		body = buf.Bytes()
+	}
+	return postJSON(ctx, w.client, w.url, w.headers, body)
+}
+
+type slack struct {
+	client  *http.Client
+	url     string
+	channel string
+}
+
+// slackMessage is the payload of a Slack incoming webhook, which Mattermost and Rocket.Chat accept too
+type slackMessage struct {
+	Text     string `json:"text"`
+	Channel  string `json:"channel,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+func (s *slack) name() string {
+	return channelSlack
+}
+
+func (s *slack) send(ctx context.Context, n Notification) error {
+	text := "*" + n.Summary() + "*"
+	for _, l := range n.Lines() {
+		text += "\n• " + l
+	}
+	body, err := json.Marshal(slackMessage{Text: text, Channel: s.channel, Username: "heimdall"})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the slack message")
+	}
+	return postJSON(ctx, s.client, s.url, nil, body)
+}
+
+// postJSON posts body to url and fails unless the response is successful
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to create the request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "failed to post the notification")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("posting the notification failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+type email struct {
+	server   string
+	from     string
+	to       []string
+	username string
+	password string
+}
+
+func (e *email) name() string {
+	return channelEmail
+}
+
+func (e *email) send(ctx context.Context, n Notification) error {
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %s\r\n", e.from)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(msg, "Subject: %s\r\n", n.Summary())
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	for _, l := range n.Lines() {
+		msg.WriteString(l + "\r\n")
+	}
+	return e.sendMail(ctx, msg.Bytes())
+}
+
+// sendMail works as smtp.SendMail but gives up when ctx is done
+func (e *email) sendMail(ctx context.Context, msg []byte) error {
+	host, _, err := net.SplitHostPort(e.server)
+	if err != nil {
+		return errors.Wrap(err, "invalid smtp server "+e.server)
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", e.server)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to smtp server "+e.server)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "failed to connect to smtp server "+e.server)
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(nil); err != nil {
+			return errors.Wrap(err, "failed to start tls with smtp server "+e.server)
+		}
+	}
+	if e.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.username, e.password, host)); err != nil {
+			return errors.Wrap(err, "failed to authenticate with smtp server "+e.server)
+		}
+	}
+	if err := c.Mail(e.from); err != nil {
+		return errors.Wrap(err, "smtp server rejected the sender")
+	}
+	for _, to := range e.to {
+		if err := c.Rcpt(to); err != nil {
+			return errors.Wrap(err, "smtp server rejected recipient "+to)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return errors.Wrap(err, "failed to send the email")
+	}
+	if _, err := w.Write(msg); err != nil {
+		return errors.Wrap(err, "failed to send the email")
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "failed to send the email")
+	}
+	return c.Quit()
+}
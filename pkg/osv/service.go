@@ -0,0 +1,98 @@
+package osv
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/integr8ly/heimdall/pkg/customMetrics"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/transport"
+	"github.com/pkg/errors"
+)
+
+// Client reads CVEs from a JSON feed containing an array of OSV vulnerabilities. Images are matched on the package
+// name, which is expected to be the repository path of the image (host/org/image or org/image), and the affected
+// versions, which are expected to be the image tags.
+type Client struct {
+	FeedURL string
+}
+
+func NewClient(feedURL string) *Client {
+	return &Client{FeedURL: feedURL}
+}
+
+func (c *Client) CVES(ctx context.Context, repo, tag string) ([]domain.CVE, error) {
+	if repo == "" || tag == "" {
+		return nil, errors.New("expected a repo and a tag but got repo " + repo + " tag " + tag)
+	}
+	vulns, err := c.feed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var cves []domain.CVE
+	for _, v := range vulns {
+		for _, a := range v.Affected {
+			if !matchesRepo(a.Package.Name, repo) || !affectsTag(a.Versions, tag) {
+				continue
+			}
+			severity := a.EcosystemSpecific.Severity
+			if severity == "" {
+				severity = v.DatabaseSpecific.Severity
+			}
+			cves = append(cves, domain.CVE{
+				ID:         cveID(v),
+				AdvisoryID: v.ID,
+				Severity:   domain.NormaliseSeverity(severity),
+			})
+			break
+		}
+	}
+	return cves, nil
+}
+
+func (c *Client) feed(ctx context.Context) ([]Vulnerability, error) {
+	resp, err := transport.Get(ctx, c.FeedURL)
+	customMetrics.RegistryCallsTotal.Inc()
+	if err != nil {
+		customMetrics.RegistryCallsFailure.Inc()
+		return nil, errors.Wrap(err, "failed to get osv feed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		customMetrics.RegistryCallsFailure.Inc()
+		return nil, errors.New("unexpected response from osv feed " + resp.Status)
+	}
+	var vulns []Vulnerability
+	if err := json.NewDecoder(resp.Body).Decode(&vulns); err != nil {
+		customMetrics.RegistryCallsFailure.Inc()
+		return nil, errors.Wrap(err, "failed to decode osv feed")
+	}
+	customMetrics.RegistryCallsSuccess.Inc()
+	return vulns, nil
+}
+
+// the package name may or may not include the registry host
+func matchesRepo(pkg, repo string) bool {
+	return pkg == repo || strings.HasSuffix(repo, "/"+pkg)
+}
+
+func affectsTag(versions []string, tag string) bool {
+	for _, v := range versions {
+		if v == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// prefer the CVE alias so the same vulnerability is reported consistently across sources
+func cveID(v Vulnerability) string {
+	for _, a := range v.Aliases {
+		if strings.HasPrefix(a, "CVE-") {
+			return a
+		}
+	}
+	return v.ID
+}
@@ -0,0 +1,88 @@
+package osv_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/osv"
+)
+
+const feed = `[
+  {"id": "GHSA-1", "aliases": ["CVE-2020-1"], "database_specific": {"severity": "HIGH"},
+   "affected": [{"package": {"ecosystem": "container", "name": "quay.io/org/image"}, "versions": ["1.0", "1.1"]}]},
+  {"id": "GHSA-2", "database_specific": {"severity": "LOW"},
+   "affected": [{"package": {"ecosystem": "container", "name": "org/image"}, "versions": ["1.0"],
+                 "ecosystem_specific": {"severity": "CRITICAL"}}]},
+  {"id": "GHSA-3", "aliases": ["CVE-2020-3"], "database_specific": {"severity": "MODERATE"},
+   "affected": [{"package": {"ecosystem": "container", "name": "org/other"}, "versions": ["1.0"]}]}
+]`
+
+func TestClient_CVES(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Repo        string
+		Tag         string
+		Status      int
+		ExpectError bool
+		Validate    func(t *testing.T, cves []domain.CVE)
+	}{
+		{
+			Name:   "test cves affecting the tag are returned with normalised severities",
+			Repo:   "quay.io/org/image",
+			Tag:    "1.0",
+			Status: http.StatusOK,
+			Validate: func(t *testing.T, cves []domain.CVE) {
+				if len(cves) != 2 {
+					t.Fatal("expected 2 cves but got ", len(cves))
+				}
+				if cves[0].ID != "CVE-2020-1" || cves[0].AdvisoryID != "GHSA-1" || cves[0].Severity != "important" {
+					t.Fatal("unexpected cve ", cves[0])
+				}
+				if cves[1].ID != "GHSA-2" || cves[1].Severity != "critical" {
+					t.Fatal("unexpected cve ", cves[1])
+				}
+			},
+		},
+		{
+			Name:   "test no cves are returned for an unaffected tag",
+			Repo:   "quay.io/org/image",
+			Tag:    "1.2",
+			Status: http.StatusOK,
+			Validate: func(t *testing.T, cves []domain.CVE) {
+				if len(cves) != 0 {
+					t.Fatal("expected no cves but got ", cves)
+				}
+			},
+		},
+		{
+			Name:        "test error when the feed is unavailable",
+			Repo:        "quay.io/org/image",
+			Tag:         "1.0",
+			Status:      http.StatusInternalServerError,
+			ExpectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.Status)
+				w.Write([]byte(feed))
+			}))
+			defer server.Close()
+			cves, err := osv.NewClient(server.URL).CVES(context.TODO(), tc.Repo, tc.Tag)
+			if tc.ExpectError && err == nil {
+				t.Fatal("expected an error but did not get one")
+			}
+			if !tc.ExpectError && err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if tc.Validate != nil {
+				tc.Validate(t, cves)
+			}
+		})
+	}
+}
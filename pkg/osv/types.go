@@ -0,0 +1,24 @@
+package osv
+
+// Vulnerability is an entry in an OSV format feed https://ossf.github.io/osv-schema/
+type Vulnerability struct {
+	ID               string     `json:"id"`
+	Summary          string     `json:"summary"`
+	Aliases          []string   `json:"aliases"`
+	Affected         []Affected `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+type Affected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	// the image tags affected by the vulnerability
+	Versions          []string `json:"versions"`
+	EcosystemSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"ecosystem_specific"`
+}
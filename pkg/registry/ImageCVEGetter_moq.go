@@ -4,6 +4,7 @@
 package registry
 
 import (
+	"context"
 	"github.com/integr8ly/heimdall/pkg/domain"
 	"sync"
 )
@@ -22,7 +23,7 @@ var _ ImageCVEGetter = &ImageCVEGetterMock{}
 //
 //         // make and configure a mocked ImageCVEGetter
 //         mockedImageCVEGetter := &ImageCVEGetterMock{
-//             CVESFunc: func(org string, tag string) ([]domain.CVE, error) {
+//             CVESFunc: func(ctx context.Context, org string, tag string) ([]domain.CVE, error) {
 // 	               panic("mock out the CVES method")
 //             },
 //         }
@@ -33,12 +34,14 @@ var _ ImageCVEGetter = &ImageCVEGetterMock{}
 //     }
 type ImageCVEGetterMock struct {
 	// CVESFunc mocks the CVES method.
-	CVESFunc func(org string, tag string) ([]domain.CVE, error)
+	CVESFunc func(ctx context.Context, org string, tag string) ([]domain.CVE, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
 		// CVES holds details about calls to the CVES method.
 		CVES []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Org is the org argument value.
 			Org string
 			// Tag is the tag argument value.
@@ -48,31 +51,35 @@ type ImageCVEGetterMock struct {
 }
 
 // CVES calls CVESFunc.
-func (mock *ImageCVEGetterMock) CVES(org string, tag string) ([]domain.CVE, error) {
+func (mock *ImageCVEGetterMock) CVES(ctx context.Context, org string, tag string) ([]domain.CVE, error) {
 	if mock.CVESFunc == nil {
 		panic("ImageCVEGetterMock.CVESFunc: method is nil but ImageCVEGetter.CVES was just called")
 	}
 	callInfo := struct {
+		Ctx context.Context
 		Org string
 		Tag string
 	}{
+		Ctx: ctx,
 		Org: org,
 		Tag: tag,
 	}
 	lockImageCVEGetterMockCVES.Lock()
 	mock.calls.CVES = append(mock.calls.CVES, callInfo)
 	lockImageCVEGetterMockCVES.Unlock()
-	return mock.CVESFunc(org, tag)
+	return mock.CVESFunc(ctx, org, tag)
 }
 
 // CVESCalls gets all the calls that were made to CVES.
 // Check the length with:
 //     len(mockedImageCVEGetter.CVESCalls())
 func (mock *ImageCVEGetterMock) CVESCalls() []struct {
+	Ctx context.Context
 	Org string
 	Tag string
 } {
 	var calls []struct {
+		Ctx context.Context
 		Org string
 		Tag string
 	}
@@ -4,6 +4,7 @@
 package registry
 
 import (
+	"context"
 	"github.com/integr8ly/heimdall/pkg/domain"
 	"sync"
 )
@@ -22,7 +23,7 @@ var _ ImageGetter = &ImageGetterMock{}
 //
 //         // make and configure a mocked ImageGetter
 //         mockedImageGetter := &ImageGetterMock{
-//             GetFunc: func(in1 string) (*domain.RemoteImageDigest, error) {
+//             GetFunc: func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
 // 	               panic("mock out the Get method")
 //             },
 //         }
@@ -33,42 +34,48 @@ var _ ImageGetter = &ImageGetterMock{}
 //     }
 type ImageGetterMock struct {
 	// GetFunc mocks the Get method.
-	GetFunc func(in1 string) (*domain.RemoteImageDigest, error)
+	GetFunc func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
 		// Get holds details about calls to the Get method.
 		Get []struct {
-			// In1 is the in1 argument value.
-			In1 string
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ref is the ref argument value.
+			Ref string
 		}
 	}
 }
 
 // Get calls GetFunc.
-func (mock *ImageGetterMock) Get(in1 string) (*domain.RemoteImageDigest, error) {
+func (mock *ImageGetterMock) Get(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
 	if mock.GetFunc == nil {
 		panic("ImageGetterMock.GetFunc: method is nil but ImageGetter.Get was just called")
 	}
 	callInfo := struct {
-		In1 string
+		Ctx context.Context
+		Ref string
 	}{
-		In1: in1,
+		Ctx: ctx,
+		Ref: ref,
 	}
 	lockImageGetterMockGet.Lock()
 	mock.calls.Get = append(mock.calls.Get, callInfo)
 	lockImageGetterMockGet.Unlock()
-	return mock.GetFunc(in1)
+	return mock.GetFunc(ctx, ref)
 }
 
 // GetCalls gets all the calls that were made to Get.
 // Check the length with:
 //     len(mockedImageGetter.GetCalls())
 func (mock *ImageGetterMock) GetCalls() []struct {
-	In1 string
+	Ctx context.Context
+	Ref string
 } {
 	var calls []struct {
-		In1 string
+		Ctx context.Context
+		Ref string
 	}
 	lockImageGetterMockGet.RLock()
 	calls = mock.calls.Get
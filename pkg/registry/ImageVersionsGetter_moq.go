@@ -4,6 +4,7 @@
 package registry
 
 import (
+	"context"
 	"github.com/integr8ly/heimdall/pkg/rhcc"
 	"sync"
 )
@@ -22,7 +23,7 @@ var _ ImageVersionsGetter = &ImageVersionsGetterMock{}
 //
 //         // make and configure a mocked ImageVersionsGetter
 //         mockedImageVersionsGetter := &ImageVersionsGetterMock{
-//             AvailableTagsSortedByDateFunc: func(in1 string) ([]rhcc.Tag, error) {
+//             AvailableTagsSortedByDateFunc: func(ctx context.Context, repo string) ([]rhcc.Tag, error) {
 // 	               panic("mock out the AvailableTagsSortedByDate method")
 //             },
 //         }
@@ -33,42 +34,48 @@ var _ ImageVersionsGetter = &ImageVersionsGetterMock{}
 //     }
 type ImageVersionsGetterMock struct {
 	// AvailableTagsSortedByDateFunc mocks the AvailableTagsSortedByDate method.
-	AvailableTagsSortedByDateFunc func(in1 string) ([]rhcc.Tag, error)
+	AvailableTagsSortedByDateFunc func(ctx context.Context, repo string) ([]rhcc.Tag, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
 		// AvailableTagsSortedByDate holds details about calls to the AvailableTagsSortedByDate method.
 		AvailableTagsSortedByDate []struct {
-			// In1 is the in1 argument value.
-			In1 string
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Repo is the repo argument value.
+			Repo string
 		}
 	}
 }
 
 // AvailableTagsSortedByDate calls AvailableTagsSortedByDateFunc.
-func (mock *ImageVersionsGetterMock) AvailableTagsSortedByDate(in1 string) ([]rhcc.Tag, error) {
+func (mock *ImageVersionsGetterMock) AvailableTagsSortedByDate(ctx context.Context, repo string) ([]rhcc.Tag, error) {
 	if mock.AvailableTagsSortedByDateFunc == nil {
 		panic("ImageVersionsGetterMock.AvailableTagsSortedByDateFunc: method is nil but ImageVersionsGetter.AvailableTagsSortedByDate was just called")
 	}
 	callInfo := struct {
-		In1 string
+		Ctx  context.Context
+		Repo string
 	}{
-		In1: in1,
+		Ctx:  ctx,
+		Repo: repo,
 	}
 	lockImageVersionsGetterMockAvailableTagsSortedByDate.Lock()
 	mock.calls.AvailableTagsSortedByDate = append(mock.calls.AvailableTagsSortedByDate, callInfo)
 	lockImageVersionsGetterMockAvailableTagsSortedByDate.Unlock()
-	return mock.AvailableTagsSortedByDateFunc(in1)
+	return mock.AvailableTagsSortedByDateFunc(ctx, repo)
 }
 
 // AvailableTagsSortedByDateCalls gets all the calls that were made to AvailableTagsSortedByDate.
 // Check the length with:
 //     len(mockedImageVersionsGetter.AvailableTagsSortedByDateCalls())
 func (mock *ImageVersionsGetterMock) AvailableTagsSortedByDateCalls() []struct {
-	In1 string
+	Ctx  context.Context
+	Repo string
 } {
 	var calls []struct {
-		In1 string
+		Ctx  context.Context
+		Repo string
 	}
 	lockImageVersionsGetterMockAvailableTagsSortedByDate.RLock()
 	calls = mock.calls.AvailableTagsSortedByDate
@@ -0,0 +1,241 @@
+package registry
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/customMetrics"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/rhcc"
+)
+
+const (
+	EnvCacheTTL      = "HEIMDALL_CACHE_TTL"
+	EnvCacheSize     = "HEIMDALL_CACHE_SIZE"
+	DefaultCacheTTL  = time.Hour
+	DefaultCacheSize = 1000
+)
+
+// ttlCache is a size bound least recently used cache whose entries expire after ttl. If dir is set entries are also
+// written to disk so they can be reused by later runs of the cli.
+type ttlCache struct {
+	name    string
+	ttl     time.Duration
+	size    int
+	dir     string
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type cacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// diskEntry is the format an entry is persisted in
+type diskEntry struct {
+	Key     string          `json:"key"`
+	Expires time.Time       `json:"expires"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// newTTLCache creates a cache holding up to size entries, a size below one falls back to DefaultCacheSize
+func newTTLCache(name string, ttl time.Duration, size int, dir string) *ttlCache {
+	if size < 1 {
+		log.Info("ignoring invalid cache size, using the default", "cache", name, "size", size, "default", DefaultCacheSize)
+		size = DefaultCacheSize
+	}
+	c := &ttlCache{
+		name:    name,
+		ttl:     ttl,
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+	if dir != "" {
+		c.dir = filepath.Join(dir, name)
+	}
+	return c
+}
+
+// get sets the value pointed to by into to the cached value for key and returns whether it was found
+func (c *ttlCache) get(key string, into interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if ok && time.Now().Before(el.Value.(*cacheEntry).expires) {
+		c.order.MoveToFront(el)
+		reflect.ValueOf(into).Elem().Set(reflect.ValueOf(el.Value.(*cacheEntry).value))
+		customMetrics.CacheHits.WithLabelValues(c.name).Inc()
+		return true
+	}
+	if ok {
+		c.remove(el)
+	}
+	if expires, ok := c.load(key, into); ok {
+		c.add(key, reflect.ValueOf(into).Elem().Interface(), expires)
+		customMetrics.CacheHits.WithLabelValues(c.name).Inc()
+		return true
+	}
+	customMetrics.CacheMisses.WithLabelValues(c.name).Inc()
+	return false
+}
+
+func (c *ttlCache) set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := time.Now().Add(c.ttl)
+	c.add(key, value, expires)
+	c.save(key, value, expires)
+}
+
+func (c *ttlCache) add(key string, value interface{}, expires time.Time) {
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		back := c.order.Back()
+		if back == nil {
+			break
+		}
+		c.remove(back)
+	}
+}
+
+func (c *ttlCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+}
+
+func (c *ttlCache) path(key string) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%x.json", sha256.Sum256([]byte(key))))
+}
+
+// load reads an unexpired entry for key from disk into the value pointed to by into
+func (c *ttlCache) load(key string, into interface{}) (time.Time, bool) {
+	if c.dir == "" {
+		return time.Time{}, false
+	}
+	data, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return time.Time{}, false
+	}
+	entry := &diskEntry{}
+	if err := json.Unmarshal(data, entry); err != nil || entry.Key != key {
+		return time.Time{}, false
+	}
+	if !time.Now().Before(entry.Expires) {
+		os.Remove(c.path(key))
+		return time.Time{}, false
+	}
+	if err := json.Unmarshal(entry.Value, into); err != nil {
+		return time.Time{}, false
+	}
+	return entry.Expires, true
+}
+
+// save writes the entry to disk. Failing to persist an entry only costs a lookup on the next run so errors are logged.
+func (c *ttlCache) save(key string, value interface{}, expires time.Time) {
+	if c.dir == "" {
+		return
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		log.Error(err, "failed to encode cache entry", "cache", c.name, "key", key)
+		return
+	}
+	data, err := json.Marshal(&diskEntry{Key: key, Expires: expires, Value: raw})
+	if err != nil {
+		log.Error(err, "failed to encode cache entry", "cache", c.name, "key", key)
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		log.Error(err, "failed to create cache dir "+c.dir)
+		return
+	}
+	if err := ioutil.WriteFile(c.path(key), data, 0644); err != nil {
+		log.Error(err, "failed to write cache entry", "cache", c.name, "key", key)
+	}
+}
+
+// CachedImageGetter caches the digests of image references
+type CachedImageGetter struct {
+	getter ImageGetter
+	cache  *ttlCache
+}
+
+func NewCachedImageGetter(getter ImageGetter, ttl time.Duration, size int, dir string) *CachedImageGetter {
+	return &CachedImageGetter{getter: getter, cache: newTTLCache("digests", ttl, size, dir)}
+}
+
+func (c *CachedImageGetter) Get(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+	var cached *domain.RemoteImageDigest
+	if c.cache.get(ref, &cached) {
+		return cached, nil
+	}
+	digest, err := c.getter.Get(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.set(ref, digest)
+	return digest, nil
+}
+
+// CachedImageVersionsGetter caches the tags available for a repository
+type CachedImageVersionsGetter struct {
+	getter ImageVersionsGetter
+	cache  *ttlCache
+}
+
+func NewCachedImageVersionsGetter(getter ImageVersionsGetter, ttl time.Duration, size int, dir string) *CachedImageVersionsGetter {
+	return &CachedImageVersionsGetter{getter: getter, cache: newTTLCache("tags", ttl, size, dir)}
+}
+
+func (c *CachedImageVersionsGetter) AvailableTagsSortedByDate(ctx context.Context, repo string) ([]rhcc.Tag, error) {
+	var cached []rhcc.Tag
+	if c.cache.get(repo, &cached) {
+		return cached, nil
+	}
+	tags, err := c.getter.AvailableTagsSortedByDate(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.set(repo, tags)
+	return tags, nil
+}
+
+// CachedImageCVEGetter caches the CVEs affecting a tag of a repository
+type CachedImageCVEGetter struct {
+	getter ImageCVEGetter
+	cache  *ttlCache
+}
+
+func NewCachedImageCVEGetter(getter ImageCVEGetter, ttl time.Duration, size int, dir string) *CachedImageCVEGetter {
+	return &CachedImageCVEGetter{getter: getter, cache: newTTLCache("cves", ttl, size, dir)}
+}
+
+func (c *CachedImageCVEGetter) CVES(ctx context.Context, repo, tag string) ([]domain.CVE, error) {
+	key := repo + ":" + tag
+	var cached []domain.CVE
+	if c.cache.get(key, &cached) {
+		return cached, nil
+	}
+	cves, err := c.getter.CVES(ctx, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.set(key, cves)
+	return cves, nil
+}
@@ -0,0 +1,170 @@
+package registry_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/registry"
+	"github.com/integr8ly/heimdall/pkg/rhcc"
+)
+
+func TestCachedImageGetter(t *testing.T) {
+	cases := []struct {
+		Name        string
+		TTL         time.Duration
+		Size        int
+		Refs        []string
+		ExpectCalls int
+	}{
+		{
+			Name:        "test repeated lookups are served from the cache",
+			TTL:         time.Hour,
+			Size:        10,
+			Refs:        []string{"a:1", "a:1", "b:1", "a:1", "b:1"},
+			ExpectCalls: 2,
+		},
+		{
+			Name:        "test expired entries are looked up again",
+			TTL:         -time.Second,
+			Size:        10,
+			Refs:        []string{"a:1", "a:1", "a:1"},
+			ExpectCalls: 3,
+		},
+		{
+			Name:        "test least recently used entries are evicted when the cache is full",
+			TTL:         time.Hour,
+			Size:        2,
+			Refs:        []string{"a:1", "b:1", "a:1", "c:1", "a:1", "b:1"},
+			ExpectCalls: 4,
+		},
+		{
+			Name:        "test an invalid size falls back to the default size",
+			TTL:         time.Hour,
+			Size:        -1,
+			Refs:        []string{"a:1", "b:1", "a:1", "b:1"},
+			ExpectCalls: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			mock := &registry.ImageGetterMock{
+				GetFunc: func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+					return &domain.RemoteImageDigest{Hash: ref, Algorithm: "sha256"}, nil
+				},
+			}
+			getter := registry.NewCachedImageGetter(mock, tc.TTL, tc.Size, "")
+			for _, ref := range tc.Refs {
+				digest, err := getter.Get(context.TODO(), ref)
+				if err != nil {
+					t.Fatal("did not expect an error but got one ", err)
+				}
+				if digest.Hash != ref {
+					t.Fatal("expected the digest for ", ref, " but got ", digest.Hash)
+				}
+			}
+			if len(mock.GetCalls()) != tc.ExpectCalls {
+				t.Fatal("expected ", tc.ExpectCalls, " calls to the registry but got ", len(mock.GetCalls()))
+			}
+		})
+	}
+}
+
+func TestCachedGettersDoNotCacheErrors(t *testing.T) {
+	fail := true
+	versions := &registry.ImageVersionsGetterMock{
+		AvailableTagsSortedByDateFunc: func(ctx context.Context, repo string) ([]rhcc.Tag, error) {
+			if fail {
+				return nil, errors.New("unavailable")
+			}
+			return []rhcc.Tag{{Name: "1.0"}}, nil
+		},
+	}
+	cves := &registry.ImageCVEGetterMock{
+		CVESFunc: func(ctx context.Context, repo, tag string) ([]domain.CVE, error) {
+			if fail {
+				return nil, errors.New("unavailable")
+			}
+			return []domain.CVE{{ID: "CVE-1"}}, nil
+		},
+	}
+	cachedVersions := registry.NewCachedImageVersionsGetter(versions, time.Hour, 10, "")
+	cachedCVEs := registry.NewCachedImageCVEGetter(cves, time.Hour, 10, "")
+	if _, err := cachedVersions.AvailableTagsSortedByDate(context.TODO(), "org/image"); err == nil {
+		t.Fatal("expected an error getting tags")
+	}
+	if _, err := cachedCVEs.CVES(context.TODO(), "org/image", "1.0"); err == nil {
+		t.Fatal("expected an error getting cves")
+	}
+	fail = false
+	tags, err := cachedVersions.AvailableTagsSortedByDate(context.TODO(), "org/image")
+	if err != nil || len(tags) != 1 {
+		t.Fatal("expected the tags to be looked up again after an error ", err)
+	}
+	found, err := cachedCVEs.CVES(context.TODO(), "org/image", "1.0")
+	if err != nil || len(found) != 1 {
+		t.Fatal("expected the cves to be looked up again after an error ", err)
+	}
+}
+
+func TestCachedGettersPersistToDisk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "heimdall-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	newMocks := func() (*registry.ImageGetterMock, *registry.ImageVersionsGetterMock, *registry.ImageCVEGetterMock) {
+		return &registry.ImageGetterMock{
+			GetFunc: func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+				return &domain.RemoteImageDigest{Hash: "hash", Algorithm: "sha256"}, nil
+			},
+		}, &registry.ImageVersionsGetterMock{
+			AvailableTagsSortedByDateFunc: func(ctx context.Context, repo string) ([]rhcc.Tag, error) {
+				return []rhcc.Tag{{Name: "1.0", Type: "persistent"}}, nil
+			},
+		}, &registry.ImageCVEGetterMock{
+			CVESFunc: func(ctx context.Context, repo, tag string) ([]domain.CVE, error) {
+				return []domain.CVE{{ID: "CVE-1", Severity: "critical"}}, nil
+			},
+		}
+	}
+
+	// the first run populates the cache dir
+	images, versions, cves := newMocks()
+	registry.NewCachedImageGetter(images, time.Hour, 10, dir).Get(context.TODO(), "org/image:1.0")
+	registry.NewCachedImageVersionsGetter(versions, time.Hour, 10, dir).AvailableTagsSortedByDate(context.TODO(), "org/image")
+	registry.NewCachedImageCVEGetter(cves, time.Hour, 10, dir).CVES(context.TODO(), "org/image", "1.0")
+
+	// a later run is served from disk
+	images, versions, cves = newMocks()
+	digest, err := registry.NewCachedImageGetter(images, time.Hour, 10, dir).Get(context.TODO(), "org/image:1.0")
+	if err != nil || digest.Hash != "hash" {
+		t.Fatal("expected the digest from disk but got ", digest, err)
+	}
+	tags, err := registry.NewCachedImageVersionsGetter(versions, time.Hour, 10, dir).AvailableTagsSortedByDate(context.TODO(), "org/image")
+	if err != nil || len(tags) != 1 || tags[0].Type != "persistent" {
+		t.Fatal("expected the tags from disk but got ", tags, err)
+	}
+	found, err := registry.NewCachedImageCVEGetter(cves, time.Hour, 10, dir).CVES(context.TODO(), "org/image", "1.0")
+	if err != nil || len(found) != 1 || found[0].Severity != "critical" {
+		t.Fatal("expected the cves from disk but got ", found, err)
+	}
+	if len(images.GetCalls()) != 0 || len(versions.AvailableTagsSortedByDateCalls()) != 0 || len(cves.CVESCalls()) != 0 {
+		t.Fatal("expected no lookups when the cache dir is populated")
+	}
+
+	// expired entries on disk are looked up again
+	images, _, _ = newMocks()
+	expired := registry.NewCachedImageGetter(images, -time.Second, 10, dir)
+	expired.Get(context.TODO(), "org/image:2.0")
+	expired.Get(context.TODO(), "org/image:2.0")
+	if len(images.GetCalls()) != 2 {
+		t.Fatal("expected expired entries to be looked up again but got ", len(images.GetCalls()), " calls")
+	}
+}
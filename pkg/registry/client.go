@@ -1,12 +1,14 @@
 package registry
 
 import (
+	"context"
 	"fmt"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/integr8ly/heimdall/pkg/customMetrics"
 	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/transport"
 	"github.com/pkg/errors"
 	"os"
 )
@@ -16,13 +18,13 @@ type Client struct {
 	Auth string
 }
 
-func (c *Client) Get(r string) (*domain.RemoteImageDigest, error) {
+func (c *Client) Get(ctx context.Context, r string) (*domain.RemoteImageDigest, error) {
 	remote.WithAuth(c)
 	ref, err := name.ParseReference(r)
 	if err != nil {
 		return nil, fmt.Errorf("parsing reference %q: %v", r, err)
 	}
-	img, err := remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	img, err := remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(transport.WithContext(ctx, transport.Shared())))
 	customMetrics.RegistryCallsTotal.Inc()
 	if err != nil {
 		customMetrics.RegistryCallsFailure.Inc()
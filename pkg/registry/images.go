@@ -1,11 +1,16 @@
 package registry
 
 import (
-	"fmt"
+	"context"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
 	"github.com/integr8ly/heimdall/pkg/domain"
 	"github.com/integr8ly/heimdall/pkg/rhcc"
 	"github.com/pkg/errors"
-	"regexp"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
 
@@ -13,23 +18,29 @@ var log = logf.Log.WithName("registry")
 
 //go:generate moq -out ImageGetter_moq.go . ImageGetter
 type ImageGetter interface {
-	Get(string) (*domain.RemoteImageDigest, error)
+	Get(ctx context.Context, ref string) (*domain.RemoteImageDigest, error)
 }
 
 //go:generate moq -out ImageVersionsGetter_moq.go . ImageVersionsGetter
 type ImageVersionsGetter interface {
-	AvailableTagsSortedByDate(string) ([]rhcc.Tag, error)
+	AvailableTagsSortedByDate(ctx context.Context, repo string) ([]rhcc.Tag, error)
 }
 
 //go:generate moq -out ImageCVEGetter_moq.go . ImageCVEGetter
 type ImageCVEGetter interface {
-	CVES(org, tag string) ([]domain.CVE, error)
+	CVES(ctx context.Context, org, tag string) ([]domain.CVE, error)
 }
 
 type ImageService struct {
 	imageGetter    ImageGetter
 	versionsGetter ImageVersionsGetter
 	cveGetter      ImageCVEGetter
+	sources        *Sources
+	workers        int
+	checkTimeout   time.Duration
+	// slots limits the tag digests fetched at once by all the checks
+	slots   chan struct{}
+	slotsMu sync.Mutex
 }
 
 func NewImagesService(imageGetter ImageGetter, versGetter ImageVersionsGetter, cveGetter ImageCVEGetter) *ImageService {
@@ -46,12 +57,111 @@ func NewImagesService(imageGetter ImageGetter, versGetter ImageVersionsGetter, c
 	return is
 }
 
+// WithSources uses the sources registered for an image's registry host in place of the Red Hat container catalog
+func (i *ImageService) WithSources(sources *Sources) *ImageService {
+	i.sources = sources
+	return i
+}
+
+// WithCache caches the digests, tags and CVEs looked up by the service and its sources. If dir is not empty the cache
+// is also persisted there so it survives restarts. It should be called after WithSources.
+func (i *ImageService) WithCache(ttl time.Duration, size int, dir string) *ImageService {
+	i.imageGetter = NewCachedImageGetter(i.imageGetter, ttl, size, dir)
+	i.versionsGetter = NewCachedImageVersionsGetter(i.versionsGetter, ttl, size, dir)
+	i.cveGetter = NewCachedImageCVEGetter(i.cveGetter, ttl, size, dir)
+	if i.sources != nil {
+		for j := range i.sources.sources {
+			s := &i.sources.sources[j].source
+			// sources are keyed on the full repository so they can share the directory with the rhcc lookups
+			s.Versions = NewCachedImageVersionsGetter(s.Versions, ttl, size, dir)
+			s.CVEs = NewCachedImageCVEGetter(s.CVEs, ttl, size, dir)
+		}
+	}
+	return i
+}
+
+var (
+	defaultImageService     *ImageService
+	defaultImageServiceErr  error
+	defaultImageServiceOnce sync.Once
+)
+
+// DefaultImagesService returns the ImageService shared by all of the controllers so they share a single cache. It is
+// backed by the registry and the Red Hat container catalog with any additional sources configured in the environment.
+func DefaultImagesService() (*ImageService, error) {
+	defaultImageServiceOnce.Do(func() {
+		defaultImageService, defaultImageServiceErr = newImagesServiceFromEnv()
+	})
+	return defaultImageService, defaultImageServiceErr
+}
+
+func newImagesServiceFromEnv() (*ImageService, error) {
+	sources, err := SourcesFromEnv()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to configure image sources")
+	}
+	rhccClient, err := rhcc.ClientFromEnv()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to configure the rhcc client")
+	}
+	ttl, size, err := cacheSettingsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	workers, checkTimeout, err := workerSettingsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewImagesService(&Client{}, rhccClient, rhccClient).
+		WithSources(sources).
+		WithCache(ttl, size, "").
+		WithWorkers(workers).
+		WithCheckTimeout(checkTimeout), nil
+}
+
+// cacheSettingsFromEnv reads the cache ttl and size from HEIMDALL_CACHE_TTL and HEIMDALL_CACHE_SIZE
+func cacheSettingsFromEnv() (time.Duration, int, error) {
+	ttl, size := DefaultCacheTTL, DefaultCacheSize
+	if v := os.Getenv(EnvCacheTTL); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "failed to parse "+EnvCacheTTL)
+		}
+		ttl = d
+	}
+	if v := os.Getenv(EnvCacheSize); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "failed to parse "+EnvCacheSize)
+		}
+		if n < 1 {
+			log.Info("ignoring invalid "+EnvCacheSize+", using the default", "size", n, "default", DefaultCacheSize)
+			n = DefaultCacheSize
+		}
+		size = n
+	}
+	return ttl, size, nil
+}
+
+// lookup returns the getters to use for tags and CVEs along with the repository to pass them
+func (i *ImageService) lookup(image *domain.ClusterImage) (ImageVersionsGetter, ImageCVEGetter, string) {
+	if s, ok := i.sources.For(image.RegistryHost()); ok {
+		return s.Versions, s.CVEs, image.RegistryPath
+	}
+	return i.versionsGetter, i.cveGetter, image.OrgImagePath
+}
+
+// Digest looks up the digest of the image reference in its registry, using the cache when there is one
+func (i *ImageService) Digest(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+	return i.imageGetter.Get(ctx, ref)
+}
+
 type registryDigest struct {
 	TagDigest string
 	SHADigest string
 }
 
-func (i *ImageService) clusterImageRegistryDigests(image *domain.ClusterImage) (registryDigest, error) {
+func (i *ImageService) clusterImageRegistryDigests(ctx context.Context, image *domain.ClusterImage) (registryDigest, error) {
 
 	var (
 		clusterImageSHAHash, clusterImageTagHash string
@@ -60,7 +170,7 @@ func (i *ImageService) clusterImageRegistryDigests(image *domain.ClusterImage) (
 	// Even though we have a SHA, if it is not from an imagestream the digest in the container seems to be calculated differently. However when we ask
 	// the registry for the image that matches the digest in the container, it gives us back an image and that digest will match the tag being used.
 	if !image.FromImageStream {
-		clusterSHAImage, err := i.imageGetter.Get(image.SHA256Path)
+		clusterSHAImage, err := i.imageGetter.Get(ctx, image.SHA256Path)
 		if err != nil {
 			return registryDigest{}, errors.Wrap(err, "failed to get correct hash for image "+image.SHA256Path)
 		}
@@ -69,7 +179,7 @@ func (i *ImageService) clusterImageRegistryDigests(image *domain.ClusterImage) (
 		clusterImageSHAHash = image.GetSHAFromPath()
 	}
 
-	clusterTagImage, err := i.imageGetter.Get(image.FullPath)
+	clusterTagImage, err := i.imageGetter.Get(ctx, image.PulledFullPath())
 	if err != nil {
 		return registryDigest{}, errors.Wrap(err, "failed to get image details from registry")
 	}
@@ -83,17 +193,18 @@ func (i *ImageService) clusterImageRegistryDigests(image *domain.ClusterImage) (
 
 // Check takes the cluster image and runs through a set of checks to find out whether the image in the cluster is
 // up to date with the image in the registry, whether there is a new patch image available and also figures out which
-// CVEs would be fixed by updating.
-func (i *ImageService) Check(image *domain.ClusterImage) (domain.ReportResult, error) {
+// CVEs would be fixed by updating. The check is abandoned if ctx is done.
+func (i *ImageService) Check(ctx context.Context, image *domain.ClusterImage) (domain.ReportResult, error) {
 	// get the registry image details based on the image we found
-	fmt.Println("checking image : " + image.FullPath)
+	log.Info("checking image : " + image.FullPath)
 	result := domain.ReportResult{}
 	result.ClusterImage = image
-	clusterImageDigests, err := i.clusterImageRegistryDigests(image)
+	clusterImageDigests, err := i.clusterImageRegistryDigests(ctx, image)
 	if err != nil {
 		return result, errors.Wrap(err, " failed to disover the cluster image SHA ")
 	}
-	tags, err := i.versionsGetter.AvailableTagsSortedByDate(image.OrgImagePath)
+	versionsGetter, _, repo := i.lookup(image)
+	tags, err := versionsGetter.AvailableTagsSortedByDate(ctx, repo)
 	if err != nil {
 		return result, errors.Wrap(err, "failed to get available image tags")
 	}
@@ -104,7 +215,11 @@ func (i *ImageService) Check(image *domain.ClusterImage) (domain.ReportResult, e
 	result.UsingFloatingTag = usingFloatingTag
 	result.ActualImageRef = image.FullPath
 	result.UpToDateWithOwnTag = clusterImageDigests.TagDigest == clusterImageDigests.SHADigest
-	floatingTagImage, err := i.imageGetter.Get(image.RegistryPath + ":" + result.FloatingTag)
+	if result.FloatingTag == "" {
+		// registries without a floating tag for this version, compare against the tag in use
+		result.FloatingTag = image.Tag
+	}
+	floatingTagImage, err := i.imageGetter.Get(ctx, image.PulledRegistryPath()+":"+result.FloatingTag)
 	if err != nil {
 		return result, errors.Wrap(err, "failed to get floating tag image from registry")
 	}
@@ -122,21 +237,28 @@ func (i *ImageService) Check(image *domain.ClusterImage) (domain.ReportResult, e
 		result.LatestGrade = nextTag.FreshnessGrade
 
 	} else {
-		// need to find the actual persistent tag for this floating tag
+		// need to find the actual persistent tag for this floating tag. The candidate tags are walked in date order
+		// and their digests fetched in batches until one matches
+		var candidates []string
+		for _, t := range tags {
+			if majorMinorVersion == "" || regexp.MustCompile("^v?"+majorMinorVersion+"(\\W)+").MatchString(t.Name) {
+				candidates = append(candidates, t.Name)
+			}
+		}
+		digests := i.tagDigests(image.PulledRegistryPath(), candidates)
 		for j, t := range tags {
 			if majorMinorVersion != "" {
 				// this is an optimisation to reduce calls to the registry if we fail to find a valid major minor version we still need to
 				// go through all the tags till we find the right one
 				mr := regexp.MustCompile("^v?" + majorMinorVersion + "(\\W)+")
 				if !mr.MatchString(t.Name) {
-					fmt.Println("skipping tag ", t.Name, " as it does not match on major minor patch version "+majorMinorVersion+".*")
 					log.Info("skipping tag ", t.Name, " as it does not match on major minor patch version "+majorMinorVersion+".*")
 					continue
 				}
 			}
-			registryTagImage, err := i.imageGetter.Get(image.RegistryPath + ":" + t.Name)
+			registryTagImage, err := digests.digest(ctx, t.Name)
 			if err != nil {
-				return result, errors.Wrap(err, "failed to get image details from registry for image "+image.RegistryPath+":"+t.Name)
+				return result, errors.Wrap(err, "failed to get image details from registry for image "+image.PulledRegistryPath()+":"+t.Name)
 			}
 			if registryTagImage.Hash == clusterImageDigests.SHADigest {
 				if t.Name == "latest" && len(tags) > 1 {
@@ -174,7 +296,7 @@ func (i *ImageService) Check(image *domain.ClusterImage) (domain.ReportResult, e
 	if result.LatestAvailablePatchVersion == result.CurrentVersion {
 		return result, nil
 	}
-	resolvableCVEs, err := i.getResolvableCVEs(image, result.LatestAvailablePatchVersion, result.CurrentVersion)
+	resolvableCVEs, err := i.getResolvableCVEs(ctx, image, result.LatestAvailablePatchVersion, result.CurrentVersion)
 	if err != nil {
 		return result, err
 	}
@@ -197,12 +319,13 @@ func findNextPatchImage(tags []rhcc.Tag, majorMinorVersion string) (rhcc.Tag, er
 	return tags[len(tags)-1], nil
 }
 
-func (i *ImageService) getResolvableCVEs(image *domain.ClusterImage, latestPatchVersion, currentVersion string) ([]domain.CVE, error) {
-	latestImageCVEs, err := i.cveGetter.CVES(image.OrgImagePath, latestPatchVersion)
+func (i *ImageService) getResolvableCVEs(ctx context.Context, image *domain.ClusterImage, latestPatchVersion, currentVersion string) ([]domain.CVE, error) {
+	_, cveGetter, repo := i.lookup(image)
+	latestImageCVEs, err := cveGetter.CVES(ctx, repo, latestPatchVersion)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get CVEs affecting latest image tag "+latestPatchVersion)
 	}
-	currentImageCVEs, err := i.cveGetter.CVES(image.OrgImagePath, currentVersion)
+	currentImageCVEs, err := cveGetter.CVES(ctx, repo, currentVersion)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get CVEs affecting current image tag "+currentVersion)
 	}
@@ -1,6 +1,7 @@
 package registry_test
 
 import (
+	"context"
 	"errors"
 	"github.com/integr8ly/heimdall/pkg/cluster"
 	"github.com/integr8ly/heimdall/pkg/domain"
@@ -29,7 +30,7 @@ func TestImageService_Check(t *testing.T) {
 			ImageStream: false,
 			ImageGetter: func() registry.ImageGetter {
 				return &registry.ImageGetterMock{
-					GetFunc: func(in1 string) (digest *domain.RemoteImageDigest, e error) {
+					GetFunc: func(ctx context.Context, in1 string) (digest *domain.RemoteImageDigest, e error) {
 						if !strings.Contains(in1, "someotherhash2") && !strings.Contains(in1, "2.0") && !strings.Contains(in1, "latest") {
 							return nil, errors.New("did not expect to be called for tag " + in1)
 						}
@@ -40,7 +41,7 @@ func TestImageService_Check(t *testing.T) {
 			},
 			VersionGetter: func() registry.ImageVersionsGetter {
 				return &registry.ImageVersionsGetterMock{
-					AvailableTagsSortedByDateFunc: func(in1 string) (strings []rhcc.Tag, e error) {
+					AvailableTagsSortedByDateFunc: func(ctx context.Context, in1 string) (strings []rhcc.Tag, e error) {
 						// we return them in order as this is how we will receive them
 						//{20191111T07:52:14.056-0500 1.0 [{floating}]} {20191111T07:52:14.056-0500 latest [{floating}]} {20191111T07:52:14.056-0500 1.0-15.1571241898 [{persistent}]}
 						return []rhcc.Tag{
@@ -53,7 +54,7 @@ func TestImageService_Check(t *testing.T) {
 			},
 			CVEGetter: func() registry.ImageCVEGetter {
 				return &registry.ImageCVEGetterMock{
-					CVESFunc: func(org string, tag string) (cves []domain.CVE, e error) {
+					CVESFunc: func(ctx context.Context, org string, tag string) (cves []domain.CVE, e error) {
 						return []domain.CVE{}, nil
 					},
 				}
@@ -77,7 +78,7 @@ func TestImageService_Check(t *testing.T) {
 			ImageStream: false,
 			ImageGetter: func() registry.ImageGetter {
 				return &registry.ImageGetterMock{
-					GetFunc: func(in1 string) (digest *domain.RemoteImageDigest, e error) {
+					GetFunc: func(ctx context.Context, in1 string) (digest *domain.RemoteImageDigest, e error) {
 						if !strings.Contains(in1, "someotherhash2") && !strings.Contains(in1, "2.0") {
 							return nil, errors.New("did not expect to be called for tag " + in1)
 						}
@@ -88,7 +89,7 @@ func TestImageService_Check(t *testing.T) {
 			},
 			VersionGetter: func() registry.ImageVersionsGetter {
 				return &registry.ImageVersionsGetterMock{
-					AvailableTagsSortedByDateFunc: func(in1 string) (strings []rhcc.Tag, e error) {
+					AvailableTagsSortedByDateFunc: func(ctx context.Context, in1 string) (strings []rhcc.Tag, e error) {
 						// we return them in order as this is how we will receive them
 						//{20191111T07:52:14.056-0500 1.0 [{floating}]} {20191111T07:52:14.056-0500 latest [{floating}]} {20191111T07:52:14.056-0500 1.0-15.1571241898 [{persistent}]}
 						return []rhcc.Tag{
@@ -100,7 +101,7 @@ func TestImageService_Check(t *testing.T) {
 			},
 			CVEGetter: func() registry.ImageCVEGetter {
 				return &registry.ImageCVEGetterMock{
-					CVESFunc: func(org string, tag string) (cves []domain.CVE, e error) {
+					CVESFunc: func(ctx context.Context, org string, tag string) (cves []domain.CVE, e error) {
 						return []domain.CVE{}, nil
 					},
 				}
@@ -124,7 +125,7 @@ func TestImageService_Check(t *testing.T) {
 			ImageStream: false,
 			ImageGetter: func() registry.ImageGetter {
 				return &registry.ImageGetterMock{
-					GetFunc: func(in1 string) (digest *domain.RemoteImageDigest, e error) {
+					GetFunc: func(ctx context.Context, in1 string) (digest *domain.RemoteImageDigest, e error) {
 						if !strings.Contains(in1, "someotherhash2") && !strings.Contains(in1, "2.0") {
 							return nil, errors.New("did not expect to be called for tag " + in1)
 						}
@@ -135,7 +136,7 @@ func TestImageService_Check(t *testing.T) {
 			},
 			VersionGetter: func() registry.ImageVersionsGetter {
 				return &registry.ImageVersionsGetterMock{
-					AvailableTagsSortedByDateFunc: func(in1 string) (strings []rhcc.Tag, e error) {
+					AvailableTagsSortedByDateFunc: func(ctx context.Context, in1 string) (strings []rhcc.Tag, e error) {
 						// we return them in order as this is how we will receive them
 						//{20191111T07:52:14.056-0500 1.0 [{floating}]} {20191111T07:52:14.056-0500 latest [{floating}]} {20191111T07:52:14.056-0500 1.0-15.1571241898 [{persistent}]}
 						return []rhcc.Tag{
@@ -148,7 +149,7 @@ func TestImageService_Check(t *testing.T) {
 			},
 			CVEGetter: func() registry.ImageCVEGetter {
 				return &registry.ImageCVEGetterMock{
-					CVESFunc: func(org string, tag string) (cves []domain.CVE, e error) {
+					CVESFunc: func(ctx context.Context, org string, tag string) (cves []domain.CVE, e error) {
 						return []domain.CVE{}, nil
 					},
 				}
@@ -172,7 +173,7 @@ func TestImageService_Check(t *testing.T) {
 			ImageStream: true,
 			ImageGetter: func() registry.ImageGetter {
 				return &registry.ImageGetterMock{
-					GetFunc: func(in1 string) (digest *domain.RemoteImageDigest, e error) {
+					GetFunc: func(ctx context.Context, in1 string) (digest *domain.RemoteImageDigest, e error) {
 						if strings.Contains(in1, "1.0.1") || strings.Contains(in1, "1.0.2") {
 							return &domain.RemoteImageDigest{Hash: "somehash", Algorithm: "sha256"}, nil
 						}
@@ -182,7 +183,7 @@ func TestImageService_Check(t *testing.T) {
 			},
 			VersionGetter: func() registry.ImageVersionsGetter {
 				return &registry.ImageVersionsGetterMock{
-					AvailableTagsSortedByDateFunc: func(in1 string) (strings []rhcc.Tag, e error) {
+					AvailableTagsSortedByDateFunc: func(ctx context.Context, in1 string) (strings []rhcc.Tag, e error) {
 						// we return them in order as this is how we will receive them
 						return []rhcc.Tag{{Name: "1.0.2", Added: "20191126T09:53:00.000-0500", TimeAdded: 2, Type: "persistent"}, {Name: "1.0.1", Added: "20191125T09:53:00.000-0500", TimeAdded: 1, Type: "persistent"}, {Name: "1.0.0", Added: "20191124T09:53:00.000-0500", TimeAdded: 0, Type: "floating"}}, nil
 					},
@@ -190,7 +191,7 @@ func TestImageService_Check(t *testing.T) {
 			},
 			CVEGetter: func() registry.ImageCVEGetter {
 				return &registry.ImageCVEGetterMock{
-					CVESFunc: func(org string, tag string) (cves []domain.CVE, e error) {
+					CVESFunc: func(ctx context.Context, org string, tag string) (cves []domain.CVE, e error) {
 						if tag == "1.0.0" {
 							return []domain.CVE{{
 								Severity:   "minor",
@@ -233,7 +234,7 @@ func TestImageService_Check(t *testing.T) {
 			ImageStream: true,
 			ImageGetter: func() registry.ImageGetter {
 				return &registry.ImageGetterMock{
-					GetFunc: func(in1 string) (digest *domain.RemoteImageDigest, e error) {
+					GetFunc: func(ctx context.Context, in1 string) (digest *domain.RemoteImageDigest, e error) {
 						if !strings.Contains(in1, "1.11-27.1578407517") {
 							return &domain.RemoteImageDigest{Hash: "somehash", Algorithm: "sha256"}, nil
 						}
@@ -243,7 +244,7 @@ func TestImageService_Check(t *testing.T) {
 			},
 			VersionGetter: func() registry.ImageVersionsGetter {
 				return &registry.ImageVersionsGetterMock{
-					AvailableTagsSortedByDateFunc: func(in1 string) (strings []rhcc.Tag, e error) {
+					AvailableTagsSortedByDateFunc: func(ctx context.Context, in1 string) (strings []rhcc.Tag, e error) {
 						// we return them in order as this is how we will receive them
 						return []rhcc.Tag{{Name: "1.11", Added: "20200119T23:03:30.187-0500", TimeAdded: 1579493010, Type: "floating"}, {Name: "3scale2.7.1", Added: "20200119T23:03:20.774-0500", TimeAdded: 1579493000, Type: "floating"}, {Name: "1.11-27.1579183773", Added: "20200119T23:03:30.187-0500", TimeAdded: 1579493010},
 							{Name: "1.11-27.1578407517", Added: "20200119T23:03:25.000-0500", TimeAdded: 1579493005, Type: "persistent"}}, nil
@@ -252,7 +253,7 @@ func TestImageService_Check(t *testing.T) {
 			},
 			CVEGetter: func() registry.ImageCVEGetter {
 				return &registry.ImageCVEGetterMock{
-					CVESFunc: func(org string, tag string) (cves []domain.CVE, e error) {
+					CVESFunc: func(ctx context.Context, org string, tag string) (cves []domain.CVE, e error) {
 						if tag == "1.11-27.1578407517" {
 							return []domain.CVE{{
 								Severity:   "minor",
@@ -294,10 +295,10 @@ func TestImageService_Check(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
 			is := registry.NewImagesService(tc.ImageGetter(), tc.VersionGetter(), tc.CVEGetter())
-			img := cluster.ParseImage(tc.Image)
+			img := cluster.MustParseImage(tc.Image)
 			img.SHA256Path = tc.SHAImage
 			img.FromImageStream = tc.ImageStream
-			result, err := is.Check(img)
+			result, err := is.Check(context.TODO(), img)
 			if tc.ExpectError && err == nil {
 				t.Fatal("expected an error but did not get one")
 			}
@@ -310,3 +311,126 @@ func TestImageService_Check(t *testing.T) {
 		})
 	}
 }
+
+func TestImageService_CheckWithSources(t *testing.T) {
+	var versionRepos, cveRepos []string
+	sources := registry.NewSources()
+	err := sources.Register("^quay\\.io$", registry.Source{
+		Versions: &registry.ImageVersionsGetterMock{
+			AvailableTagsSortedByDateFunc: func(ctx context.Context, repo string) ([]rhcc.Tag, error) {
+				versionRepos = append(versionRepos, repo)
+				return []rhcc.Tag{{Name: "latest", Type: "floating"}, {Name: "1.0.1", Type: "persistent"}, {Name: "1.0.0", Type: "persistent"}}, nil
+			},
+		},
+		CVEs: &registry.ImageCVEGetterMock{
+			CVESFunc: func(ctx context.Context, repo string, tag string) ([]domain.CVE, error) {
+				cveRepos = append(cveRepos, repo)
+				if tag == "1.0.0" {
+					return []domain.CVE{{ID: "CVE-1", Severity: "critical"}}, nil
+				}
+				return nil, nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal("did not expect an error registering the source ", err)
+	}
+	rhccCalled := false
+	is := registry.NewImagesService(&registry.ImageGetterMock{
+		GetFunc: func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+			if strings.HasSuffix(ref, ":1.0.0") || strings.Contains(ref, "@sha256") {
+				return &domain.RemoteImageDigest{Hash: "current", Algorithm: "sha256"}, nil
+			}
+			return &domain.RemoteImageDigest{Hash: "newer", Algorithm: "sha256"}, nil
+		},
+	}, &registry.ImageVersionsGetterMock{
+		AvailableTagsSortedByDateFunc: func(ctx context.Context, repo string) ([]rhcc.Tag, error) {
+			rhccCalled = true
+			return nil, errors.New("rhcc should not be used for quay.io images")
+		},
+	}, &registry.ImageCVEGetterMock{
+		CVESFunc: func(ctx context.Context, org string, tag string) ([]domain.CVE, error) {
+			rhccCalled = true
+			return nil, errors.New("rhcc should not be used for quay.io images")
+		},
+	}).WithSources(sources)
+
+	img := cluster.MustParseImage("quay.io/org/image:1.0.0")
+	img.SHA256Path = "quay.io/org/image@sha256:current"
+	result, err := is.Check(context.TODO(), img)
+	if err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	if rhccCalled {
+		t.Fatal("expected the registered source to be used in place of rhcc")
+	}
+	if result.CurrentVersion != "1.0.0" || result.LatestAvailablePatchVersion != "1.0.1" {
+		t.Fatal("expected current version 1.0.0 and latest 1.0.1 but got ", result.CurrentVersion, result.LatestAvailablePatchVersion)
+	}
+	if len(result.ResolvableCVEs) != 1 {
+		t.Fatal("expected one resolvable cve but got ", result.ResolvableCVEs)
+	}
+	for _, repo := range append(versionRepos, cveRepos...) {
+		if repo != "quay.io/org/image" {
+			t.Fatal("expected the source to be called with the full repository but got ", repo)
+		}
+	}
+}
+
+func TestImageService_CheckMirroredImage(t *testing.T) {
+	policy, err := registry.NewPolicy(nil, nil, []registry.Mirror{{Source: "registry.redhat.io/amq7", Mirrors: []string{"mirror.local:5000/amq7"}}})
+	if err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	var digestRefs, versionRepos, cveRepos []string
+	is := registry.NewImagesService(&registry.ImageGetterMock{
+		GetFunc: func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+			digestRefs = append(digestRefs, ref)
+			if strings.HasSuffix(ref, ":2.0.3") || strings.Contains(ref, "@sha256") {
+				return &domain.RemoteImageDigest{Hash: "current", Algorithm: "sha256"}, nil
+			}
+			return &domain.RemoteImageDigest{Hash: "newer", Algorithm: "sha256"}, nil
+		},
+	}, &registry.ImageVersionsGetterMock{
+		AvailableTagsSortedByDateFunc: func(ctx context.Context, repo string) ([]rhcc.Tag, error) {
+			versionRepos = append(versionRepos, repo)
+			return []rhcc.Tag{
+				{Name: "2.0.4", Type: "persistent"},
+				{Name: "2.0", Type: "floating"},
+				{Name: "2.0.3", Type: "persistent"},
+			}, nil
+		},
+	}, &registry.ImageCVEGetterMock{
+		CVESFunc: func(ctx context.Context, repo string, tag string) ([]domain.CVE, error) {
+			cveRepos = append(cveRepos, repo)
+			return nil, nil
+		},
+	})
+
+	// the floating tag is in use so the digests of the candidate tags are looked up too
+	img := cluster.MustParseImage("mirror.local:5000/amq7/amq-online-1-api-server:2.0")
+	img.SHA256Path = "mirror.local:5000/amq7/amq-online-1-api-server@sha256:current"
+	result, err := is.Check(context.TODO(), policy.Resolve(img))
+	if err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	if result.CurrentVersion != "2.0.3" || result.LatestAvailablePatchVersion != "2.0.4" {
+		t.Fatal("expected current version 2.0.3 and latest 2.0.4 but got ", result.CurrentVersion, result.LatestAvailablePatchVersion)
+	}
+	if len(digestRefs) == 0 {
+		t.Fatal("expected digests to be looked up")
+	}
+	for _, ref := range digestRefs {
+		if !strings.HasPrefix(ref, "mirror.local:5000/amq7/amq-online-1-api-server") {
+			t.Fatal("expected the digests to be looked up in the mirror but got ", ref)
+		}
+	}
+	for _, repo := range append(versionRepos, cveRepos...) {
+		if repo != "amq7/amq-online-1-api-server" {
+			t.Fatal("expected the catalogue to be called with the upstream repository but got ", repo)
+		}
+	}
+	if len(cveRepos) == 0 {
+		t.Fatal("expected the cves to be looked up")
+	}
+}
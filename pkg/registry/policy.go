@@ -0,0 +1,195 @@
+package registry
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/pkg/errors"
+)
+
+// DefaultIncludePattern matches the Red Hat registries which were the only registries checked before policies existed
+const DefaultIncludePattern = "redhat"
+
+// Mirror maps repositories mirrored from Source back to it in the same way as an ImageContentSourcePolicy
+type Mirror struct {
+	Source  string
+	Mirrors []string
+}
+
+// Policy decides which images are checked based on the host of the registry they come from and resolves images
+// pulled from a mirror back to the upstream repository so tags and CVEs are looked up against the source.
+type Policy struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	mirrors []Mirror
+	// containers and images that are skipped regardless of their registry
+	excludeContainers []*regexp.Regexp
+	excludeImages     []*regexp.Regexp
+}
+
+// PolicyGetter returns the policy to apply to images found in a namespace
+type PolicyGetter interface {
+	PolicyFor(ctx context.Context, namespace string) (*Policy, error)
+}
+
+// NewPolicy creates a Policy from include and exclude registry host patterns. An image is checked if its host matches
+// any include pattern and no exclude pattern. If no include patterns are given DefaultIncludePattern is used.
+func NewPolicy(include, exclude []string, mirrors []Mirror) (*Policy, error) {
+	if len(include) == 0 {
+		include = []string{DefaultIncludePattern}
+	}
+	p := &Policy{mirrors: mirrors}
+	for _, i := range include {
+		reg, err := regexp.Compile(i)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to compile registry include pattern "+i)
+		}
+		p.include = append(p.include, reg)
+	}
+	for _, e := range exclude {
+		reg, err := regexp.Compile(e)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to compile registry exclude pattern "+e)
+		}
+		p.exclude = append(p.exclude, reg)
+	}
+	return p, nil
+}
+
+// DefaultPolicy only checks images from the Red Hat registries
+func DefaultPolicy() *Policy {
+	p, _ := NewPolicy(nil, nil, nil)
+	return p
+}
+
+// WithExclusions returns a copy of the policy that also skips the containers with a name matching any of the container
+// patterns and the images with a full path matching any of the image patterns
+func (p *Policy) WithExclusions(containers, images []string) (*Policy, error) {
+	excluding := *p
+	excluding.excludeContainers = append([]*regexp.Regexp{}, p.excludeContainers...)
+	excluding.excludeImages = append([]*regexp.Regexp{}, p.excludeImages...)
+	for _, c := range containers {
+		reg, err := regexp.Compile(c)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to compile container exclude pattern "+c)
+		}
+		excluding.excludeContainers = append(excluding.excludeContainers, reg)
+	}
+	for _, i := range images {
+		reg, err := regexp.Compile(i)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to compile image exclude pattern "+i)
+		}
+		excluding.excludeImages = append(excluding.excludeImages, reg)
+	}
+	return &excluding, nil
+}
+
+// Exclude returns a copy of the image without the containers skipped by the policy. Nil is returned when the image is
+// skipped or it is only used by skipped containers. Images should be excluded before they are resolved.
+func (p *Policy) Exclude(image *domain.ClusterImage) *domain.ClusterImage {
+	if matchesAny(p.excludeImages, image.FullPath) {
+		return nil
+	}
+	if len(p.excludeContainers) == 0 || len(image.Pods) == 0 {
+		return image
+	}
+	included := *image
+	included.Pods = nil
+	for _, pod := range image.Pods {
+		var containers []string
+		for _, c := range pod.Containers {
+			if !matchesAny(p.excludeContainers, c) {
+				containers = append(containers, c)
+			}
+		}
+		if len(containers) > 0 {
+			pod.Containers = containers
+			included.Pods = append(included.Pods, pod)
+		}
+	}
+	if len(included.Pods) == 0 {
+		return nil
+	}
+	return &included
+}
+
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, p := range patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checked returns whether an image found in a workload is checked: it is used by a container that is not excluded and
+// the registry it resolves to is allowed. It lets images be skipped before their digest is looked up.
+func (p *Policy) Checked(image *domain.ClusterImage) bool {
+	included := p.Exclude(image)
+	return included != nil && p.Allowed(p.Resolve(included))
+}
+
+// Filter returns the images that are checked without the containers skipped by the policy and resolved to the
+// repository they are checked against, in the order given
+func (p *Policy) Filter(images []*domain.ClusterImage) []*domain.ClusterImage {
+	var checked []*domain.ClusterImage
+	for _, image := range images {
+		if image = p.Exclude(image); image == nil {
+			continue
+		}
+		if image = p.Resolve(image); p.Allowed(image) {
+			checked = append(checked, image)
+		}
+	}
+	return checked
+}
+
+// PolicyFor allows a single Policy to be used for every namespace
+func (p *Policy) PolicyFor(ctx context.Context, namespace string) (*Policy, error) {
+	return p, nil
+}
+
+// Allowed returns whether the image should be checked. Images should be resolved before checking if they are allowed.
+func (p *Policy) Allowed(image *domain.ClusterImage) bool {
+	host := image.RegistryHost()
+	for _, e := range p.exclude {
+		if e.MatchString(host) {
+			return false
+		}
+	}
+	for _, i := range p.include {
+		if i.MatchString(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns a copy of the image pointing at the upstream repository if it was pulled from a mirror, otherwise the
+// image is returned as is. The SHA256Path still refers to the mirror as that is what is running in the cluster and
+// mirrors serve the same content so the digest is unchanged.
+func (p *Policy) Resolve(image *domain.ClusterImage) *domain.ClusterImage {
+	for _, m := range p.mirrors {
+		for _, mirror := range m.Mirrors {
+			mirror = strings.TrimSuffix(mirror, "/")
+			if image.RegistryPath != mirror && !strings.HasPrefix(image.RegistryPath, mirror+"/") {
+				continue
+			}
+			source := strings.TrimSuffix(m.Source, "/")
+			resolved := *image
+			resolved.MirrorPath = image.RegistryPath
+			resolved.RegistryPath = source + strings.TrimPrefix(image.RegistryPath, mirror)
+			resolved.FullPath = source + strings.TrimPrefix(image.FullPath, mirror)
+			pathParts := strings.Split(resolved.RegistryPath, "/")
+			resolved.OrgImagePath = strings.Join(pathParts[1:], "/")
+			if len(pathParts) > 1 {
+				resolved.Org = pathParts[1]
+			}
+			return &resolved
+		}
+	}
+	return image
+}
@@ -0,0 +1,208 @@
+package registry_test
+
+import (
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/registry"
+)
+
+func TestPolicy(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Include       []string
+		Exclude       []string
+		Mirrors       []registry.Mirror
+		Image         string
+		ExpectAllowed bool
+		ExpectOrgPath string
+	}{
+		{
+			Name:          "test default policy allows the redhat registries",
+			Image:         "registry.redhat.io/amq7/amq-online-1-api-server:2.0.0",
+			ExpectAllowed: true,
+			ExpectOrgPath: "amq7/amq-online-1-api-server",
+		},
+		{
+			Name:          "test default policy skips other registries",
+			Image:         "quay.io/org/image:1.0",
+			ExpectAllowed: false,
+			ExpectOrgPath: "org/image",
+		},
+		{
+			Name:          "test exclude takes precedence over include",
+			Include:       []string{".*"},
+			Exclude:       []string{"^quay\\.io$"},
+			Image:         "quay.io/org/image:1.0",
+			ExpectAllowed: false,
+			ExpectOrgPath: "org/image",
+		},
+		{
+			Name:          "test mirrored image is resolved to its source",
+			Mirrors:       []registry.Mirror{{Source: "registry.redhat.io/amq7", Mirrors: []string{"mirror.local:5000/amq7"}}},
+			Image:         "mirror.local:5000/amq7/amq-online-1-api-server:2.0.0",
+			ExpectAllowed: true,
+			ExpectOrgPath: "amq7/amq-online-1-api-server",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			p, err := registry.NewPolicy(tc.Include, tc.Exclude, tc.Mirrors)
+			if err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			img := cluster.MustParseImage(tc.Image)
+			img.SHA256Path = img.RegistryPath + "@sha256:hash"
+			resolved := p.Resolve(img)
+			if p.Allowed(resolved) != tc.ExpectAllowed {
+				t.Fatal("expected allowed to be ", tc.ExpectAllowed, " for ", resolved.FullPath)
+			}
+			if resolved.OrgImagePath != tc.ExpectOrgPath {
+				t.Fatal("expected org image path ", tc.ExpectOrgPath, " but got ", resolved.OrgImagePath)
+			}
+			if resolved.SHA256Path != img.SHA256Path {
+				t.Fatal("expected the sha path to be unchanged but got ", resolved.SHA256Path)
+			}
+		})
+	}
+}
+
+func TestPolicy_Exclude(t *testing.T) {
+	image := func() *domain.ClusterImage {
+		img := cluster.MustParseImage("registry.redhat.io/openshift4/ose-oauth-proxy:4.1")
+		img.Pods = []domain.PodAndContainerRef{
+			{Name: "server-1", Containers: []string{"server", "oauth-proxy"}},
+			{Name: "proxy-1", Containers: []string{"oauth-proxy"}},
+		}
+		return img
+	}
+	cases := []struct {
+		Name       string
+		Containers []string
+		Images     []string
+		ExpectNil  bool
+		ExpectPods int
+		ExpectErr  bool
+	}{
+		{
+			Name:       "test nothing is excluded by default",
+			ExpectPods: 2,
+		},
+		{
+			Name:       "test excluded containers are removed",
+			Containers: []string{"^oauth-proxy$"},
+			ExpectPods: 1,
+		},
+		{
+			Name:       "test image is excluded when only used by excluded containers",
+			Containers: []string{"^oauth-proxy$", "^server$"},
+			ExpectNil:  true,
+		},
+		{
+			Name:      "test image is excluded when its path matches",
+			Images:    []string{"ose-oauth-proxy"},
+			ExpectNil: true,
+		},
+		{
+			Name:       "test error when a pattern is invalid",
+			Containers: []string{"("},
+			ExpectErr:  true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			p, err := registry.DefaultPolicy().WithExclusions(tc.Containers, tc.Images)
+			if tc.ExpectErr {
+				if err == nil {
+					t.Fatal("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			img := image()
+			included := p.Exclude(img)
+			if tc.ExpectNil {
+				if included != nil {
+					t.Fatal("expected the image to be excluded")
+				}
+				return
+			}
+			if included == nil {
+				t.Fatal("did not expect the image to be excluded")
+			}
+			if len(included.Pods) != tc.ExpectPods {
+				t.Fatal("expected ", tc.ExpectPods, " pods but got ", len(included.Pods))
+			}
+			if len(img.Pods) != 2 || len(img.Pods[0].Containers) != 2 {
+				t.Fatal("expected the original image to be left unchanged")
+			}
+		})
+	}
+}
+
+func TestPolicy_Checked(t *testing.T) {
+	p, err := registry.NewPolicy(nil, nil, []registry.Mirror{{Source: "registry.redhat.io", Mirrors: []string{"mirror.example.com"}}})
+	if err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	p, err = p.WithExclusions([]string{"^oauth-proxy$"}, nil)
+	if err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	cases := []struct {
+		Name      string
+		Image     string
+		Container string
+		Expect    bool
+	}{
+		{Name: "test an image from an included registry is checked", Image: "registry.redhat.io/amq7/amq-broker:7.5", Container: "broker", Expect: true},
+		{Name: "test an image from a mirror of an included registry is checked", Image: "mirror.example.com/amq7/amq-broker:7.5", Container: "broker", Expect: true},
+		{Name: "test an image from another registry is not checked", Image: "nginx:1.19", Container: "web", Expect: false},
+		{Name: "test an image only used by excluded containers is not checked", Image: "registry.redhat.io/openshift4/ose-oauth-proxy:4.1", Container: "oauth-proxy", Expect: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			img := cluster.MustParseImage(tc.Image)
+			img.AddContainer("", "test", domain.ContainerTypeContainer, tc.Container)
+			if p.Checked(img) != tc.Expect {
+				t.Fatal("expected checked to be ", tc.Expect, " for ", tc.Image)
+			}
+		})
+	}
+}
+
+func TestPolicy_Filter(t *testing.T) {
+	p, err := registry.NewPolicy(nil, nil, []registry.Mirror{{Source: "registry.redhat.io", Mirrors: []string{"mirror.example.com"}}})
+	if err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	p, err = p.WithExclusions([]string{"^oauth-proxy$"}, nil)
+	if err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	var images []*domain.ClusterImage
+	for _, ref := range [][2]string{
+		{"mirror.example.com/amq7/amq-broker:7.5", "broker"},
+		{"nginx:1.19", "web"},
+		{"registry.redhat.io/openshift4/ose-oauth-proxy:4.1", "oauth-proxy"},
+		{"registry.redhat.io/ubi8/ubi:8.1", "init"},
+	} {
+		img := cluster.MustParseImage(ref[0])
+		img.AddContainer("", "test", domain.ContainerTypeContainer, ref[1])
+		images = append(images, img)
+	}
+	checked := p.Filter(images)
+	if len(checked) != 2 {
+		t.Fatal("expected only the images that are checked but got ", len(checked))
+	}
+	if checked[0].FullPath != "registry.redhat.io/amq7/amq-broker:7.5" || checked[0].MirrorPath != "mirror.example.com/amq7/amq-broker" {
+		t.Fatal("expected the image pulled from the mirror to be resolved but got ", checked[0].FullPath)
+	}
+	if checked[1].FullPath != "registry.redhat.io/ubi8/ubi:8.1" {
+		t.Fatal("expected the images in the order given but got ", checked[1].FullPath)
+	}
+}
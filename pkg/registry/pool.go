@@ -0,0 +1,278 @@
+package registry
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/pkg/errors"
+)
+
+const (
+	EnvCheckWorkers     = "HEIMDALL_CHECK_WORKERS"
+	EnvCheckTimeout     = "HEIMDALL_CHECK_TIMEOUT"
+	DefaultCheckWorkers = 5
+	DefaultCheckTimeout = 5 * time.Minute
+)
+
+// CheckResult is the outcome of checking an image
+type CheckResult struct {
+	Result domain.ReportResult
+	Err    error
+}
+
+// ComponentImage is an image used by a component (e.g. a deployment)
+type ComponentImage struct {
+	Component string
+	Image     *domain.ClusterImage
+}
+
+// ComponentImages returns the images used by component
+func ComponentImages(component string, images []*domain.ClusterImage) []ComponentImage {
+	componentImages := make([]ComponentImage, len(images))
+	for i, image := range images {
+		componentImages[i] = ComponentImage{Component: component, Image: image}
+	}
+	return componentImages
+}
+
+// CheckErrors are the failures of a check of several images. The reports of the images that were checked are returned
+// alongside them as they are still valid.
+type CheckErrors []error
+
+func (e CheckErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Err returns the errors as an error, or nil when there are none
+func (e CheckErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// CheckComponents checks the images of the components concurrently and returns a report for each component image in
+// the order given. Images that fail to be checked are left out of the reports and their failures are returned as
+// CheckErrors along with the reports of the others.
+func (i *ImageService) CheckComponents(ctx context.Context, images []ComponentImage) ([]domain.ReportResult, error) {
+	toCheck := make([]*domain.ClusterImage, len(images))
+	for j, ci := range images {
+		toCheck[j] = ci.Image
+	}
+	results := i.CheckAll(ctx, toCheck)
+	var reports []domain.ReportResult
+	var errs CheckErrors
+	failed := map[string]bool{}
+	for _, ci := range images {
+		res := results[ci.Image.SHA256Path]
+		if res.Err != nil {
+			log.Error(res.Err, "a report failed", "component", ci.Component, "image", ci.Image.FullPath)
+			// an image shared by several components is only checked once
+			if !failed[ci.Image.SHA256Path] {
+				failed[ci.Image.SHA256Path] = true
+				errs = append(errs, errors.Wrap(res.Err, "failed to check image "+ci.Image.FullPath))
+			}
+			continue
+		}
+		rep := res.Result
+		rep.Component = ci.Component
+		// the same image may be used by several components, each with its own pods
+		rep.ClusterImage = ci.Image
+		reports = append(reports, rep)
+	}
+	return reports, errs.Err()
+}
+
+// WithWorkers sets how many images are checked at once and how many tag digests are fetched at once across the checks
+func (i *ImageService) WithWorkers(workers int) *ImageService {
+	if workers < 1 {
+		workers = 1
+	}
+	i.workers = workers
+	i.slotsMu.Lock()
+	i.slots = nil
+	i.slotsMu.Unlock()
+	return i
+}
+
+// WithCheckTimeout sets how long the check of a single image may take before it is abandoned. Zero or less means no
+// timeout other than the one of the context passed to CheckAll.
+func (i *ImageService) WithCheckTimeout(timeout time.Duration) *ImageService {
+	i.checkTimeout = timeout
+	return i
+}
+
+func (i *ImageService) checkWithTimeout(ctx context.Context, image *domain.ClusterImage) (domain.ReportResult, error) {
+	if i.checkTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, i.checkTimeout)
+		defer cancel()
+	}
+	return i.Check(ctx, image)
+}
+
+// CheckAll checks the images concurrently using the configured number of workers. Images with the same SHA256Path are
+// only checked once. The results are keyed on the SHA256Path. If ctx is cancelled images not yet checked are given the
+// context's error and checks in progress are abandoned.
+func (i *ImageService) CheckAll(ctx context.Context, images []*domain.ClusterImage) map[string]CheckResult {
+	results := map[string]CheckResult{}
+	var unique []*domain.ClusterImage
+	for _, img := range images {
+		if _, ok := results[img.SHA256Path]; ok {
+			continue
+		}
+		results[img.SHA256Path] = CheckResult{}
+		unique = append(unique, img)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan *domain.ClusterImage)
+	for w := 0; w < i.workerCount(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for img := range queue {
+				res := CheckResult{}
+				if err := ctx.Err(); err != nil {
+					res.Err = errors.Wrap(err, "check of "+img.FullPath+" was abandoned")
+				} else {
+					res.Result, res.Err = i.checkWithTimeout(ctx, img)
+				}
+				mu.Lock()
+				results[img.SHA256Path] = res
+				mu.Unlock()
+			}
+		}()
+	}
+	dispatched := 0
+dispatch:
+	for _, img := range unique {
+		select {
+		case queue <- img:
+			dispatched++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+	for _, img := range unique[dispatched:] {
+		results[img.SHA256Path] = CheckResult{Err: errors.Wrap(ctx.Err(), "check of "+img.FullPath+" was abandoned")}
+	}
+	return results
+}
+
+func (i *ImageService) workerCount() int {
+	if i.workers < 1 {
+		return 1
+	}
+	return i.workers
+}
+
+type tagDigest struct {
+	digest *domain.RemoteImageDigest
+	err    error
+}
+
+// tagWalker fetches the digests of the tags of a repository as they are walked in order. The digests are fetched
+// concurrently a batch at a time so a walk that stops at the first matching tag only fetches the batch it is in.
+type tagWalker struct {
+	service *ImageService
+	repo    string
+	tags    []string
+	next    int
+	digests map[string]tagDigest
+}
+
+func (i *ImageService) tagDigests(repo string, tags []string) *tagWalker {
+	return &tagWalker{service: i, repo: repo, tags: tags, digests: map[string]tagDigest{}}
+}
+
+// digest returns the digest of tag, fetching the batch of tags from it when it has not been fetched yet. Errors are
+// returned per tag so callers walking the tags in order only fail on the tags they need.
+func (w *tagWalker) digest(ctx context.Context, tag string) (*domain.RemoteImageDigest, error) {
+	if d, ok := w.digests[tag]; ok {
+		return d.digest, d.err
+	}
+	batch := []string{tag}
+	for j := w.next; j < len(w.tags); j++ {
+		if w.tags[j] != tag {
+			continue
+		}
+		end := j + w.service.workerCount()
+		if end > len(w.tags) {
+			end = len(w.tags)
+		}
+		batch = w.tags[j:end]
+		w.next = end
+		break
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, t := range batch {
+		wg.Add(1)
+		go func(t string) {
+			defer wg.Done()
+			d, err := w.service.fetchDigest(ctx, w.repo+":"+t)
+			mu.Lock()
+			w.digests[t] = tagDigest{digest: d, err: err}
+			mu.Unlock()
+		}(t)
+	}
+	wg.Wait()
+	d := w.digests[tag]
+	return d.digest, d.err
+}
+
+// fetchDigest looks up the digest of ref holding one of the fetch slots shared by all the checks of the service, so
+// the checks running at once do not each fetch a batch of tags at the same time
+func (i *ImageService) fetchDigest(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+	slots := i.fetchSlots()
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), "abandoned fetching the digest of "+ref)
+	}
+	defer func() { <-slots }()
+	return i.imageGetter.Get(ctx, ref)
+}
+
+func (i *ImageService) fetchSlots() chan struct{} {
+	i.slotsMu.Lock()
+	defer i.slotsMu.Unlock()
+	if i.slots == nil {
+		i.slots = make(chan struct{}, i.workerCount())
+	}
+	return i.slots
+}
+
+// workerSettingsFromEnv reads the worker count and check timeout from HEIMDALL_CHECK_WORKERS and HEIMDALL_CHECK_TIMEOUT.
+// Requests to each registry host are rate limited by the shared transport.
+func workerSettingsFromEnv() (int, time.Duration, error) {
+	workers, timeout := DefaultCheckWorkers, DefaultCheckTimeout
+	if v := os.Getenv(EnvCheckWorkers); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "failed to parse "+EnvCheckWorkers)
+		}
+		workers = n
+	}
+	if v := os.Getenv(EnvCheckTimeout); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "failed to parse "+EnvCheckTimeout)
+		}
+		timeout = d
+	}
+	return workers, timeout, nil
+}
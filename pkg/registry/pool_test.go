@@ -0,0 +1,203 @@
+package registry_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/cluster"
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/registry"
+	"github.com/integr8ly/heimdall/pkg/rhcc"
+)
+
+func poolTestService(versions *registry.ImageVersionsGetterMock) *registry.ImageService {
+	return registry.NewImagesService(&registry.ImageGetterMock{
+		GetFunc: func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+			return &domain.RemoteImageDigest{Hash: "current", Algorithm: "sha256"}, nil
+		},
+	}, versions, &registry.ImageCVEGetterMock{
+		CVESFunc: func(ctx context.Context, org string, tag string) ([]domain.CVE, error) {
+			return nil, nil
+		},
+	})
+}
+
+func poolTestImage(ref string) *domain.ClusterImage {
+	img := cluster.MustParseImage(ref)
+	img.SHA256Path = img.RegistryPath + "@sha256:current"
+	return img
+}
+
+func TestImageService_CheckAll(t *testing.T) {
+	var mu sync.Mutex
+	repos := map[string]int{}
+	versions := &registry.ImageVersionsGetterMock{
+		AvailableTagsSortedByDateFunc: func(ctx context.Context, repo string) ([]rhcc.Tag, error) {
+			mu.Lock()
+			repos[repo]++
+			mu.Unlock()
+			return []rhcc.Tag{{Name: "1.0", Type: "floating"}, {Name: "1.0.0", Type: "persistent"}}, nil
+		},
+	}
+	is := poolTestService(versions).WithWorkers(3)
+	images := []*domain.ClusterImage{
+		poolTestImage("registry.redhat.io/org/a:1.0.0"),
+		poolTestImage("registry.redhat.io/org/b:1.0.0"),
+		poolTestImage("registry.redhat.io/org/a:1.0.0"),
+		poolTestImage("registry.redhat.io/org/c:1.0.0"),
+	}
+	results := is.CheckAll(context.TODO(), images)
+	if len(results) != 3 {
+		t.Fatal("expected a result per unique image but got ", len(results))
+	}
+	for sha, res := range results {
+		if res.Err != nil {
+			t.Fatal("did not expect an error checking ", sha, res.Err)
+		}
+		if res.Result.CurrentVersion != "1.0.0" {
+			t.Fatal("expected current version 1.0.0 for ", sha, " but got ", res.Result.CurrentVersion)
+		}
+	}
+	for repo, calls := range repos {
+		if calls != 1 {
+			t.Fatal("expected each image to be checked once but ", repo, " was checked ", calls, " times")
+		}
+	}
+}
+
+func TestImageService_CheckAllCancelled(t *testing.T) {
+	versions := &registry.ImageVersionsGetterMock{
+		AvailableTagsSortedByDateFunc: func(ctx context.Context, repo string) ([]rhcc.Tag, error) {
+			return []rhcc.Tag{{Name: "1.0.0", Type: "persistent"}}, nil
+		},
+	}
+	is := poolTestService(versions)
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	images := []*domain.ClusterImage{
+		poolTestImage("registry.redhat.io/org/a:1.0.0"),
+		poolTestImage("registry.redhat.io/org/b:1.0.0"),
+	}
+	results := is.CheckAll(ctx, images)
+	for _, img := range images {
+		if results[img.SHA256Path].Err == nil {
+			t.Fatal("expected an error for ", img.FullPath, " as the context was cancelled")
+		}
+	}
+	if len(versions.AvailableTagsSortedByDateCalls()) != 0 {
+		t.Fatal("expected no images to be checked after the context was cancelled")
+	}
+}
+
+func TestImageService_CheckComponents(t *testing.T) {
+	versions := &registry.ImageVersionsGetterMock{
+		AvailableTagsSortedByDateFunc: func(ctx context.Context, repo string) ([]rhcc.Tag, error) {
+			if strings.HasSuffix(repo, "/broken") {
+				return nil, errors.New("no tags")
+			}
+			return []rhcc.Tag{{Name: "1.0.0", Type: "persistent"}}, nil
+		},
+	}
+	shared := poolTestImage("registry.redhat.io/org/a:1.0.0")
+	sharedCopy := poolTestImage("registry.redhat.io/org/a:1.0.0")
+	reports, err := poolTestService(versions).CheckComponents(context.TODO(), []registry.ComponentImage{
+		{Component: "first", Image: shared},
+		{Component: "second", Image: poolTestImage("registry.redhat.io/org/broken:1.0.0")},
+		{Component: "third", Image: sharedCopy},
+	})
+	errs, ok := err.(registry.CheckErrors)
+	if !ok || len(errs) != 1 || !strings.Contains(err.Error(), "registry.redhat.io/org/broken:1.0.0") {
+		t.Fatal("expected the failure of the broken image to be returned but got ", err)
+	}
+	if len(reports) != 2 {
+		t.Fatal("expected the failed image to be left out of the reports but got ", len(reports))
+	}
+	if reports[0].Component != "first" || reports[1].Component != "third" {
+		t.Fatal("expected the reports in the order given but got ", reports[0].Component, reports[1].Component)
+	}
+	if reports[0].ClusterImage != shared || reports[1].ClusterImage != sharedCopy {
+		t.Fatal("expected each report to refer to its own component's image")
+	}
+	if len(versions.AvailableTagsSortedByDateCalls()) != 2 {
+		t.Fatal("expected the shared image to be checked once but got ", len(versions.AvailableTagsSortedByDateCalls()), " checks")
+	}
+}
+
+func TestImageService_CheckAllTimeout(t *testing.T) {
+	is := registry.NewImagesService(&registry.ImageGetterMock{
+		GetFunc: func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+			// a registry that never responds
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}, &registry.ImageVersionsGetterMock{}, &registry.ImageCVEGetterMock{}).WithCheckTimeout(10 * time.Millisecond)
+	img := poolTestImage("registry.redhat.io/org/a:1.0.0")
+	results := is.CheckAll(context.TODO(), []*domain.ClusterImage{img})
+	if results[img.SHA256Path].Err == nil {
+		t.Fatal("expected the check to be abandoned once the check timeout passed")
+	}
+}
+
+func TestImageService_CheckFloatingTagStopsAtFirstMatch(t *testing.T) {
+	var mu sync.Mutex
+	var fetched []string
+	inFlight, maxInFlight := 0, 0
+	tags := []rhcc.Tag{{Name: "1.0", Type: "floating"}}
+	for v := 9; v >= 0; v-- {
+		tags = append(tags, rhcc.Tag{Name: "1.0." + strconv.Itoa(v), Type: "persistent"})
+	}
+	is := registry.NewImagesService(&registry.ImageGetterMock{
+		GetFunc: func(ctx context.Context, ref string) (*domain.RemoteImageDigest, error) {
+			if strings.Contains(ref, "@sha256") || strings.HasSuffix(ref, ":1.0") {
+				return &domain.RemoteImageDigest{Hash: "current", Algorithm: "sha256"}, nil
+			}
+			mu.Lock()
+			fetched = append(fetched, ref)
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			if strings.HasSuffix(ref, ":1.0.8") {
+				return &domain.RemoteImageDigest{Hash: "current", Algorithm: "sha256"}, nil
+			}
+			return &domain.RemoteImageDigest{Hash: "other", Algorithm: "sha256"}, nil
+		},
+	}, &registry.ImageVersionsGetterMock{
+		AvailableTagsSortedByDateFunc: func(ctx context.Context, repo string) ([]rhcc.Tag, error) {
+			return tags, nil
+		},
+	}, &registry.ImageCVEGetterMock{
+		CVESFunc: func(ctx context.Context, org string, tag string) ([]domain.CVE, error) {
+			return nil, nil
+		},
+	}).WithWorkers(2)
+
+	results := is.CheckAll(context.TODO(), []*domain.ClusterImage{
+		poolTestImage("registry.redhat.io/org/a:1.0"),
+		poolTestImage("registry.redhat.io/org/b:1.0"),
+	})
+	for sha, res := range results {
+		if res.Err != nil {
+			t.Fatal("did not expect an error checking ", sha, res.Err)
+		}
+		if res.Result.CurrentVersion != "1.0.8" || res.Result.LatestAvailablePatchVersion != "1.0.9" {
+			t.Fatal("expected current version 1.0.8 and latest 1.0.9 but got ", res.Result.CurrentVersion, res.Result.LatestAvailablePatchVersion)
+		}
+	}
+	if len(fetched) != 4 {
+		t.Fatal("expected only the first batch of tags of each image to be fetched but got ", fetched)
+	}
+	if maxInFlight > 2 {
+		t.Fatal("expected the tag digests fetched at once to be limited by the workers but got ", maxInFlight)
+	}
+}
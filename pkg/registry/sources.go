@@ -0,0 +1,90 @@
+package registry
+
+import (
+	"os"
+	"regexp"
+
+	"github.com/integr8ly/heimdall/pkg/clair"
+	"github.com/integr8ly/heimdall/pkg/osv"
+	"github.com/pkg/errors"
+)
+
+const (
+	EnvOSVFeedURL = "HEIMDALL_OSV_FEED_URL"
+	EnvOSVHosts   = "HEIMDALL_OSV_HOSTS"
+	EnvClairURL   = "HEIMDALL_CLAIR_URL"
+	EnvClairHosts = "HEIMDALL_CLAIR_HOSTS"
+)
+
+// Source provides the available tags and the CVEs for images pulled from a registry that is not covered by the
+// Red Hat container catalog. Sources are always called with the full repository path (host/org/image) rather than
+// the org path so they can address the registry the image came from. If Versions is nil the tags are listed from the
+// registry itself.
+type Source struct {
+	Versions ImageVersionsGetter
+	CVEs     ImageCVEGetter
+}
+
+type hostSource struct {
+	host   *regexp.Regexp
+	source Source
+}
+
+// Sources selects the Source to use for an image based on the host of the registry it is pulled from
+type Sources struct {
+	sources []hostSource
+}
+
+func NewSources() *Sources {
+	return &Sources{}
+}
+
+// Register adds a source for any registry host matching the hostPattern regular expression. Sources are matched in
+// the order they are registered.
+func (s *Sources) Register(hostPattern string, source Source) error {
+	if hostPattern == "" {
+		return errors.New("a registry host pattern is required to register a source")
+	}
+	if source.CVEs == nil {
+		return errors.New("a source for hosts matching " + hostPattern + " must provide CVEs")
+	}
+	reg, err := regexp.Compile(hostPattern)
+	if err != nil {
+		return errors.Wrap(err, "failed to compile registry host pattern "+hostPattern)
+	}
+	if source.Versions == nil {
+		source.Versions = &TagsClient{}
+	}
+	s.sources = append(s.sources, hostSource{host: reg, source: source})
+	return nil
+}
+
+// For returns the source registered for host. If none is registered the Red Hat container catalog is used.
+func (s *Sources) For(host string) (Source, bool) {
+	if s == nil {
+		return Source{}, false
+	}
+	for _, hs := range s.sources {
+		if hs.host.MatchString(host) {
+			return hs.source, true
+		}
+	}
+	return Source{}, false
+}
+
+// SourcesFromEnv registers the OSV feed and Clair sources configured via the HEIMDALL_OSV_* and HEIMDALL_CLAIR_*
+// environment variables
+func SourcesFromEnv() (*Sources, error) {
+	sources := NewSources()
+	if feed := os.Getenv(EnvOSVFeedURL); feed != "" {
+		if err := sources.Register(os.Getenv(EnvOSVHosts), Source{CVEs: osv.NewClient(feed)}); err != nil {
+			return nil, err
+		}
+	}
+	if clairURL := os.Getenv(EnvClairURL); clairURL != "" {
+		if err := sources.Register(os.Getenv(EnvClairHosts), Source{CVEs: clair.NewClient(clairURL, &Client{})}); err != nil {
+			return nil, err
+		}
+	}
+	return sources, nil
+}
@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"unicode"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/integr8ly/heimdall/pkg/customMetrics"
+	"github.com/integr8ly/heimdall/pkg/rhcc"
+	"github.com/integr8ly/heimdall/pkg/transport"
+	"github.com/pkg/errors"
+)
+
+// TagsClient lists the tags of a repository directly from the registry. Registries do not expose when a tag was
+// pushed or whether it floats, so the tags are ordered newest version first and only "latest" is treated as floating.
+type TagsClient struct {
+}
+
+func (tc *TagsClient) AvailableTagsSortedByDate(ctx context.Context, repo string) ([]rhcc.Tag, error) {
+	r, err := name.NewRepository(repo, name.WeakValidation)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse repository "+repo)
+	}
+	auth, err := authn.DefaultKeychain.Resolve(r.Registry)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve credentials for registry "+r.RegistryStr())
+	}
+	names, err := remote.List(r, auth, transport.WithContext(ctx, transport.Shared()))
+	customMetrics.RegistryCallsTotal.Inc()
+	if err != nil {
+		customMetrics.RegistryCallsFailure.Inc()
+		return nil, errors.Wrap(err, "failed to list tags for repository "+repo)
+	}
+	customMetrics.RegistryCallsSuccess.Inc()
+	sort.Slice(names, func(i, j int) bool {
+		return versionLess(names[j], names[i])
+	})
+	tags := make([]rhcc.Tag, len(names))
+	for i, n := range names {
+		tags[i] = rhcc.Tag{Name: n, Type: "persistent"}
+		if n == "latest" {
+			tags[i].Type = "floating"
+		}
+	}
+	// latest always leads as it is the most recent image pushed
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].Name == "latest" && tags[j].Name != "latest"
+	})
+	return tags, nil
+}
+
+// versionLess compares two tags treating runs of digits as numbers so that 1.10 is newer than 1.9
+func versionLess(a, b string) bool {
+	ar, br := []rune(a), []rune(b)
+	for len(ar) > 0 && len(br) > 0 {
+		if unicode.IsDigit(ar[0]) && unicode.IsDigit(br[0]) {
+			an, arest := leadingNumber(ar)
+			bn, brest := leadingNumber(br)
+			if an != bn {
+				return an < bn
+			}
+			ar, br = arest, brest
+			continue
+		}
+		if ar[0] != br[0] {
+			return ar[0] < br[0]
+		}
+		ar, br = ar[1:], br[1:]
+	}
+	return len(ar) < len(br)
+}
+
+func leadingNumber(r []rune) (int64, []rune) {
+	i := 0
+	for i < len(r) && unicode.IsDigit(r[i]) {
+		i++
+	}
+	n, _ := strconv.ParseInt(string(r[:i]), 10, 64)
+	return n, r[i:]
+}
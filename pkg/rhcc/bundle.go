@@ -0,0 +1,79 @@
+package rhcc
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Bundle is an offline snapshot of the container catalog data for a set of repositories. It allows Heimdall to run in
+// clusters with no internet access.
+type Bundle struct {
+	Created string `json:"created"`
+	// keyed on the repository e.g. amq7/amq-online-1-api-server
+	Repositories map[string]*ContainerRepository `json:"repositories"`
+	// keyed on the repository and tag, see BundleImageKey
+	Images map[string]*ContainerRepositoryImage `json:"images"`
+}
+
+func BundleImageKey(org, tag string) string {
+	return org + ":" + tag
+}
+
+func LoadBundle(path string) (*Bundle, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read rhcc bundle "+path)
+	}
+	bundle := &Bundle{}
+	if err := json.Unmarshal(data, bundle); err != nil {
+		return nil, errors.Wrap(err, "failed to parse rhcc bundle "+path)
+	}
+	return bundle, nil
+}
+
+func (b *Bundle) Save(path string) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode rhcc bundle")
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write rhcc bundle "+path)
+	}
+	return nil
+}
+
+// ExportBundle snapshots the repository data and the image data for every tag of each of the repositories
+func (c *Client) ExportBundle(ctx context.Context, repositories []string) (*Bundle, error) {
+	bundle := &Bundle{
+		Created:      time.Now().Format(time.RFC3339),
+		Repositories: map[string]*ContainerRepository{},
+		Images:       map[string]*ContainerRepositoryImage{},
+	}
+	for _, org := range repositories {
+		cr, err := c.containerRepository(ctx, org)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get repository "+org)
+		}
+		bundle.Repositories[org] = cr
+		tags, err := sortedTags(org, cr)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get tags for repository "+org)
+		}
+		for _, t := range tags {
+			key := BundleImageKey(org, t.Name)
+			if _, ok := bundle.Images[key]; ok {
+				continue
+			}
+			cri, err := c.containerRepositoryImage(ctx, org, t.Name)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to get image "+key)
+			}
+			bundle.Images[key] = cri
+		}
+	}
+	return bundle, nil
+}
@@ -0,0 +1,89 @@
+package rhcc_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/integr8ly/heimdall/pkg/rhcc"
+)
+
+const repository = `{"processed": [{"images": [
+  {"repositories": [{"tags": [{"added_date": "20191125T09:53:00.000-0500", "name": "1.0-2", "tag_history": [{"tag_type": "persistent"}]}]}]},
+  {"repositories": [{"tags": [{"added_date": "20191124T09:53:00.000-0500", "name": "1.0-1", "tag_history": [{"tag_type": "persistent"}]}]}]}
+]}]}`
+
+const imageTemplate = `{"processed": [{"images": [{"vulnerabilitiesRef": [{"severity": "critical", "advisory_id": "RHSA-1", "cve_id": "CVE-%s"}]}]}]}`
+
+func TestClient_ExportBundle(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		path := r.URL.Path
+		if !strings.HasPrefix(path, "/repository/registry.access.redhat.com/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if strings.HasSuffix(path, "/images") {
+			w.Write([]byte(repository))
+			return
+		}
+		parts := strings.Split(path, "/")
+		w.Write([]byte(strings.Replace(imageTemplate, "%s", parts[len(parts)-1], 1)))
+	}))
+	defer server.Close()
+
+	bundle, err := rhcc.NewClient(server.URL).ExportBundle(context.TODO(), []string{"amq7/amq-online"})
+	if err != nil {
+		t.Fatal("did not expect an error exporting the bundle but got one ", err)
+	}
+	if len(bundle.Repositories) != 1 || len(bundle.Images) != 2 {
+		t.Fatal("expected 1 repository and 2 images in the bundle but got ", len(bundle.Repositories), len(bundle.Images))
+	}
+	if calls != 3 {
+		t.Fatal("expected the repository and each of its images to be fetched once but got ", calls, " calls")
+	}
+
+	dir, err := ioutil.TempDir("", "rhcc-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bundle.json")
+	if err := bundle.Save(path); err != nil {
+		t.Fatal("did not expect an error saving the bundle but got one ", err)
+	}
+	loaded, err := rhcc.LoadBundle(path)
+	if err != nil {
+		t.Fatal("did not expect an error loading the bundle but got one ", err)
+	}
+
+	server.Close()
+	calls = 0
+	client := rhcc.NewBundleClient(loaded)
+	tags, err := client.AvailableTagsSortedByDate(context.TODO(), "amq7/amq-online")
+	if err != nil {
+		t.Fatal("did not expect an error getting tags from the bundle but got one ", err)
+	}
+	if len(tags) != 2 || tags[0].Name != "1.0-2" {
+		t.Fatal("expected the tags sorted newest first but got ", tags)
+	}
+	cves, err := client.CVES(context.TODO(), "amq7/amq-online", "1.0-1")
+	if err != nil {
+		t.Fatal("did not expect an error getting cves from the bundle but got one ", err)
+	}
+	if len(cves) != 1 || cves[0].ID != "CVE-1.0-1" {
+		t.Fatal("expected the cve for tag 1.0-1 but got ", cves)
+	}
+	if _, err := client.CVES(context.TODO(), "amq7/other", "1.0"); err == nil {
+		t.Fatal("expected an error for a repository not in the bundle")
+	}
+	if calls != 0 {
+		t.Fatal("expected no calls to the api when using a bundle but got ", calls)
+	}
+}
@@ -1,15 +1,19 @@
 package rhcc
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/integr8ly/heimdall/pkg/customMetrics"
 	"github.com/pkg/errors"
 
 	"fmt"
 	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/transport"
 	"net/http"
 	"net/url"
+	"os"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -17,7 +21,45 @@ const host = "https://rhcc-api.redhat.com/rest/v1"
 const images = "%s/repository/%s/%s/images"
 const image = "%s/repository/%s/%s/images/%s?architecture="
 
+const (
+	EnvURL    = "HEIMDALL_RHCC_URL"
+	EnvBundle = "HEIMDALL_RHCC_BUNDLE"
+)
+
+// Client reads tags and CVEs from the Red Hat container catalog API. If a Bundle is set the data is served from the
+// bundle rather than the API so Heimdall can run without internet access. The zero value uses the public API.
 type Client struct {
+	// BaseURL overrides the url of the catalog API e.g. to point at a proxy or a mirror of the API
+	BaseURL string
+	Bundle  *Bundle
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func NewBundleClient(bundle *Bundle) *Client {
+	return &Client{Bundle: bundle}
+}
+
+// ClientFromEnv creates a Client using the bundle file in HEIMDALL_RHCC_BUNDLE if set, otherwise the API at
+// HEIMDALL_RHCC_URL falling back to the public API
+func ClientFromEnv() (*Client, error) {
+	if path := os.Getenv(EnvBundle); path != "" {
+		bundle, err := LoadBundle(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewBundleClient(bundle), nil
+	}
+	return NewClient(os.Getenv(EnvURL)), nil
+}
+
+func (c *Client) host() string {
+	if c.BaseURL != "" {
+		return c.BaseURL
+	}
+	return host
 }
 
 type Tag struct {
@@ -29,29 +71,20 @@ type Tag struct {
 	Type string
 }
 
-func (c *Client) AvailableTagsSortedByDate(org string) ([]Tag, error) {
-	cr := &ContainerRepository{}
-	// seems to need double encoding
-	image := url.QueryEscape(url.QueryEscape(org))
-	// done to allow us to call the API without the need for credentials (should revisit)
-	url := fmt.Sprintf(images, host, "registry.access.redhat.com", image)
-	resp, err := http.Get(url)
-	customMetrics.RegistryCallsTotal.Inc()
+func (c *Client) AvailableTagsSortedByDate(ctx context.Context, org string) ([]Tag, error) {
+	cr, err := c.containerRepository(ctx, org)
 	if err != nil {
-		customMetrics.RegistryCallsFailure.Inc()
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		customMetrics.RegistryCallsFailure.Inc()
-		return nil, errors.New("unexpected response from rhcc api " + resp.Status)
+	return sortedTags(org, cr)
+}
+
+// sortedTags returns the tags of the repository org, newest first
+func sortedTags(org string, cr *ContainerRepository) ([]Tag, error) {
+	if len(cr.Processed) == 0 {
+		return nil, errors.New("no images found in the rhcc api for " + org)
 	}
 	var format = "20060102T15:04:05.000-0700"
-	defer resp.Body.Close()
-	dec := json.NewDecoder(resp.Body)
-	if err := dec.Decode(cr); err != nil {
-		customMetrics.RegistryCallsFailure.Inc()
-		return nil, err
-	}
 	// sort by added date
 	//20191125T09:53:00.000-0500
 	var tags []Tag
@@ -97,38 +130,79 @@ func (c *Client) AvailableTagsSortedByDate(org string) ([]Tag, error) {
 	sort.Slice(tags, func(i, j int) bool {
 		return tags[i].TimeAdded > tags[j].TimeAdded
 	})
-	customMetrics.RegistryCallsSuccess.Inc()
 	return tags, nil
 }
 
-func (c *Client) CVES(org, tag string) ([]domain.CVE, error) {
+func (c *Client) CVES(ctx context.Context, org, tag string) ([]domain.CVE, error) {
 	if org == "" || tag == "" {
 		return nil, errors.New("expected and org and a tag but got org  " + org + " tag " + tag)
 	}
+	cri, err := c.containerRepositoryImage(ctx, org, tag)
+	if err != nil {
+		return nil, err
+	}
+	if len(cri.Processed) == 0 || len(cri.Processed[0].Images) == 0 {
+		return nil, errors.New("no image found in the rhcc api for " + org + ":" + tag)
+	}
+	var cves []domain.CVE
+	// should only be one image as we used specific tag
+	for _, v := range cri.Processed[0].Images[0].VulnerabilitiesRef {
+		cves = append(cves, domain.CVE{AdvisoryID: v.AdvisoryID, Severity: v.Severity, ID: v.CveID})
+	}
+	return cves, nil
+}
+
+func (c *Client) containerRepository(ctx context.Context, org string) (*ContainerRepository, error) {
+	if c.Bundle != nil {
+		cr, ok := c.Bundle.Repositories[org]
+		if !ok {
+			return nil, errors.New("repository " + org + " is not in the rhcc bundle")
+		}
+		return cr, nil
+	}
+	cr := &ContainerRepository{}
+	// seems to need double encoding
+	i := url.QueryEscape(url.QueryEscape(org))
+	// done to allow us to call the API without the need for credentials (should revisit)
+	if err := c.get(ctx, fmt.Sprintf(images, c.host(), "registry.access.redhat.com", i), cr); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+func (c *Client) containerRepositoryImage(ctx context.Context, org, tag string) (*ContainerRepositoryImage, error) {
+	if c.Bundle != nil {
+		cri, ok := c.Bundle.Images[BundleImageKey(org, tag)]
+		if !ok {
+			return nil, errors.New("image " + org + ":" + tag + " is not in the rhcc bundle")
+		}
+		return cri, nil
+	}
 	cri := &ContainerRepositoryImage{}
 	i := url.QueryEscape(url.QueryEscape(org))
-	url := fmt.Sprintf(image, host, "registry.access.redhat.com", i, tag)
-	resp, err := http.Get(url)
+	if err := c.get(ctx, fmt.Sprintf(image, c.host(), "registry.access.redhat.com", i, tag), cri); err != nil {
+		return nil, err
+	}
+	return cri, nil
+}
+
+func (c *Client) get(ctx context.Context, url string, into interface{}) error {
+	resp, err := transport.Get(ctx, url)
 	customMetrics.RegistryCallsTotal.Inc()
 	if err != nil {
-		return nil, err
+		customMetrics.RegistryCallsFailure.Inc()
+		return err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		customMetrics.RegistryCallsFailure.Inc()
-		return nil, errors.New("unexpected response from rhcc api " + resp.Status)
+		return errors.New("unexpected response from rhcc api " + resp.Status)
 	}
-
-	defer resp.Body.Close()
 	dec := json.NewDecoder(resp.Body)
-	if err := dec.Decode(cri); err != nil {
+	if err := dec.Decode(into); err != nil {
 		customMetrics.RegistryCallsFailure.Inc()
-		return nil, err
-	}
-	var cves []domain.CVE
-	// should only be one image as we used specific tag
-	for _, v := range cri.Processed[0].Images[0].VulnerabilitiesRef {
-		cves = append(cves, domain.CVE{AdvisoryID: v.AdvisoryID, Severity: v.Severity, ID: v.CveID})
+		return err
 	}
 	customMetrics.RegistryCallsSuccess.Inc()
-	return cves, nil
+	return nil
 }
@@ -0,0 +1,106 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/schedule"
+)
+
+func TestCron_Next(t *testing.T) {
+	// a saturday
+	from := time.Date(2020, time.February, 1, 10, 30, 0, 0, time.UTC)
+	cases := []struct {
+		Name      string
+		Expr      string
+		Expect    time.Time
+		ExpectErr bool
+	}{
+		{
+			Name:   "test every minute",
+			Expr:   "* * * * *",
+			Expect: time.Date(2020, time.February, 1, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			Name:   "test daily at 2am",
+			Expr:   "0 2 * * *",
+			Expect: time.Date(2020, time.February, 2, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:   "test shorthand",
+			Expr:   "@monthly",
+			Expect: time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:   "test steps and ranges",
+			Expr:   "*/20 8-18 * * *",
+			Expect: time.Date(2020, time.February, 1, 10, 40, 0, 0, time.UTC),
+		},
+		{
+			Name:   "test day names",
+			Expr:   "0 9 * * mon-fri",
+			Expect: time.Date(2020, time.February, 3, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:   "test 7 is sunday",
+			Expr:   "0 9 * * 7",
+			Expect: time.Date(2020, time.February, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:   "test month names and lists",
+			Expr:   "0 0 1 jan,jun *",
+			Expect: time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:   "test either day field matches when both are set",
+			Expr:   "0 0 15 * mon",
+			Expect: time.Date(2020, time.February, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:   "test leap day",
+			Expr:   "0 0 29 feb *",
+			Expect: time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Name: "test zero time when never matched",
+			Expr: "0 0 30 feb *",
+		},
+		{
+			Name:      "test error on wrong number of fields",
+			Expr:      "0 0 * *",
+			ExpectErr: true,
+		},
+		{
+			Name:      "test error on value out of range",
+			Expr:      "60 * * * *",
+			ExpectErr: true,
+		},
+		{
+			Name:      "test error on invalid step",
+			Expr:      "*/0 * * * *",
+			ExpectErr: true,
+		},
+		{
+			Name:      "test error on unknown name",
+			Expr:      "0 0 * * someday",
+			ExpectErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			c, err := schedule.ParseCron(tc.Expr)
+			if tc.ExpectErr {
+				if err == nil {
+					t.Fatal("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if next := c.Next(from); !next.Equal(tc.Expect) {
+				t.Fatal("expected next to be ", tc.Expect, " but got ", next)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package schedule
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/pkg/errors"
+)
+
+const EnvRecheckMins = "HEIMDALL_RECHECK_MINS"
+
+// Getter returns the schedule to check the workloads in a namespace on
+type Getter interface {
+	ScheduleFor(ctx context.Context, namespace string) (*Schedule, error)
+}
+
+// Schedule decides when a workload is rechecked. A workload is due either once the interval has passed since it was
+// last checked or, when the schedule has a cron expression, once the expression has matched since it was last checked.
+// No checks are run during a maintenance window, those that become due are run once the window ends.
+type Schedule struct {
+	interval time.Duration
+	cron     *Cron
+	windows  []Window
+}
+
+// Window is a maintenance window starting each time its cron expression matches and lasting for its duration
+type Window struct {
+	start    *Cron
+	duration time.Duration
+}
+
+// NewWindow creates a maintenance window from a cron expression and duration e.g. "0 22 * * sat" and 8h
+func NewWindow(start string, duration time.Duration) (Window, error) {
+	if duration <= 0 {
+		return Window{}, errors.New("the duration of a maintenance window must be positive")
+	}
+	c, err := ParseCron(start)
+	if err != nil {
+		return Window{}, errors.Wrap(err, "invalid maintenance window")
+	}
+	return Window{start: c, duration: duration}, nil
+}
+
+// New creates a schedule that rechecks workloads each time the cron expression matches or, if it is empty, after the
+// interval. Checks are held back during the maintenance windows.
+func New(interval time.Duration, cron string, windows ...Window) (*Schedule, error) {
+	s := &Schedule{interval: interval, windows: windows}
+	if cron != "" {
+		c, err := ParseCron(cron)
+		if err != nil {
+			return nil, err
+		}
+		s.cron = c
+	} else if interval <= 0 {
+		return nil, errors.New("the recheck interval must be positive")
+	}
+	return s, nil
+}
+
+// Every returns a schedule rechecking workloads after interval without maintenance windows
+func Every(interval time.Duration) *Schedule {
+	return &Schedule{interval: interval}
+}
+
+// DefaultInterval reads the recheck interval from HEIMDALL_RECHECK_MINS. The interval is a day if it is not set and
+// the error is returned alongside the default if it can not be parsed.
+func DefaultInterval() (time.Duration, error) {
+	interval := time.Duration(domain.MinRecheckIntervalMins) * time.Minute
+	v := os.Getenv(EnvRecheckMins)
+	if v == "" {
+		return interval, nil
+	}
+	mins, err := strconv.ParseInt(v, 10, 32)
+	if err != nil || mins <= 0 {
+		return interval, errors.Errorf("failed to parse %s %q, using the default of %v", EnvRecheckMins, v, interval)
+	}
+	return time.Duration(mins) * time.Minute, nil
+}
+
+// ScheduleFor allows a single Schedule to be used for every namespace
+func (s *Schedule) ScheduleFor(ctx context.Context, namespace string) (*Schedule, error) {
+	return s, nil
+}
+
+// Due reports whether a workload last checked at lastChecked should be checked at now, ignoring maintenance windows
+func (s *Schedule) Due(lastChecked, now time.Time) bool {
+	next := s.next(lastChecked)
+	return !next.IsZero() && !next.After(now)
+}
+
+// InMaintenance reports whether t falls in a maintenance window and if so when the window ends
+func (s *Schedule) InMaintenance(t time.Time) (time.Time, bool) {
+	var end time.Time
+	for _, w := range s.windows {
+		// any window that started in the last duration is still open, the latest start ends last
+		for start := w.start.Next(t.Add(-w.duration).Add(-time.Minute)); !start.IsZero() && !start.After(t); start = w.start.Next(start) {
+			if e := start.Add(w.duration); e.After(end) {
+				end = e
+			}
+		}
+	}
+	return end, !end.IsZero() && end.After(t)
+}
+
+// NextCheck returns when a workload last checked at lastChecked is next due to be checked, no earlier than now and
+// outside of any maintenance window. The zero time is returned if it is never due again.
+func (s *Schedule) NextCheck(lastChecked, now time.Time) time.Time {
+	next := s.next(lastChecked)
+	if next.IsZero() {
+		return next
+	}
+	if next.Before(now) {
+		next = now
+	}
+	// windows can follow on from each other
+	for i := 0; i <= len(s.windows); i++ {
+		end, ok := s.InMaintenance(next)
+		if !ok {
+			break
+		}
+		next = end
+	}
+	return next
+}
+
+func (s *Schedule) next(lastChecked time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.Next(lastChecked)
+	}
+	return lastChecked.Add(s.interval)
+}
@@ -0,0 +1,131 @@
+package schedule_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/domain"
+	"github.com/integr8ly/heimdall/pkg/schedule"
+)
+
+type window struct {
+	start    string
+	duration time.Duration
+}
+
+func TestSchedule_NextCheck(t *testing.T) {
+	// a saturday
+	now := time.Date(2020, time.February, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		Name        string
+		Interval    time.Duration
+		Cron        string
+		Windows     []window
+		LastChecked time.Time
+		ExpectDue   bool
+		Expect      time.Time
+	}{
+		{
+			Name:        "test next check after the interval",
+			Interval:    6 * time.Hour,
+			LastChecked: now.Add(-time.Hour),
+			Expect:      now.Add(5 * time.Hour),
+		},
+		{
+			Name:        "test overdue check is due now",
+			Interval:    6 * time.Hour,
+			LastChecked: now.Add(-7 * time.Hour),
+			ExpectDue:   true,
+			Expect:      now,
+		},
+		{
+			Name:        "test cron takes precedence over the interval",
+			Interval:    time.Minute,
+			Cron:        "0 2 * * *",
+			LastChecked: now.Add(-time.Hour),
+			Expect:      time.Date(2020, time.February, 2, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:        "test check held back until the maintenance window ends",
+			Interval:    time.Hour,
+			Windows:     []window{{"0 10 * * sat", 4 * time.Hour}},
+			LastChecked: now.Add(-2 * time.Hour),
+			ExpectDue:   true,
+			Expect:      now.Add(2 * time.Hour),
+		},
+		{
+			Name:     "test check held back until following maintenance windows end",
+			Interval: time.Hour,
+			Windows: []window{
+				{"0 10 * * sat", 4 * time.Hour},
+				{"0 14 * * sat", time.Hour},
+			},
+			LastChecked: now.Add(-2 * time.Hour),
+			ExpectDue:   true,
+			Expect:      now.Add(3 * time.Hour),
+		},
+		{
+			Name:        "test maintenance window that has ended does not hold back checks",
+			Interval:    time.Hour,
+			Windows:     []window{{"0 8 * * sat", 2 * time.Hour}},
+			LastChecked: now.Add(-2 * time.Hour),
+			ExpectDue:   true,
+			Expect:      now,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var windows []schedule.Window
+			for _, w := range tc.Windows {
+				window, err := schedule.NewWindow(w.start, w.duration)
+				if err != nil {
+					t.Fatal("failed to create maintenance window ", err)
+				}
+				windows = append(windows, window)
+			}
+			s, err := schedule.New(tc.Interval, tc.Cron, windows...)
+			if err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			if due := s.Due(tc.LastChecked, now); due != tc.ExpectDue {
+				t.Fatal("expected due to be ", tc.ExpectDue, " but got ", due)
+			}
+			if next := s.NextCheck(tc.LastChecked, now); !next.Equal(tc.Expect) {
+				t.Fatal("expected next check to be ", tc.Expect, " but got ", next)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := schedule.New(0, ""); err == nil {
+		t.Fatal("expected an error for a schedule without an interval or cron expression")
+	}
+	if _, err := schedule.New(0, "0 25 * * *"); err == nil {
+		t.Fatal("expected an error for an invalid cron expression")
+	}
+	if _, err := schedule.NewWindow("0 22 * * sat", 0); err == nil {
+		t.Fatal("expected an error for a maintenance window without a duration")
+	}
+}
+
+func TestDefaultInterval(t *testing.T) {
+	defer os.Unsetenv(schedule.EnvRecheckMins)
+	if err := os.Setenv(schedule.EnvRecheckMins, "90"); err != nil {
+		t.Fatal(err)
+	}
+	if interval, err := schedule.DefaultInterval(); err != nil || interval != 90*time.Minute {
+		t.Fatal("expected an interval of 90m but got ", interval, err)
+	}
+	if err := os.Setenv(schedule.EnvRecheckMins, "often"); err != nil {
+		t.Fatal(err)
+	}
+	interval, err := schedule.DefaultInterval()
+	if err == nil {
+		t.Fatal("expected an error for an invalid interval")
+	}
+	if interval != time.Duration(domain.MinRecheckIntervalMins)*time.Minute {
+		t.Fatal("expected the default interval but got ", interval)
+	}
+}
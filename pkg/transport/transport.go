@@ -0,0 +1,309 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/customMetrics"
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("transport")
+
+const (
+	EnvRateLimit  = "HEIMDALL_HTTP_RATE_LIMIT"
+	EnvRateBurst  = "HEIMDALL_HTTP_RATE_BURST"
+	EnvMaxRetries = "HEIMDALL_HTTP_MAX_RETRIES"
+	EnvMinBackoff = "HEIMDALL_HTTP_MIN_BACKOFF"
+	EnvMaxBackoff = "HEIMDALL_HTTP_MAX_BACKOFF"
+	EnvTimeout    = "HEIMDALL_HTTP_TIMEOUT"
+)
+
+// Settings configures the rate limiting and retries of a Transport
+type Settings struct {
+	// RateLimit is the number of requests per second allowed to each host. Zero or less means no limit.
+	RateLimit float64
+	RateBurst int
+	// MaxRetries is how many times a failed request is retried before giving up
+	MaxRetries int
+	// MinBackoff is the wait before the first retry, it doubles with each retry up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Timeout is how long a single attempt of a request may take, including reading the response body. Zero or less
+	// means no timeout.
+	Timeout time.Duration
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		RateLimit:  20,
+		RateBurst:  40,
+		MaxRetries: 4,
+		MinBackoff: 500 * time.Millisecond,
+		MaxBackoff: 30 * time.Second,
+		Timeout:    30 * time.Second,
+	}
+}
+
+// SettingsFromEnv reads the settings from the HEIMDALL_HTTP_* environment variables falling back to the defaults
+func SettingsFromEnv() (Settings, error) {
+	s := DefaultSettings()
+	if v := os.Getenv(EnvRateLimit); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return s, errors.Wrap(err, "failed to parse "+EnvRateLimit)
+		}
+		s.RateLimit = f
+	}
+	for env, into := range map[string]*int{EnvRateBurst: &s.RateBurst, EnvMaxRetries: &s.MaxRetries} {
+		if v := os.Getenv(env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return s, errors.Wrap(err, "failed to parse "+env)
+			}
+			*into = n
+		}
+	}
+	for env, into := range map[string]*time.Duration{EnvMinBackoff: &s.MinBackoff, EnvMaxBackoff: &s.MaxBackoff, EnvTimeout: &s.Timeout} {
+		if v := os.Getenv(env); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return s, errors.Wrap(err, "failed to parse "+env)
+			}
+			*into = d
+		}
+	}
+	return s, nil
+}
+
+// Transport is a http.RoundTripper that limits the rate of requests to each host and retries GET and HEAD requests
+// that fail with a network error or a 429, 502, 503 or 504 response. Retries back off exponentially with jitter unless
+// the response has a Retry-After header, which is honoured as long as it is no longer than MaxBackoff.
+type Transport struct {
+	base       http.RoundTripper
+	settings   Settings
+	limitersMu sync.Mutex
+	limiters   map[string]*rate.Limiter
+}
+
+// New creates a Transport sending requests with base, if base is nil http.DefaultTransport is used
+func New(base http.RoundTripper, settings Settings) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if settings.RateBurst < 1 {
+		settings.RateBurst = 1
+	}
+	return &Transport{base: base, settings: settings, limiters: map[string]*rate.Limiter{}}
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   *Transport
+)
+
+// Shared returns the Transport used for all registry, rhcc and CVE source calls. Unless SetShared has been called it
+// is configured from the environment.
+func Shared() *Transport {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if shared == nil {
+		settings, err := SettingsFromEnv()
+		if err != nil {
+			log.Error(err, "invalid http settings, using the defaults")
+			settings = DefaultSettings()
+		}
+		shared = New(nil, settings)
+	}
+	return shared
+}
+
+// SetShared replaces the shared Transport e.g. with one configured from cli flags
+func SetShared(t *Transport) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	shared = t
+}
+
+// Client returns a http.Client using the shared Transport
+func Client() *http.Client {
+	return &http.Client{Transport: Shared()}
+}
+
+// Get issues a GET to url with the shared Transport, abandoning it if ctx is done
+func Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request for "+url)
+	}
+	return Client().Do(req.WithContext(ctx))
+}
+
+// WithContext returns a http.RoundTripper sending every request through rt with ctx. It is for clients such as the
+// registry client that do not accept a context themselves.
+func WithContext(ctx context.Context, rt http.RoundTripper) http.RoundTripper {
+	return contextRoundTripper{ctx: ctx, rt: rt}
+}
+
+type contextRoundTripper struct {
+	ctx context.Context
+	rt  http.RoundTripper
+}
+
+func (c contextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return c.rt.RoundTrip(req.WithContext(c.ctx))
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	host := req.URL.Host
+	retryable := req.Method == http.MethodGet || req.Method == http.MethodHead
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(ctx, host); err != nil {
+			return nil, err
+		}
+		resp, err := t.attempt(req)
+		if !retryable || attempt >= t.settings.MaxRetries || !shouldRetry(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+		backoff := t.backoff(attempt)
+		if resp != nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				customMetrics.HTTPThrottled.WithLabelValues(host).Inc()
+			}
+			if after, ok := retryAfter(resp); ok {
+				if after > t.settings.MaxBackoff {
+					// the server will not accept requests for longer than we are prepared to wait
+					return resp, nil
+				}
+				backoff = after
+			}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		customMetrics.HTTPRetries.WithLabelValues(host).Inc()
+		log.V(1).Info("retrying request", "url", req.URL.String(), "attempt", attempt+1, "backoff", backoff.String())
+		if err := sleep(ctx, backoff); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends the request once. The attempt is cancelled when the timeout passes or the response body is closed.
+func (t *Transport) attempt(req *http.Request) (*http.Response, error) {
+	if t.settings.Timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.settings.Timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// wait blocks until the rate limit of host allows another request
+func (t *Transport) wait(ctx context.Context, host string) error {
+	if t.settings.RateLimit <= 0 {
+		return nil
+	}
+	t.limitersMu.Lock()
+	l, ok := t.limiters[host]
+	if !ok {
+		l = rate.NewLimiter(rate.Limit(t.settings.RateLimit), t.settings.RateBurst)
+		t.limiters[host] = l
+	}
+	t.limitersMu.Unlock()
+	r := l.Reserve()
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	customMetrics.HTTPRateLimited.WithLabelValues(host).Inc()
+	if err := sleep(ctx, delay); err != nil {
+		r.Cancel()
+		return err
+	}
+	return nil
+}
+
+// backoff returns a random duration between half and all of MinBackoff doubled for each attempt, capped at MaxBackoff
+func (t *Transport) backoff(attempt int) time.Duration {
+	d := t.settings.MinBackoff
+	for i := 0; i < attempt && d < t.settings.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > t.settings.MaxBackoff {
+		d = t.settings.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses the Retry-After header which is either a number of seconds or a http date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		d := time.Until(at)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
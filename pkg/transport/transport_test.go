@@ -0,0 +1,159 @@
+package transport_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/integr8ly/heimdall/pkg/transport"
+)
+
+func testSettings() transport.Settings {
+	return transport.Settings{
+		MaxRetries: 3,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+	}
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Method       string
+		Responses    []int
+		RetryAfter   string
+		ExpectStatus int
+		ExpectCalls  int
+	}{
+		{
+			Name:         "test transient failures are retried until the request succeeds",
+			Method:       http.MethodGet,
+			Responses:    []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			ExpectStatus: http.StatusOK,
+			ExpectCalls:  3,
+		},
+		{
+			Name:         "test retries stop after the max retries",
+			Method:       http.MethodGet,
+			Responses:    []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			ExpectStatus: http.StatusBadGateway,
+			ExpectCalls:  4,
+		},
+		{
+			Name:         "test errors that are not transient are not retried",
+			Method:       http.MethodGet,
+			Responses:    []int{http.StatusNotFound, http.StatusOK},
+			ExpectStatus: http.StatusNotFound,
+			ExpectCalls:  1,
+		},
+		{
+			Name:         "test requests that are not idempotent are not retried",
+			Method:       http.MethodPost,
+			Responses:    []int{http.StatusServiceUnavailable, http.StatusOK},
+			ExpectStatus: http.StatusServiceUnavailable,
+			ExpectCalls:  1,
+		},
+		{
+			Name:         "test a retry after within the max backoff is honoured",
+			Method:       http.MethodGet,
+			Responses:    []int{http.StatusTooManyRequests, http.StatusOK},
+			RetryAfter:   "0",
+			ExpectStatus: http.StatusOK,
+			ExpectCalls:  2,
+		},
+		{
+			Name:         "test a retry after beyond the max backoff is not waited for",
+			Method:       http.MethodGet,
+			Responses:    []int{http.StatusTooManyRequests, http.StatusOK},
+			RetryAfter:   "3600",
+			ExpectStatus: http.StatusTooManyRequests,
+			ExpectCalls:  1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tc.Responses[calls]
+				calls++
+				if tc.RetryAfter != "" && status == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", tc.RetryAfter)
+				}
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+			client := &http.Client{Transport: transport.New(nil, testSettings())}
+			req, err := http.NewRequest(tc.Method, server.URL, strings.NewReader(""))
+			if err != nil {
+				t.Fatal("did not expect an error creating the request ", err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal("did not expect an error but got one ", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.ExpectStatus {
+				t.Fatal("expected status ", tc.ExpectStatus, " but got ", resp.StatusCode)
+			}
+			if calls != tc.ExpectCalls {
+				t.Fatal("expected ", tc.ExpectCalls, " calls but got ", calls)
+			}
+		})
+	}
+}
+
+func TestTransport_RateLimit(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+	settings := testSettings()
+	settings.RateLimit = 1
+	settings.RateBurst = 1
+	client := &http.Client{Transport: transport.New(nil, settings)}
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatal("did not expect an error but got one ", err)
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	if _, err := client.Do(req.WithContext(ctx)); err == nil {
+		t.Fatal("expected the second request to be held back by the rate limit until the context expired")
+	}
+	if calls != 1 {
+		t.Fatal("expected one call to reach the server but got ", calls)
+	}
+}
+
+func TestTransport_Timeout(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// hang until the attempt is abandoned
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	settings := testSettings()
+	settings.Timeout = 20 * time.Millisecond
+	client := &http.Client{Transport: transport.New(nil, settings)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal("expected the hung attempt to time out and be retried but got ", err)
+	}
+	resp.Body.Close()
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatal("expected two calls but got ", n)
+	}
+}